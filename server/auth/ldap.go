@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPProvider LDAP / Active Directory 绑定认证
+// 先用服务账号按过滤条件查找用户DN，再以用户DN和密码绑定校验
+type LDAPProvider struct{}
+
+// ldapUser 从目录中读取的用户信息
+type ldapUser struct {
+	DN            string
+	Name          string
+	Email         string
+	Phone         string
+	Department    string
+	FunctionGroup string
+	Groups        []string
+}
+
+// Name 提供者名称
+func (p *LDAPProvider) Name() string {
+	return config.AuthSourceLDAP
+}
+
+// Authenticate 目录绑定认证，成功后同步用户信息（首次登录即时创建）
+func (p *LDAPProvider) Authenticate(username, password string, user *models.User) (*models.User, error) {
+	if !config.LDAPEnabled {
+		return nil, errors.New("LDAP认证未启用")
+	}
+	// 空密码会被目录视为匿名绑定而"成功"，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.lookup(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用用户自身的DN和密码绑定
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP用户绑定失败: %w", err)
+	}
+
+	info := &ldapUser{
+		DN:            entry.DN,
+		Name:          entry.GetAttributeValue(config.LDAPAttrName),
+		Email:         entry.GetAttributeValue(config.LDAPAttrEmail),
+		Phone:         entry.GetAttributeValue(config.LDAPAttrPhone),
+		Department:    entry.GetAttributeValue(config.LDAPAttrDepartment),
+		FunctionGroup: entry.GetAttributeValue(config.LDAPAttrFunctionGroup),
+		Groups:        entry.GetAttributeValues(config.LDAPAttrMemberOf),
+	}
+
+	// 未使用 memberOf 的目录（如默认的OpenLDAP）按组过滤条件查询所属组
+	if config.LDAPGroupBaseDN != "" {
+		if err := p.bindService(conn); err != nil {
+			return nil, err
+		}
+		groups, err := p.searchGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		info.Groups = append(info.Groups, groups...)
+	}
+
+	return syncLDAPUser(username, info, user)
+}
+
+// dial 连接目录服务器
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.LDAPInsecureSkipVerify}
+
+	conn, err := ldap.DialURL(config.LDAPURL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP服务器失败: %w", err)
+	}
+
+	if config.LDAPStartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS失败: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// bindService 使用服务账号绑定（未配置时保持匿名）
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if config.LDAPBindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(config.LDAPBindDN, config.LDAPBindPassword); err != nil {
+		return fmt.Errorf("LDAP服务账号绑定失败: %w", err)
+	}
+	return nil
+}
+
+// lookup 按用户过滤条件查找唯一的用户条目
+func (p *LDAPProvider) lookup(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(config.LDAPUserFilter, "{username}", ldap.EscapeFilter(username))
+	attrs := []string{
+		config.LDAPAttrName, config.LDAPAttrEmail, config.LDAPAttrPhone,
+		config.LDAPAttrDepartment, config.LDAPAttrFunctionGroup, config.LDAPAttrMemberOf,
+	}
+
+	req := ldap.NewSearchRequest(config.LDAPBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, 10, false, filter, attrs, nil)
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP查询用户失败: %w", err)
+	}
+	// 查不到或匹配到多个条目均按认证失败处理
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+// searchGroups 查询用户所属的组DN
+func (p *LDAPProvider) searchGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	filter := strings.ReplaceAll(config.LDAPGroupFilter, "{dn}", ldap.EscapeFilter(userDN))
+
+	req := ldap.NewSearchRequest(config.LDAPGroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 10, false, filter, []string{"dn"}, nil)
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP查询用户组失败: %w", err)
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		groups = append(groups, e.DN)
+	}
+	return groups, nil
+}
+
+// syncLDAPUser 同步目录用户到本地用户表
+// 首次登录即时创建；已存在的用户每次登录时更新目录属性，匹配到组映射时同步角色
+func syncLDAPUser(username string, info *ldapUser, user *models.User) (*models.User, error) {
+	db := config.GetDB()
+
+	role := resolveRole(info.Groups)
+
+	if user == nil {
+		if role == nil {
+			var defaultRole models.Role
+			if err := db.Where("code = ?", config.LDAPDefaultRole).First(&defaultRole).Error; err != nil {
+				return nil, fmt.Errorf("LDAP默认角色不存在: %s", config.LDAPDefaultRole)
+			}
+			role = &defaultRole
+		}
+
+		// 目录账号不使用本地密码，写入随机值的哈希占位
+		random, err := utils.GenerateRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword, err := utils.HashPassword(random)
+		if err != nil {
+			return nil, err
+		}
+
+		name := info.Name
+		if name == "" {
+			name = username
+		}
+		newUser := models.User{
+			Username:      username,
+			Password:      hashedPassword,
+			Name:          name,
+			Email:         info.Email,
+			Phone:         info.Phone,
+			Department:    info.Department,
+			FunctionGroup: info.FunctionGroup,
+			RoleID:        role.ID,
+			Status:        1,
+			AuthSource:    config.AuthSourceLDAP,
+		}
+		if err := db.Create(&newUser).Error; err != nil {
+			return nil, fmt.Errorf("创建LDAP用户失败: %w", err)
+		}
+		newUser.Role = role
+		return &newUser, nil
+	}
+
+	updates := make(map[string]interface{})
+	if info.Name != "" {
+		updates["name"] = info.Name
+	}
+	if info.Email != "" {
+		updates["email"] = info.Email
+	}
+	if info.Phone != "" {
+		updates["phone"] = info.Phone
+	}
+	if info.Department != "" {
+		updates["department"] = info.Department
+	}
+	if info.FunctionGroup != "" {
+		updates["function_group"] = info.FunctionGroup
+	}
+	if role != nil && role.ID != user.RoleID {
+		updates["role_id"] = role.ID
+	}
+	if len(updates) > 0 {
+		if err := db.Model(user).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("同步LDAP用户失败: %w", err)
+		}
+	}
+
+	if err := db.Preload("Role").First(user, user.ID).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// resolveRole 根据用户所属组匹配优先级最高的角色映射，未匹配时返回nil
+func resolveRole(groups []string) *models.Role {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	lowered := make([]string, 0, len(groups))
+	for _, g := range groups {
+		lowered = append(lowered, strings.ToLower(g))
+	}
+
+	db := config.GetDB()
+	var mappings []models.LDAPGroupMapping
+	db.Where("LOWER(group_dn) IN ?", lowered).Order("priority DESC, id ASC").Find(&mappings)
+
+	for _, m := range mappings {
+		var role models.Role
+		if db.Where("code = ?", m.RoleCode).First(&role).Error == nil {
+			return &role
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+)
+
+// LocalProvider 本地账号认证（bcrypt密码）
+type LocalProvider struct{}
+
+// Name 提供者名称
+func (p *LocalProvider) Name() string {
+	return config.AuthSourceLocal
+}
+
+// Authenticate 校验本地密码
+func (p *LocalProvider) Authenticate(username, password string, user *models.User) (*models.User, error) {
+	if user == nil || !utils.CheckPassword(user.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
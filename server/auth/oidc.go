@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project-flow/config"
+	"project-flow/models"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrOIDCUserNotLinked 身份提供方的账号未关联到系统用户
+var ErrOIDCUserNotLinked = errors.New("该企业账号未关联系统用户，请联系管理员")
+
+// OIDCIdentity 从ID Token中解析出的用户身份
+type OIDCIdentity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// oidcClient OIDC客户端（首次使用时通过发现文档初始化）
+type oidcClient struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcMu     sync.Mutex
+	oidcCached *oidcClient
+)
+
+// getOIDCClient 获取OIDC客户端，发现文档获取失败时下次请求重试
+func getOIDCClient(ctx context.Context) (*oidcClient, error) {
+	if !config.OIDCEnabled {
+		return nil, errors.New("OIDC登录未启用")
+	}
+
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcCached != nil {
+		return oidcCached, nil
+	}
+
+	// 发现文档中的JWKS地址由 go-oidc 缓存并在密钥轮换时自动刷新
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), config.OIDCIssuer)
+	if err != nil {
+		return nil, fmt.Errorf("获取OIDC发现文档失败: %w", err)
+	}
+
+	oidcCached = &oidcClient{
+		oauth2: oauth2.Config{
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  config.OIDCRedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       strings.Fields(config.OIDCScopes),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.OIDCClientID}),
+	}
+	return oidcCached, nil
+}
+
+// OIDCAuthCodeURL 生成跳转到身份提供方的授权地址（授权码模式 + PKCE）
+func OIDCAuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	client, err := getOIDCClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	return client.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// OIDCExchange 使用授权码换取Token，并校验ID Token的签名、签发方、受众、有效期和nonce
+func OIDCExchange(ctx context.Context, code, nonce, verifier string) (*OIDCIdentity, error) {
+	client, err := getOIDCClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := client.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取Token失败: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("身份提供方未返回ID Token")
+	}
+
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token校验失败: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID Token nonce不匹配")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析ID Token失败: %w", err)
+	}
+
+	identity := &OIDCIdentity{Subject: idToken.Subject}
+	identity.Username, _ = claims[config.OIDCUsernameClaim].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	return identity, nil
+}
+
+// LinkOIDCUser 将身份提供方的账号关联到系统用户
+// 优先按已绑定的 subject 查找，其次按已验证的邮箱匹配并完成绑定；
+// 按用户名匹配需开启 OIDC_LINK_BY_USERNAME
+func LinkOIDCUser(identity *OIDCIdentity) (*models.User, error) {
+	db := config.GetDB()
+	subject := config.OIDCIssuer + "|" + identity.Subject
+
+	var user models.User
+	if db.Preload("Role").Where("oidc_subject = ?", subject).First(&user).Error == nil {
+		return &user, nil
+	}
+
+	found := false
+	if config.OIDCLinkByUsername && identity.Username != "" {
+		found = db.Preload("Role").Where("username = ? AND (oidc_subject IS NULL OR oidc_subject = '')", identity.Username).
+			First(&user).Error == nil
+	}
+	if !found && identity.Email != "" && identity.EmailVerified {
+		var users []models.User
+		db.Preload("Role").Where("email = ? AND (oidc_subject IS NULL OR oidc_subject = '')", identity.Email).
+			Limit(2).Find(&users)
+		// 邮箱对应多个用户时无法确定关联对象
+		if len(users) == 1 {
+			user = users[0]
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrOIDCUserNotLinked
+	}
+
+	if err := db.Model(&user).Update("oidc_subject", subject).Error; err != nil {
+		return nil, fmt.Errorf("绑定企业账号失败: %w", err)
+	}
+	user.OIDCSubject = &subject
+	return &user, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"project-flow/config"
+	"project-flow/models"
+)
+
+// ErrInvalidCredentials 用户名或密码错误
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// Provider 认证提供者
+type Provider interface {
+	// Name 提供者名称，与 User.AuthSource 对应
+	Name() string
+	// Authenticate 校验用户名和密码，user 为系统中已存在的用户（可能为nil）
+	// 认证成功时返回对应的系统用户，必要时即时创建
+	Authenticate(username, password string, user *models.User) (*models.User, error)
+}
+
+var (
+	localProvider = &LocalProvider{}
+	ldapProvider  = &LDAPProvider{}
+)
+
+// ProviderFor 根据用户的认证来源选择认证提供者
+// 系统中不存在的用户在启用LDAP时交由LDAP认证（首次登录即时创建）
+func ProviderFor(user *models.User) Provider {
+	if user == nil {
+		if config.LDAPEnabled {
+			return ldapProvider
+		}
+		return localProvider
+	}
+
+	switch user.AuthSource {
+	case config.AuthSourceLDAP:
+		return ldapProvider
+	default:
+		return localProvider
+	}
+}
@@ -2,14 +2,22 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // 系统配置
 var (
 	// JWT配置
-	JWTSecret     = []byte(getEnv("JWT_SECRET", "project-flow-secret-key-2024"))
-	JWTExpireTime = time.Hour * 24 // Token有效期24小时
+	JWTSecret           = []byte(getEnv("JWT_SECRET", "project-flow-secret-key-2024"))
+	JWTExpireTime       = time.Minute * 30   // 访问Token有效期30分钟
+	RefreshExpireTime   = time.Hour * 24 * 7 // 刷新Token（会话）有效期7天
+	ChallengeExpireTime = time.Minute * 5    // 登录第二步验证（双因素认证）的挑战Token有效期5分钟
+
+	// 双因素认证配置
+	TOTPIssuer        = getEnv("TOTP_ISSUER", "ProjectFlow") // 验证器App中显示的签发方名称
+	RecoveryCodeCount = 10                                   // 每次生成的恢复码数量
 
 	// 服务器配置
 	ServerPort = getEnv("SERVER_PORT", ":8082")
@@ -18,6 +26,10 @@ var (
 	UploadPath  = getEnv("UPLOAD_PATH", "./uploads")
 	MaxFileSize = int64(100 * 1024 * 1024) // 100MB
 
+	// 文件下载签名配置（签名URL只对单个文件有效，几分钟后过期）
+	DownloadSignSecret    = []byte(getEnv("DOWNLOAD_SIGN_SECRET", string(JWTSecret)))
+	DownloadURLExpireTime = time.Minute * 5
+
 	// 数据库配置（MySQL）
 	DBHost     = getEnv("DB_HOST", "172.17.7.180")
 	DBPort     = getEnv("DB_PORT", "3307")
@@ -26,8 +38,81 @@ var (
 	DBName     = getEnv("DB_NAME", "project_track")
 	//DBName     = getEnv("DB_NAME", "project_flow")
 
-	// 密码复杂度要求
-	MinPasswordLength = 8
+	// 登录失败锁定策略：连续失败达到次数后锁定一段时间
+	LoginMaxFailures  = getEnvInt("LOGIN_MAX_FAILURES", 5)
+	LoginLockDuration = time.Minute * time.Duration(getEnvInt("LOGIN_LOCK_MINUTES", 30))
+
+	// 接口限流（令牌桶，格式为"次数/时间窗口"，如 20/1m，设为 0 表示不限制）
+	RateLimitLoginIP   = getEnvRate("RATE_LIMIT_LOGIN_IP", "20/1m")   // 登录类接口按客户端IP
+	RateLimitLoginUser = getEnvRate("RATE_LIMIT_LOGIN_USER", "10/5m") // 登录接口按用户名（防止分布式猜测单个账号）
+	RateLimitAPI       = getEnvRate("RATE_LIMIT_API", "600/1m")       // 其他接口按客户端IP
+
+	// 受信任的反向代理地址（逗号分隔的IP或CIDR），只有来自这些地址的请求才采信 X-Forwarded-For 获取客户端IP；
+	// 未配置时直接使用连接的来源地址
+	TrustedProxies = getEnvList("TRUSTED_PROXIES")
+
+	// 重复任务：后台定期为重复系列提前生成未来N天内到期的任务实例
+	RecurrenceLeadDays      = getEnvInt("RECURRENCE_LEAD_DAYS", 30)
+	RecurrenceCheckInterval = time.Minute * time.Duration(getEnvInt("RECURRENCE_CHECK_MINUTES", 60))
+
+	// 工时：未按用户或职能组配置费率时使用的默认小时费率（元），单日工时上限
+	DefaultHourlyRate = float64(getEnvInt("DEFAULT_HOURLY_RATE", 0))
+	MaxDailyHours     = float64(getEnvInt("MAX_DAILY_HOURS", 24))
+
+	// 工作负荷：未设置个人容量时的每周可用工时，未填预估工时的任务按工期×每日工时估算
+	DefaultWeeklyCapacity = float64(getEnvInt("DEFAULT_WEEKLY_CAPACITY", 40))
+	WorkHoursPerDay       = float64(getEnvInt("WORK_HOURS_PER_DAY", 8))
+
+	// 逾期提醒：后台定期检查任务截止时间，按阶段提醒负责人、项目负责人和部门经理（项目未单独配置时的默认值）
+	EscalationCheckInterval   = time.Minute * time.Duration(getEnvInt("ESCALATION_CHECK_MINUTES", 30))
+	EscalationRemindHours     = getEnvInt("ESCALATION_REMIND_HOURS", 24)
+	EscalationManagerDays     = getEnvInt("ESCALATION_MANAGER_DAYS", 1)
+	EscalationDeptManagerDays = getEnvInt("ESCALATION_DEPT_MANAGER_DAYS", 3)
+
+	// 密码策略
+	Password = PasswordPolicy{
+		MinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:    getEnv("PASSWORD_REQUIRE_UPPER", "true") == "true",
+		RequireLower:    getEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+		RequireDigit:    getEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+		RequireSpecial:  getEnv("PASSWORD_REQUIRE_SPECIAL", "true") == "true",
+		HistoryDepth:    getEnvInt("PASSWORD_HISTORY_DEPTH", 5),
+		MaxAgeDays:      getEnvInt("PASSWORD_MAX_AGE_DAYS", 90),
+		DictionaryCheck: getEnv("PASSWORD_DICTIONARY_CHECK", "true") == "true",
+		DictionaryFile:  getEnv("PASSWORD_DICTIONARY_FILE", ""),
+	}
+
+	// LDAP / Active Directory 认证配置
+	LDAPEnabled            = getEnv("LDAP_ENABLED", "false") == "true"
+	LDAPURL                = getEnv("LDAP_URL", "ldap://localhost:389")
+	LDAPStartTLS           = getEnv("LDAP_START_TLS", "false") == "true"
+	LDAPInsecureSkipVerify = getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true"
+	LDAPBindDN             = getEnv("LDAP_BIND_DN", "")       // 服务账号DN（为空时匿名查询）
+	LDAPBindPassword       = getEnv("LDAP_BIND_PASSWORD", "") // 服务账号密码
+	LDAPBaseDN             = getEnv("LDAP_BASE_DN", "dc=example,dc=com")
+	LDAPUserFilter         = getEnv("LDAP_USER_FILTER", "(uid={username})") // AD可配置为 (sAMAccountName={username})
+	LDAPGroupBaseDN        = getEnv("LDAP_GROUP_BASE_DN", "")               // 为空时仅使用用户的 memberOf 属性
+	LDAPGroupFilter        = getEnv("LDAP_GROUP_FILTER", "(|(member={dn})(uniqueMember={dn}))")
+	LDAPAttrName           = getEnv("LDAP_ATTR_NAME", "cn")
+	LDAPAttrEmail          = getEnv("LDAP_ATTR_EMAIL", "mail")
+	LDAPAttrPhone          = getEnv("LDAP_ATTR_PHONE", "mobile")
+	LDAPAttrDepartment     = getEnv("LDAP_ATTR_DEPARTMENT", "departmentNumber") // AD一般为 department
+	LDAPAttrFunctionGroup  = getEnv("LDAP_ATTR_FUNCTION_GROUP", "title")
+	LDAPAttrMemberOf       = getEnv("LDAP_ATTR_MEMBER_OF", "memberOf")
+	LDAPDefaultRole        = getEnv("LDAP_DEFAULT_ROLE", RoleTeamMember) // 未匹配到组映射时的默认角色
+
+	// OpenID Connect 单点登录配置
+	OIDCEnabled       = getEnv("OIDC_ENABLED", "false") == "true"
+	OIDCIssuer        = getEnv("OIDC_ISSUER", "") // 身份提供方地址，如 http://localhost:5556/dex
+	OIDCClientID      = getEnv("OIDC_CLIENT_ID", "")
+	OIDCClientSecret  = getEnv("OIDC_CLIENT_SECRET", "")
+	OIDCRedirectURL   = getEnv("OIDC_REDIRECT_URL", "http://localhost:8082/api/oidc/callback") // 需与身份提供方登记的回调地址一致
+	OIDCScopes        = getEnv("OIDC_SCOPES", "openid profile email")
+	OIDCUsernameClaim = getEnv("OIDC_USERNAME_CLAIM", "preferred_username") // 用于匹配系统用户名的声明
+	OIDCFrontendURL   = getEnv("OIDC_FRONTEND_URL", "/project_track/login") // 登录完成后携带Token跳转的前端地址
+
+	// 是否按用户名自动关联未绑定的本地账号；用户名声明通常可由用户自行修改，仅在身份提供方保证其唯一且不可修改时开启
+	OIDCLinkByUsername = getEnv("OIDC_LINK_BY_USERNAME", "false") == "true"
 )
 
 // getEnv 获取环境变量，如果不存在则返回默认值
@@ -38,6 +123,78 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt 获取整数类型的环境变量，未设置或格式错误时使用默认值
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList 获取逗号分隔的列表类型环境变量，未设置时返回 nil
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// RateLimitRule 令牌桶限流规则：桶容量为 Burst，每个 Period 内补充 Burst 个令牌
+type RateLimitRule struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Enabled 是否启用限流
+func (r RateLimitRule) Enabled() bool {
+	return r.Burst > 0 && r.Period > 0
+}
+
+// getEnvRate 获取限流规则类型的环境变量（格式"次数/时间窗口"），格式错误时使用默认值
+func getEnvRate(key, defaultValue string) RateLimitRule {
+	if rule, ok := parseRateLimit(getEnv(key, defaultValue)); ok {
+		return rule
+	}
+	rule, _ := parseRateLimit(defaultValue)
+	return rule
+}
+
+// parseRateLimit 解析"次数/时间窗口"格式的限流规则，"0" 表示不限制
+func parseRateLimit(value string) (RateLimitRule, bool) {
+	if value == "0" {
+		return RateLimitRule{}, true
+	}
+	count, window, found := strings.Cut(value, "/")
+	if !found {
+		return RateLimitRule{}, false
+	}
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst < 0 {
+		return RateLimitRule{}, false
+	}
+	period, err := time.ParseDuration(window)
+	if err != nil || period <= 0 {
+		return RateLimitRule{}, false
+	}
+	return RateLimitRule{Burst: burst, Period: period}, true
+}
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	MinLength       int    `json:"min_length"`       // 最小长度
+	RequireUpper    bool   `json:"require_upper"`    // 必须包含大写字母
+	RequireLower    bool   `json:"require_lower"`    // 必须包含小写字母
+	RequireDigit    bool   `json:"require_digit"`    // 必须包含数字
+	RequireSpecial  bool   `json:"require_special"`  // 必须包含特殊符号
+	HistoryDepth    int    `json:"history_depth"`    // 不能与最近N次使用过的密码相同（0为不限制）
+	MaxAgeDays      int    `json:"max_age_days"`     // 密码有效期（天），到期后须修改（0为永不过期）
+	DictionaryCheck bool   `json:"dictionary_check"` // 是否拒绝常见弱密码
+	DictionaryFile  string `json:"-"`                // 自定义弱密码字典文件（每行一个）
+}
+
 // 项目阶段常量（固定阶段）
 const (
 	PhaseInitiation = "initiation" // 立项（固定）
@@ -57,10 +214,18 @@ const (
 
 // 任务状态
 const (
-	TaskNotStarted = "not_started" // 未开始
-	TaskInProgress = "in_progress" // 进行中
-	TaskCompleted  = "completed"   // 已完成
-	TaskRejected   = "rejected"    // 被驳回
+	TaskNotStarted = "not_started"          // 未开始
+	TaskInProgress = "in_progress"          // 进行中
+	TaskCompleted  = "completed"            // 已完成
+	TaskRejected   = "rejected"             // 被驳回
+	TaskSubmitted  = "submitted_for_review" // 已提交审核
+	TaskBlocked    = "blocked"              // 受阻
+)
+
+// 认证来源
+const (
+	AuthSourceLocal = "local" // 本地账号（bcrypt密码）
+	AuthSourceLDAP  = "ldap"  // LDAP / Active Directory
 )
 
 // 角色类型（组织级）
@@ -71,6 +236,131 @@ const (
 	RoleTeamMember  = "team_member"  // 组员
 )
 
+// 权限标识（存储于 Role.Permissions，"all" 表示全部权限，"模块:all" 表示模块下全部权限）
+const (
+	PermAll = "all" // 全部权限
+
+	PermUserManage = "user:manage" // 用户管理
+	PermRoleManage = "role:manage" // 角色与权限管理
+
+	PermProjectView     = "project:view"      // 查看项目（含阶段、任务），仅限参与的项目
+	PermProjectViewDept = "project:view_dept" // 查看本部门负责的全部项目
+	PermProjectViewAll  = "project:view_all"  // 查看全部项目
+	PermProjectCreate   = "project:create"    // 创建项目
+	PermTemplateManage  = "project:template"  // 管理项目模板
+
+	PermTaskCreate = "task:create" // 创建/分配任务
+
+	PermDocumentView     = "document:view"     // 查看资料
+	PermDocumentDownload = "document:download" // 下载资料
+	PermDocumentUpload   = "document:upload"   // 上传/编辑/删除资料
+	PermDocumentArchive  = "document:archive"  // 归档资料
+
+	PermContractView   = "contract:view"   // 查看合同
+	PermContractManage = "contract:manage" // 创建/编辑/删除合同
+
+	PermKBView     = "kb:view"     // 查看知识库
+	PermKBDownload = "kb:download" // 下载知识库资料
+	PermKBUpload   = "kb:upload"   // 上传知识库资料及新版本
+	PermKBManage   = "kb:manage"   // 编辑知识库资料、管理分类
+
+	PermLogView = "log:view" // 查看操作日志
+
+	PermExpenseView      = "expense:view"       // 查看费用及统计（仅限可见项目及本人费用）
+	PermExpenseViewAll   = "expense:view_all"   // 查看全部费用
+	PermExpenseEdit      = "expense:edit"       // 新增/修改/删除费用记录
+	PermExpenseImport    = "expense:import"     // 导入费用
+	PermExpenseExport    = "expense:export"     // 导出费用
+	PermExpenseDeleteAll = "expense:delete_all" // 一键删除费用
+	PermLaborRate        = "expense:labor_rate" // 配置人工小时费率
+)
+
+// Permission 权限定义（用于角色配置界面）
+type Permission struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// AllPermissions 系统支持的全部权限
+var AllPermissions = []Permission{
+	{PermAll, "全部权限"},
+	{PermUserManage, "用户管理"},
+	{PermRoleManage, "角色与权限管理"},
+	{PermProjectView, "查看项目"},
+	{PermProjectViewDept, "查看本部门项目"},
+	{PermProjectViewAll, "查看全部项目"},
+	{PermProjectCreate, "创建项目"},
+	{PermTemplateManage, "管理项目模板"},
+	{PermTaskCreate, "创建/分配任务"},
+	{PermDocumentView, "查看资料"},
+	{PermDocumentDownload, "下载资料"},
+	{PermDocumentUpload, "上传/编辑资料"},
+	{PermDocumentArchive, "归档资料"},
+	{PermContractView, "查看合同"},
+	{PermContractManage, "管理合同"},
+	{"kb:all", "知识库全部权限"},
+	{PermKBView, "查看知识库"},
+	{PermKBDownload, "下载知识库资料"},
+	{PermKBUpload, "上传知识库资料"},
+	{PermKBManage, "管理知识库"},
+	{PermLogView, "查看操作日志"},
+	{"expense:all", "费用全部权限"},
+	{PermExpenseView, "查看费用"},
+	{PermExpenseViewAll, "查看全部费用"},
+	{PermExpenseEdit, "编辑费用"},
+	{PermExpenseImport, "导入费用"},
+	{PermExpenseExport, "导出费用"},
+	{PermExpenseDeleteAll, "一键删除费用"},
+	{PermLaborRate, "配置人工费率"},
+}
+
+// IsValidPermission 判断权限标识是否受支持
+func IsValidPermission(code string) bool {
+	for _, p := range AllPermissions {
+		if p.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// 个人访问Token配置
+const (
+	AccessTokenPrefix         = "pft_" // Token明文前缀，用于区分JWT
+	AccessTokenDefaultExpire  = 90     // 默认有效期（天）
+	AccessTokenMaxExpire      = 365    // 最长有效期（天）
+	AccessTokenMaxPerUser     = 20     // 每个用户最多持有的有效Token数量
+	AccessTokenTouchIntervalS = 60     // 最近使用时间的更新间隔（秒），避免每次请求都写库
+)
+
+// AllTokenScopes 个人访问Token支持的授权范围
+// 按 /api/ 下的一级路由分组，read 允许 GET 请求，write 允许全部请求（包含 read）
+var AllTokenScopes = []Permission{
+	{"projects:read", "项目-只读"},
+	{"projects:write", "项目-读写"},
+	{"tasks:read", "任务-只读"},
+	{"tasks:write", "任务-读写"},
+	{"documents:read", "文档-只读"},
+	{"documents:write", "文档-读写"},
+	{"contracts:read", "合同-只读"},
+	{"contracts:write", "合同-读写"},
+	{"knowledge:read", "知识库-只读"},
+	{"knowledge:write", "知识库-读写"},
+	{"expenses:read", "费用-只读（含导出）"},
+	{"expenses:write", "费用-读写（含导入）"},
+	{"logs:read", "操作日志-只读"},
+}
+
+// IsValidTokenScope 判断Token授权范围是否受支持
+func IsValidTokenScope(code string) bool {
+	for _, s := range AllTokenScopes {
+		if s.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
 // 部门类型
 const (
 	DeptBMS      = "BMS研发部"  // BMS研发部
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// projectAccess 当前用户的项目可见范围
+// 普通用户：负责、创建、子负责、参与（项目成员）或被分配任务的项目
+// 拥有 project:view_dept 权限：额外可见负责人属于本部门的项目
+// 拥有 project:view_all 权限：可见全部项目
+type projectAccess struct {
+	userID     uint
+	department string
+	viewDept   bool
+	viewAll    bool
+	expenseAll bool
+}
+
+// getProjectAccess 根据当前登录用户构建项目可见范围
+func getProjectAccess(c *gin.Context) *projectAccess {
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+
+	pa := &projectAccess{userID: userID.(uint)}
+
+	var role models.Role
+	if config.GetDB().Where("code = ?", roleCode).First(&role).Error == nil {
+		pa.viewAll = role.HasPermission(config.PermProjectViewAll)
+		pa.viewDept = role.HasPermission(config.PermProjectViewDept)
+		pa.expenseAll = role.HasPermission(config.PermExpenseViewAll)
+	}
+
+	if pa.viewDept && !pa.viewAll {
+		var user models.User
+		if config.GetDB().Select("id, department").First(&user, pa.userID).Error == nil {
+			pa.department = user.Department
+		}
+	}
+
+	return pa
+}
+
+// projectIDs 可见项目ID子查询
+func (pa *projectAccess) projectIDs() *gorm.DB {
+	db := config.GetDB()
+	memberProjects := db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", pa.userID)
+	taskProjects := db.Model(&models.Task{}).Select("project_id").Where("assignee_id = ?", pa.userID)
+
+	cond := db.Where("manager_id = ? OR created_by = ? OR sub_manager_id = ?", pa.userID, pa.userID, pa.userID).
+		Or("id IN (?)", memberProjects).
+		Or("id IN (?)", taskProjects)
+	if pa.department != "" {
+		deptManagers := db.Model(&models.User{}).Select("id").Where("department = ?", pa.department)
+		cond = cond.Or("manager_id IN (?)", deptManagers)
+	}
+
+	return db.Model(&models.Project{}).Select("id").Where(cond)
+}
+
+// scope 按项目可见范围过滤查询，column 为查询表中的项目ID列
+func (pa *projectAccess) scope(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if pa.viewAll {
+			return db
+		}
+		return db.Where(column+" IN (?)", pa.projectIDs())
+	}
+}
+
+// expenseScope 按可见范围过滤费用记录：可见项目的费用，以及本人报账或录入的费用
+func (pa *projectAccess) expenseScope(db *gorm.DB) *gorm.DB {
+	if pa.viewAll || pa.expenseAll {
+		return db
+	}
+	cond := config.GetDB().Where("expenses.project_id IN (?)", pa.projectIDs()).
+		Or("expenses.reimbursed_by = ? OR expenses.created_by = ?", pa.userID, pa.userID)
+	if pa.department != "" {
+		cond = cond.Or("expenses.project_id IS NULL AND expenses.department_name = ?", pa.department)
+	}
+	return db.Where(cond)
+}
+
+// canView 判断是否可以查看指定项目
+func (pa *projectAccess) canView(projectID uint) bool {
+	if pa.viewAll {
+		return true
+	}
+	var count int64
+	config.GetDB().Model(&models.Project{}).Where("id = ?", projectID).
+		Scopes(pa.scope("id")).Count(&count)
+	return count > 0
+}
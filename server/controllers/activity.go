@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 任务动态类型
+const (
+	ActivityComment = "comment" // 评论
+	ActivityStatus  = "status"  // 状态变更
+	ActivityReview  = "review"  // 审核
+	ActivityUpload  = "upload"  // 上传交付件
+	ActivityOther   = "other"   // 创建、修改等其他操作
+)
+
+// ActivityItem 任务动态
+type ActivityItem struct {
+	Type        string    `json:"type"`
+	Action      string    `json:"action"`
+	UserID      uint      `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	Description string    `json:"description"`
+	CommentID   uint      `json:"comment_id,omitempty"`
+	Content     string    `json:"content,omitempty"` // 评论内容
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// taskActivityTypes 操作日志动作与动态类型的对应关系
+var taskActivityTypes = map[string]string{
+	"update_status": ActivityStatus,
+	"review":        ActivityReview,
+	"submit_review": ActivityReview,
+	"upload":        ActivityUpload,
+}
+
+// Activity 获取任务动态：合并评论、状态变更、审核和交付件上传记录，按时间倒序
+func (tc *TaskController) Activity(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	var items []ActivityItem
+
+	// 操作日志：任务本身的操作和任务交付件的上传
+	var docIDs []uint
+	db.Model(&models.Document{}).Where("task_id = ?", task.ID).Pluck("id", &docIDs)
+	targets := db.Where("module = ? AND target_type = ? AND target_id = ?", "task", "task", task.ID)
+	if len(docIDs) > 0 {
+		targets = targets.Or("module = ? AND action = ? AND target_id IN ?", "document", "upload", docIDs)
+	}
+	var logs []models.OperationLog
+	db.Preload("User").Where("result = ?", "success").Where(targets).Order("id DESC").Limit(200).Find(&logs)
+	for _, l := range logs {
+		activityType, ok := taskActivityTypes[l.Action]
+		if !ok {
+			activityType = ActivityOther
+		}
+		item := ActivityItem{Type: activityType, Action: l.Action, UserID: l.UserID, Description: l.Description, CreatedAt: l.CreatedAt}
+		if l.User != nil {
+			item.UserName = l.User.Name
+		}
+		items = append(items, item)
+	}
+
+	// 评论
+	var comments []models.Comment
+	db.Preload("Author").Where("target_type = ? AND target_id = ?", models.CommentTargetTask, task.ID).
+		Order("id DESC").Limit(200).Find(&comments)
+	for _, cm := range comments {
+		item := ActivityItem{Type: ActivityComment, Action: "comment", UserID: cm.AuthorID, Description: "发表评论", CommentID: cm.ID, Content: cm.Content, CreatedAt: cm.CreatedAt}
+		if cm.ParentID != nil {
+			item.Description = "回复评论"
+		}
+		if cm.Author != nil {
+			item.UserName = cm.Author.Name
+		}
+		items = append(items, item)
+	}
+
+	// 系统自动变更的状态（子任务带动父任务）没有操作日志，取自状态历史
+	var history []models.TaskStatusHistory
+	db.Where("task_id = ? AND actor = ?", task.ID, ActorSystem).Order("id DESC").Limit(200).Find(&history)
+	for _, h := range history {
+		description := "系统更新任务状态: " + taskStatusLabels[h.FromStatus] + " -> " + taskStatusLabels[h.ToStatus]
+		if h.Comment != "" {
+			description += "（" + h.Comment + "）"
+		}
+		items = append(items, ActivityItem{Type: ActivityStatus, Action: "update_status", UserName: "系统", Description: description, CreatedAt: h.CreatedAt})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.After(items[j].CreatedAt) })
+	if items == nil {
+		items = []ActivityItem{}
+	}
+	utils.Success(c, items)
+}
@@ -1,6 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"project-flow/auth"
 	"project-flow/config"
 	"project-flow/middleware"
 	"project-flow/models"
@@ -20,8 +25,15 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    int64       `json:"expires_in"` // 访问Token有效期（秒）
+	User         models.User `json:"user"`
+}
+
+// RefreshTokenRequest 刷新Token请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // Login 用户登录
@@ -33,71 +45,171 @@ func (ac *AuthController) Login(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+	var existing *models.User
 	var user models.User
-	if err := db.Preload("Role").Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := db.Preload("Role").Where("username = ?", req.Username).First(&user).Error; err == nil {
+		existing = &user
+	} else if !config.LDAPEnabled {
+		// 用户不存在时同样执行一次密码校验，使响应时间与密码错误时一致
+		utils.DummyCheckPassword(req.Password)
 		utils.Error(c, 401, "用户名或密码错误")
 		return
 	}
 
-	// 检查账号是否被锁定
-	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
-		utils.Error(c, 403, "账号已被锁定，请稍后再试")
+	if existing != nil {
+		// 检查账号是否被锁定
+		if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+			utils.Error(c, 403, "账号已被锁定，请稍后再试")
+			return
+		}
+
+		// 检查账号状态
+		if user.Status != 1 {
+			utils.Error(c, 403, "账号已被禁用")
+			return
+		}
+	}
+
+	// 按用户的认证来源校验密码（启用LDAP时，系统中不存在的用户由目录认证并即时创建）
+	authed, err := auth.ProviderFor(existing).Authenticate(req.Username, req.Password, existing)
+	if err != nil {
+		// 只有凭据错误计入失败次数；目录服务不可用、服务账号绑定失败等不能锁定用户
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			if existing != nil {
+				registerLoginFailure(c, &user)
+			}
+			utils.Error(c, 401, "用户名或密码错误")
+		} else {
+			log.Printf("登录认证失败 (%s): %v", req.Username, err)
+			utils.ErrorWithStatus(c, http.StatusServiceUnavailable, 503, "认证服务暂不可用，请稍后再试")
+		}
 		return
 	}
+	user = *authed
+
+	// 本地账号密码超过有效期时，要求登录后先修改密码
+	if passwordExpired(&user) {
+		user.MustChangePassword = true
+		db.Model(&user).Update("must_change_password", true)
+	}
 
-	// 检查账号状态
-	if user.Status != 1 {
-		utils.Error(c, 403, "账号已被禁用")
+	// 已启用双因素认证或角色要求双因素认证时，先返回挑战Token，第二步验证通过后再创建会话
+	challenge, err := twoFactorChallenge(&user)
+	if err != nil {
+		utils.ServerError(c, "生成Token失败")
+		return
+	}
+	if challenge != nil {
+		utils.Success(c, challenge)
 		return
 	}
 
-	// 验证密码
-	if !utils.CheckPassword(user.Password, req.Password) {
-		// 记录失败次数
-		user.FailedLogins++
-		if user.FailedLogins >= 5 {
-			lockTime := time.Now().Add(30 * time.Minute)
-			user.LockedUntil = &lockTime
-		}
-		db.Save(&user)
-		utils.Error(c, 401, "用户名或密码错误")
+	completeLogin(c, &user, user.Name+" 登录系统")
+}
+
+// completeLogin 认证通过：重置失败次数，创建会话并返回Token
+func completeLogin(c *gin.Context, user *models.User, description string) {
+	config.GetDB().Model(user).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	})
+
+	// 创建会话并生成Token
+	resp, err := issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成Token失败")
 		return
 	}
 
-	// 重置失败次数
-	user.FailedLogins = 0
-	user.LockedUntil = nil
-	db.Save(&user)
+	// 记录登录日志
+	recordLogin(c, user.ID, description)
 
-	// 生成Token
-	roleCode := ""
-	if user.Role != nil {
-		roleCode = user.Role.Code
+	utils.Success(c, resp)
+}
+
+// registerLoginFailure 记录登录失败次数，连续失败达到上限时锁定账号
+func registerLoginFailure(c *gin.Context, user *models.User) {
+	// 上一次锁定已到期，重新开始计数
+	if user.LockedUntil != nil && !user.LockedUntil.After(time.Now()) {
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	}
+
+	user.FailedLogins++
+	updates := map[string]interface{}{"failed_logins": user.FailedLogins, "locked_until": user.LockedUntil}
+	locked := config.LoginMaxFailures > 0 && user.FailedLogins >= config.LoginMaxFailures
+	if locked {
+		lockTime := time.Now().Add(config.LoginLockDuration)
+		user.LockedUntil = &lockTime
+		updates["locked_until"] = lockTime
+	}
+	config.GetDB().Model(user).Updates(updates)
+
+	if locked {
+		middleware.LogOperationByUser(c, user.ID, "lock", "auth", "user", user.ID, user.Name,
+			fmt.Sprintf("连续登录失败%d次，账号锁定至 %s", user.FailedLogins, user.LockedUntil.Format("2006-01-02 15:04:05")), "success")
 	}
-	token, err := utils.GenerateToken(user.ID, user.Username, roleCode)
+}
+
+// RefreshToken 使用刷新Token换取新的访问Token（刷新Token同时轮换）
+func (ac *AuthController) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "缺少刷新Token")
+		return
+	}
+
+	db := config.GetDB()
+	oldHash := utils.HashToken(req.RefreshToken)
+	var session models.UserSession
+	if err := db.Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?",
+		oldHash, time.Now()).First(&session).Error; err != nil {
+		utils.Unauthorized(c, "登录已失效，请重新登录")
+		return
+	}
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, session.UserID).Error; err != nil || user.Status != 1 {
+		revokeUserSessions(session.UserID)
+		utils.Unauthorized(c, "登录已失效，请重新登录")
+		return
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
 	if err != nil {
 		utils.ServerError(c, "生成Token失败")
 		return
 	}
+	// 条件更新：同一刷新Token并发刷新时只有一个请求能完成轮换
+	now := time.Now()
+	result := db.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": utils.HashToken(refreshToken),
+			"refreshed_at":       now,
+			"ip_address":         c.ClientIP(),
+			"user_agent":         c.Request.UserAgent(),
+		})
+	if result.Error != nil {
+		utils.ServerError(c, "刷新Token失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Unauthorized(c, "登录已失效，请重新登录")
+		return
+	}
 
-	// 记录登录日志
-	go func() {
-		log := models.OperationLog{
-			UserID:      user.ID,
-			Action:      "login",
-			Module:      "auth",
-			Description: user.Name + " 登录系统",
-			Result:      "success",
-			IPAddress:   c.ClientIP(),
-			UserAgent:   c.Request.UserAgent(),
-			CreatedAt:   time.Now(),
-		}
-		db.Create(&log)
-	}()
+	token, err := utils.GenerateToken(user.ID, user.Username, roleCodeOf(&user), session.ID, user.MustChangePassword)
+	if err != nil {
+		utils.ServerError(c, "生成Token失败")
+		return
+	}
 
 	utils.Success(c, LoginResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.JWTExpireTime.Seconds()),
+		User:         user,
 	})
 }
 
@@ -129,12 +241,6 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// 验证新密码复杂度
-	if !utils.ValidatePassword(req.NewPassword) {
-		utils.BadRequest(c, "密码至少8位，需包含大小写字母、数字和特殊符号")
-		return
-	}
-
 	userID, _ := c.Get("userID")
 	db := config.GetDB()
 	var user models.User
@@ -143,12 +249,28 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 目录账号的密码由企业目录维护
+	if user.AuthSource == config.AuthSourceLDAP {
+		utils.Error(c, 400, "目录账号请在企业目录中修改密码")
+		return
+	}
+
 	// 验证旧密码
 	if !utils.CheckPassword(user.Password, req.OldPassword) {
 		utils.Error(c, 400, "原密码错误")
 		return
 	}
 
+	// 按密码策略校验新密码，并拒绝重复使用最近的密码
+	if err := utils.ValidatePassword(req.NewPassword, user.Username); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if passwordReused(&user, req.NewPassword) {
+		utils.BadRequest(c, fmt.Sprintf("新密码不能与最近%d次使用过的密码相同", config.Password.HistoryDepth))
+		return
+	}
+
 	// 更新密码
 	hashedPassword, err := utils.HashPassword(req.NewPassword)
 	if err != nil {
@@ -156,13 +278,22 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 原密码计入历史记录
+	recordPasswordHistory(user.ID, user.Password)
+
+	now := time.Now()
 	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
 	db.Save(&user)
 
+	// 修改密码后吊销所有会话，需重新登录
+	revokeUserSessions(user.ID)
+
 	// 记录日志
 	middleware.LogOperation(c, "change_password", "auth", "user", user.ID, user.Name, "修改密码", "success")
 
-	utils.SuccessWithMessage(c, "密码修改成功", nil)
+	utils.SuccessWithMessage(c, "密码修改成功，请重新登录", nil)
 }
 
 // Logout 用户退出
@@ -170,6 +301,9 @@ func (ac *AuthController) Logout(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	username, _ := c.Get("username")
 
+	// 吊销该用户的所有会话
+	revokeUserSessions(userID.(uint))
+
 	// 记录退出日志
 	go func() {
 		log := models.OperationLog{
@@ -187,3 +321,126 @@ func (ac *AuthController) Logout(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "退出成功", nil)
 }
+
+// issueTokens 为用户创建新会话并签发访问Token和刷新Token
+func issueTokens(c *gin.Context, user *models.User) (*LoginResponse, error) {
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := models.UserSession{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		IPAddress:        c.ClientIP(),
+		UserAgent:        c.Request.UserAgent(),
+		ExpiresAt:        time.Now().Add(config.RefreshExpireTime),
+	}
+	if err := config.GetDB().Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Username, roleCodeOf(user), session.ID, user.MustChangePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.JWTExpireTime.Seconds()),
+		User:         *user,
+	}, nil
+}
+
+// recordLogin 记录登录日志
+func recordLogin(c *gin.Context, userID uint, description string) {
+	ip := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+	go func() {
+		log := models.OperationLog{
+			UserID:      userID,
+			Action:      "login",
+			Module:      "auth",
+			Description: description,
+			Result:      "success",
+			IPAddress:   ip,
+			UserAgent:   userAgent,
+			CreatedAt:   time.Now(),
+		}
+		config.GetDB().Create(&log)
+	}()
+}
+
+// GetPasswordPolicy 获取密码策略（用于前端提示）
+func (ac *AuthController) GetPasswordPolicy(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"policy":      config.Password,
+		"description": utils.PasswordPolicyDescription(),
+	})
+}
+
+// passwordExpired 判断本地账号的密码是否已超过有效期（从未修改过时按账号创建时间计算）
+func passwordExpired(user *models.User) bool {
+	if config.Password.MaxAgeDays <= 0 || user.AuthSource == config.AuthSourceLDAP || user.MustChangePassword {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(config.Password.MaxAgeDays)*24*time.Hour
+}
+
+// passwordReused 判断新密码是否与当前密码或最近使用过的密码相同
+func passwordReused(user *models.User, password string) bool {
+	depth := config.Password.HistoryDepth
+	if depth <= 0 {
+		return false
+	}
+	if utils.CheckPassword(user.Password, password) {
+		return true
+	}
+
+	// 当前密码占用一个名额
+	var history []models.PasswordHistory
+	config.GetDB().Where("user_id = ?", user.ID).Order("id DESC").Limit(depth - 1).Find(&history)
+	for _, h := range history {
+		if utils.CheckPassword(h.PasswordHash, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPasswordHistory 记录被替换的密码，只保留策略要求的条数
+func recordPasswordHistory(userID uint, passwordHash string) {
+	depth := config.Password.HistoryDepth
+	if depth <= 1 || passwordHash == "" {
+		return
+	}
+	db := config.GetDB()
+	db.Create(&models.PasswordHistory{UserID: userID, PasswordHash: passwordHash})
+
+	var stale []uint
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Offset(depth-1).Pluck("id", &stale)
+	if len(stale) > 0 {
+		db.Delete(&models.PasswordHistory{}, stale)
+	}
+}
+
+// revokeUserSessions 吊销用户的所有有效会话
+func revokeUserSessions(userID uint) {
+	config.GetDB().Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+}
+
+// roleCodeOf 获取用户角色编码
+func roleCodeOf(user *models.User) string {
+	if user.Role != nil {
+		return user.Role.Code
+	}
+	return ""
+}
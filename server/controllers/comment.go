@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentController struct{}
+
+// commentTarget 评论对象
+type commentTarget struct {
+	ProjectID uint
+	Name      string
+}
+
+// 评论对象类型名称（用于通知文案）
+var commentTargetLabels = map[string]string{
+	models.CommentTargetTask:     "任务",
+	models.CommentTargetDocument: "文档",
+	models.CommentTargetContract: "合同",
+}
+
+// resolveCommentTarget 加载评论对象并校验查看权限，失败时已写入响应
+func resolveCommentTarget(c *gin.Context, db *gorm.DB, targetType string, targetID uint) (*commentTarget, bool) {
+	var target commentTarget
+	roleCode, _ := c.Get("roleCode")
+
+	switch targetType {
+	case models.CommentTargetTask:
+		var task models.Task
+		if err := db.First(&task, targetID).Error; err != nil {
+			utils.NotFound(c, "任务不存在")
+			return nil, false
+		}
+		target = commentTarget{ProjectID: task.ProjectID, Name: task.TaskName}
+	case models.CommentTargetDocument:
+		if !middleware.HasPermission(roleCode.(string), config.PermDocumentView) {
+			utils.Forbidden(c, "没有权限查看文档")
+			return nil, false
+		}
+		var doc models.Document
+		if err := db.First(&doc, targetID).Error; err != nil {
+			utils.NotFound(c, "文档不存在")
+			return nil, false
+		}
+		target = commentTarget{ProjectID: doc.ProjectID, Name: doc.DocName}
+	case models.CommentTargetContract:
+		if !middleware.HasPermission(roleCode.(string), config.PermContractView) {
+			utils.Forbidden(c, "没有权限查看合同")
+			return nil, false
+		}
+		var contract models.Contract
+		if err := db.First(&contract, targetID).Error; err != nil {
+			utils.NotFound(c, "合同不存在")
+			return nil, false
+		}
+		target = commentTarget{ProjectID: contract.ProjectID, Name: contract.ContractName}
+	default:
+		utils.BadRequest(c, "评论对象类型无效")
+		return nil, false
+	}
+
+	if !getProjectAccess(c).canView(target.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return nil, false
+	}
+	return &target, true
+}
+
+// mentionedUsers 解析评论中 @ 的用户，只有项目负责人、子负责人和项目成员可以被 @
+func mentionedUsers(db *gorm.DB, projectID uint, content string) []uint {
+	names := utils.ParseMentions(content)
+	if len(names) == 0 {
+		return nil
+	}
+	var ids []uint
+	db.Model(&models.User{}).
+		Where("username IN ?", names).
+		Where("id IN (?) OR id IN (?) OR id IN (?)",
+			db.Model(&models.Project{}).Select("manager_id").Where("id = ?", projectID),
+			db.Model(&models.Project{}).Select("sub_manager_id").Where("id = ? AND sub_manager_id IS NOT NULL", projectID),
+			db.Model(&models.ProjectMember{}).Select("user_id").Where("project_id = ?", projectID)).
+		Pluck("id", &ids)
+	return ids
+}
+
+// notifyMentions 通知被 @ 的用户
+func notifyMentions(db *gorm.DB, comment *models.Comment, target *commentTarget, userIDs []uint, senderName string) {
+	notifyUsers(db, userIDs, models.Notification{
+		Type:       models.NotificationMention,
+		Title:      fmt.Sprintf("%s 在%s「%s」中提到了你", senderName, commentTargetLabels[comment.TargetType], target.Name),
+		Content:    comment.Content,
+		TargetType: comment.TargetType,
+		TargetID:   comment.TargetID,
+		ProjectID:  comment.ProjectID,
+		SenderID:   comment.AuthorID,
+	})
+}
+
+// List 获取评论列表（树形，已删除但仍有回复的评论保留占位）
+func (cc *CommentController) List(c *gin.Context) {
+	targetType := c.Query("target_type")
+	targetID, _ := strconv.ParseUint(c.Query("target_id"), 10, 32)
+
+	db := config.GetDB()
+	if _, ok := resolveCommentTarget(c, db, targetType, uint(targetID)); !ok {
+		return
+	}
+
+	var comments []models.Comment
+	db.Unscoped().Preload("Author").Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("id").Find(&comments)
+
+	replies := make(map[uint][]models.Comment)
+	var roots []models.Comment
+	for _, cm := range comments {
+		if cm.ParentID == nil {
+			roots = append(roots, cm)
+		} else {
+			replies[*cm.ParentID] = append(replies[*cm.ParentID], cm)
+		}
+	}
+
+	var build func(cm models.Comment) (models.Comment, bool)
+	build = func(cm models.Comment) (models.Comment, bool) {
+		for _, r := range replies[cm.ID] {
+			if reply, ok := build(r); ok {
+				cm.Replies = append(cm.Replies, reply)
+			}
+		}
+		if cm.DeletedAt.Valid {
+			if len(cm.Replies) == 0 {
+				return cm, false
+			}
+			cm.Deleted, cm.Content = true, ""
+		}
+		return cm, true
+	}
+
+	result := make([]models.Comment, 0, len(roots))
+	for _, root := range roots {
+		if cm, ok := build(root); ok {
+			result = append(result, cm)
+		}
+	}
+	utils.Success(c, result)
+}
+
+// CreateCommentRequest 发表评论请求
+type CreateCommentRequest struct {
+	TargetType string `json:"target_type" binding:"required"`
+	TargetID   uint   `json:"target_id" binding:"required"`
+	ParentID   *uint  `json:"parent_id"`
+	Content    string `json:"content" binding:"required"`
+}
+
+// Create 发表评论或回复
+func (cc *CommentController) Create(c *gin.Context) {
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请输入评论内容")
+		return
+	}
+
+	db := config.GetDB()
+	target, ok := resolveCommentTarget(c, db, req.TargetType, req.TargetID)
+	if !ok {
+		return
+	}
+
+	var parent models.Comment
+	if req.ParentID != nil {
+		if err := db.Where("id = ? AND target_type = ? AND target_id = ?", *req.ParentID, req.TargetType, req.TargetID).
+			First(&parent).Error; err != nil {
+			utils.NotFound(c, "回复的评论不存在")
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	mentions := mentionedUsers(db, target.ProjectID, req.Content)
+	mentionsJSON, _ := json.Marshal(mentions)
+	comment := models.Comment{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		ProjectID:  target.ProjectID,
+		ParentID:   req.ParentID,
+		Content:    req.Content,
+		Mentions:   string(mentionsJSON),
+		AuthorID:   userID.(uint),
+	}
+	if err := db.Create(&comment).Error; err != nil {
+		utils.ServerError(c, "发表评论失败")
+		return
+	}
+	db.Preload("Author").First(&comment, comment.ID)
+
+	// 通知被 @ 的用户和被回复的评论作者
+	senderName := ""
+	if comment.Author != nil {
+		senderName = comment.Author.Name
+	}
+	notifyMentions(db, &comment, target, mentions, senderName)
+	if req.ParentID != nil {
+		notifyUsers(db, []uint{parent.AuthorID}, models.Notification{
+			Type:       models.NotificationReply,
+			Title:      fmt.Sprintf("%s 回复了你在%s「%s」中的评论", senderName, commentTargetLabels[req.TargetType], target.Name),
+			Content:    comment.Content,
+			TargetType: comment.TargetType,
+			TargetID:   comment.TargetID,
+			ProjectID:  comment.ProjectID,
+			SenderID:   comment.AuthorID,
+		})
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "create", "comment", req.TargetType, req.TargetID, target.Name, "发表评论", "success")
+
+	utils.SuccessWithMessage(c, "发表成功", comment)
+}
+
+// UpdateCommentRequest 编辑评论请求
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// Update 编辑评论（只有作者可编辑，编辑前的内容保存到修改历史）
+func (cc *CommentController) Update(c *gin.Context) {
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请输入评论内容")
+		return
+	}
+
+	db := config.GetDB()
+	var comment models.Comment
+	if err := db.First(&comment, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "评论不存在")
+		return
+	}
+	target, ok := resolveCommentTarget(c, db, comment.TargetType, comment.TargetID)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if comment.AuthorID != userID.(uint) {
+		utils.Forbidden(c, "只能编辑自己的评论")
+		return
+	}
+	if req.Content == comment.Content {
+		utils.SuccessWithMessage(c, "更新成功", comment)
+		return
+	}
+
+	// 只通知新增的 @ 用户
+	var oldMentions []uint
+	json.Unmarshal([]byte(comment.Mentions), &oldMentions)
+	notified := make(map[uint]bool, len(oldMentions))
+	for _, id := range oldMentions {
+		notified[id] = true
+	}
+	mentions := mentionedUsers(db, target.ProjectID, req.Content)
+	var newMentions []uint
+	for _, id := range mentions {
+		if !notified[id] {
+			newMentions = append(newMentions, id)
+		}
+	}
+	mentionsJSON, _ := json.Marshal(mentions)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.CommentRevision{CommentID: comment.ID, Content: comment.Content, Action: "edit", EditorID: userID.(uint)}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&comment).Updates(map[string]interface{}{
+			"content":   req.Content,
+			"mentions":  string(mentionsJSON),
+			"edited_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "更新失败")
+		return
+	}
+	db.Preload("Author").First(&comment, comment.ID)
+
+	if comment.Author != nil {
+		notifyMentions(db, &comment, target, newMentions, comment.Author.Name)
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", comment)
+}
+
+// Delete 删除评论（作者、项目负责人或管理员可删除，删除前的内容保存到修改历史）
+func (cc *CommentController) Delete(c *gin.Context) {
+	db := config.GetDB()
+	var comment models.Comment
+	if err := db.First(&comment, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "评论不存在")
+		return
+	}
+	target, ok := resolveCommentTarget(c, db, comment.TargetType, comment.TargetID)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if comment.AuthorID != userID.(uint) {
+		var project models.Project
+		db.First(&project, target.ProjectID)
+		if !canManageProjectTasks(c, &project) {
+			utils.Forbidden(c, "只能删除自己的评论")
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.CommentRevision{CommentID: comment.ID, Content: comment.Content, Action: "delete", EditorID: userID.(uint)}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&comment).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "delete", "comment", comment.TargetType, comment.TargetID, target.Name, "删除评论", "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Revisions 获取评论修改历史
+func (cc *CommentController) Revisions(c *gin.Context) {
+	db := config.GetDB()
+	var comment models.Comment
+	if err := db.Unscoped().First(&comment, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "评论不存在")
+		return
+	}
+	if _, ok := resolveCommentTarget(c, db, comment.TargetType, comment.TargetID); !ok {
+		return
+	}
+
+	var revisions []models.CommentRevision
+	db.Preload("Editor").Where("comment_id = ?", comment.ID).Order("id DESC").Find(&revisions)
+	utils.Success(c, revisions)
+}
@@ -43,9 +43,7 @@ func (cc *ContractController) List(c *gin.Context) {
 	var contracts []models.Contract
 	var total int64
 
-	query := db.Model(&models.Contract{})
-
-	// 所有角色都可以查看所有合同
+	query := db.Model(&models.Contract{}).Scopes(getProjectAccess(c).scope("project_id"))
 
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
@@ -67,6 +65,10 @@ func (cc *ContractController) Create(c *gin.Context) {
 		utils.BadRequest(c, "请填写合同名称")
 		return
 	}
+	if !getProjectAccess(c).canView(req.ProjectID) {
+		utils.Forbidden(c, "没有权限为该项目创建合同")
+		return
+	}
 
 	userID, _ := c.Get("userID")
 	db := config.GetDB()
@@ -124,11 +126,38 @@ func (cc *ContractController) Get(c *gin.Context) {
 		return
 	}
 
-	// 所有角色都可以查看合同详情
+	if !getProjectAccess(c).canView(contract.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该合同")
+		return
+	}
 
 	utils.Success(c, contract)
 }
 
+// DownloadURL 获取合同文件的签名下载地址
+func (cc *ContractController) DownloadURL(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var contract models.Contract
+	if err := db.First(&contract, id).Error; err != nil {
+		utils.NotFound(c, "合同不存在")
+		return
+	}
+
+	if !getProjectAccess(c).canView(contract.ProjectID) {
+		utils.Forbidden(c, "没有权限下载该合同")
+		return
+	}
+
+	if contract.FilePath == "" {
+		utils.NotFound(c, "该合同尚未上传文件")
+		return
+	}
+
+	utils.Success(c, signedDownloadURL(FileResourceContract, contract.ID, 0))
+}
+
 // UpdateContractRequest 更新合同请求
 type UpdateContractRequest struct {
 	ContractName  string  `json:"contract_name"`
@@ -158,6 +187,10 @@ func (cc *ContractController) Update(c *gin.Context) {
 		utils.NotFound(c, "合同不存在")
 		return
 	}
+	if !getProjectAccess(c).canView(contract.ProjectID) {
+		utils.Forbidden(c, "没有权限修改该合同")
+		return
+	}
 
 	updates := make(map[string]interface{})
 	if req.ContractName != "" {
@@ -218,6 +251,10 @@ func (cc *ContractController) UploadFile(c *gin.Context) {
 		utils.NotFound(c, "合同不存在")
 		return
 	}
+	if !getProjectAccess(c).canView(contract.ProjectID) {
+		utils.Forbidden(c, "没有权限修改该合同")
+		return
+	}
 
 	// 创建上传目录
 	uploadDir := filepath.Join(config.UploadPath, "contracts", time.Now().Format("200601"))
@@ -257,14 +294,16 @@ func (cc *ContractController) Delete(c *gin.Context) {
 		utils.NotFound(c, "合同不存在")
 		return
 	}
-
-	// 删除文件
-	if contract.FilePath != "" {
-		os.Remove(contract.FilePath)
+	if !getProjectAccess(c).canView(contract.ProjectID) {
+		utils.Forbidden(c, "没有权限删除该合同")
+		return
 	}
 
 	db.Delete(&contract)
 
+	// 删除文件（克隆项目引用的同一文件仍在使用时保留）
+	removeUnreferencedFile(contract.FilePath)
+
 	// 记录日志
 	middleware.LogOperation(c, "delete", "contract", "contract", contract.ID, contract.ContractName, "删除合同: "+contract.ContractName, "success")
 
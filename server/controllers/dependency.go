@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/schedule"
+	"project-flow/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 依赖类型说明
+var dependencyTypeLabels = map[string]string{
+	models.DependencyFinishToStart:  "完成-开始",
+	models.DependencyStartToStart:   "开始-开始",
+	models.DependencyFinishToFinish: "完成-完成",
+}
+
+// canManageProjectTasks 项目负责人或管理员
+func canManageProjectTasks(c *gin.Context, project *models.Project) bool {
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+	return roleCode == config.RoleAdmin || (project != nil && project.ManagerID == userID.(uint))
+}
+
+// GetDependencies 获取任务的前置和后续依赖
+func (tc *TaskController) GetDependencies(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	var predecessors, successors []models.TaskDependency
+	db.Preload("Predecessor").Where("successor_id = ?", task.ID).Find(&predecessors)
+	db.Preload("Successor").Where("predecessor_id = ?", task.ID).Find(&successors)
+
+	utils.Success(c, gin.H{
+		"predecessors": predecessors,
+		"successors":   successors,
+	})
+}
+
+// AddDependencyRequest 添加前置任务请求
+type AddDependencyRequest struct {
+	PredecessorID uint   `json:"predecessor_id" binding:"required"`
+	Type          string `json:"type"` // FS/SS/FF，默认FS
+	Lag           int    `json:"lag"`
+}
+
+// AddDependency 为任务添加前置任务（只有项目负责人或管理员可操作）
+func (tc *TaskController) AddDependency(c *gin.Context) {
+	var req AddDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请选择前置任务")
+		return
+	}
+	if req.Type == "" {
+		req.Type = models.DependencyFinishToStart
+	}
+	if _, ok := dependencyTypeLabels[req.Type]; !ok {
+		utils.BadRequest(c, "依赖类型无效")
+		return
+	}
+
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !canManageProjectTasks(c, task.Project) {
+		utils.Forbidden(c, "只有项目负责人才能设置任务依赖")
+		return
+	}
+
+	var predecessor models.Task
+	if err := db.First(&predecessor, req.PredecessorID).Error; err != nil {
+		utils.NotFound(c, "前置任务不存在")
+		return
+	}
+	if predecessor.ProjectID != task.ProjectID {
+		utils.BadRequest(c, "只能依赖同一项目内的任务")
+		return
+	}
+	if predecessor.ID == task.ID {
+		utils.BadRequest(c, "任务不能依赖自身")
+		return
+	}
+
+	var existing []models.TaskDependency
+	db.Where("project_id = ?", task.ProjectID).Find(&existing)
+	for _, d := range existing {
+		if d.PredecessorID == predecessor.ID && d.SuccessorID == task.ID {
+			utils.Error(c, 400, "该依赖已存在")
+			return
+		}
+	}
+	// 已存在从当前任务到前置任务的路径时，新增依赖会形成循环
+	if schedule.HasPath(schedule.Edges(existing), task.ID, predecessor.ID) {
+		utils.Error(c, 400, "添加后任务依赖将形成循环")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	dep := models.TaskDependency{
+		ProjectID:     task.ProjectID,
+		PredecessorID: predecessor.ID,
+		SuccessorID:   task.ID,
+		Type:          req.Type,
+		Lag:           req.Lag,
+		CreatedBy:     userID.(uint),
+	}
+	if err := db.Create(&dep).Error; err != nil {
+		utils.ServerError(c, "添加依赖失败")
+		return
+	}
+
+	// 记录日志
+	description := fmt.Sprintf("添加前置任务: %s（%s）", predecessor.TaskName, dependencyTypeLabels[req.Type])
+	middleware.LogOperation(c, "add_dependency", "task", "task", task.ID, task.TaskName, description, "success")
+
+	utils.SuccessWithMessage(c, "添加成功", dep)
+}
+
+// RemoveDependency 删除任务依赖（只有项目负责人或管理员可操作）
+func (tc *TaskController) RemoveDependency(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !canManageProjectTasks(c, task.Project) {
+		utils.Forbidden(c, "只有项目负责人才能设置任务依赖")
+		return
+	}
+
+	var dep models.TaskDependency
+	if err := db.Preload("Predecessor").Where("id = ? AND (successor_id = ? OR predecessor_id = ?)", c.Param("depId"), task.ID, task.ID).
+		First(&dep).Error; err != nil {
+		utils.NotFound(c, "依赖不存在")
+		return
+	}
+	db.Delete(&dep)
+
+	// 记录日志
+	description := "删除任务依赖"
+	if dep.Predecessor != nil {
+		description = "删除前置任务: " + dep.Predecessor.TaskName
+	}
+	middleware.LogOperation(c, "remove_dependency", "task", "task", task.ID, task.TaskName, description, "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// blockingPredecessors 返回阻止任务变更为目标状态的前置任务：
+// 开始任务要求 FS 前置任务已完成、SS 前置任务已开始；完成任务还要求 FF 前置任务已完成
+func blockingPredecessors(db *gorm.DB, task *models.Task, status string) []gin.H {
+	if status != config.TaskInProgress && status != config.TaskCompleted {
+		return nil
+	}
+
+	var deps []models.TaskDependency
+	db.Preload("Predecessor").Where("successor_id = ?", task.ID).Find(&deps)
+
+	var blocking []gin.H
+	for _, d := range deps {
+		if d.Predecessor == nil {
+			continue
+		}
+		var satisfied bool
+		switch d.Type {
+		case models.DependencyStartToStart:
+			satisfied = d.Predecessor.Status != config.TaskNotStarted
+		case models.DependencyFinishToFinish:
+			satisfied = status != config.TaskCompleted || d.Predecessor.Status == config.TaskCompleted
+		default:
+			satisfied = d.Predecessor.Status == config.TaskCompleted
+		}
+		if !satisfied {
+			blocking = append(blocking, gin.H{
+				"task_id":   d.Predecessor.ID,
+				"task_name": d.Predecessor.TaskName,
+				"status":    d.Predecessor.Status,
+				"type":      d.Type,
+			})
+		}
+	}
+	return blocking
+}
+
+// Schedule 计算项目任务排程（最早/最晚时间、浮动时间和关键路径）
+func (pc *ProjectController) Schedule(c *gin.Context) {
+	pid, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	db := config.GetDB()
+	var project models.Project
+	if err := db.First(&project, pid).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+
+	result, err := schedule.ForProject(db, &project)
+	if err != nil {
+		utils.Error(c, 400, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
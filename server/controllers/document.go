@@ -30,7 +30,8 @@ func (dc *DocumentController) List(c *gin.Context) {
 	var docs []models.Document
 	var total int64
 
-	query := db.Model(&models.Document{}).Preload("Uploader").Preload("Phase")
+	query := db.Model(&models.Document{}).Preload("Uploader").Preload("Phase").
+		Scopes(getProjectAccess(c).scope("project_id"))
 
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
@@ -80,6 +81,10 @@ func (dc *DocumentController) Upload(c *gin.Context) {
 		utils.BadRequest(c, "项目参数错误")
 		return
 	}
+	if !getProjectAccess(c).canView(uint(projectIDUint64)) {
+		utils.Forbidden(c, "没有权限向该项目上传文档")
+		return
+	}
 
 	var phaseIDUint64 uint64
 	if phaseIDStr != "" {
@@ -230,6 +235,11 @@ func (dc *DocumentController) Get(c *gin.Context) {
 		return
 	}
 
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该文档")
+		return
+	}
+
 	utils.Success(c, doc)
 }
 
@@ -244,6 +254,11 @@ func (dc *DocumentController) Download(c *gin.Context) {
 		return
 	}
 
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限下载该文档")
+		return
+	}
+
 	// 检查文件是否存在
 	if _, err := os.Stat(doc.FilePath); os.IsNotExist(err) {
 		utils.NotFound(c, "文件不存在")
@@ -255,11 +270,29 @@ func (dc *DocumentController) Download(c *gin.Context) {
 	c.File(doc.FilePath)
 }
 
+// DownloadURL 获取文档的签名下载地址
+func (dc *DocumentController) DownloadURL(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var doc models.Document
+	if err := db.First(&doc, id).Error; err != nil {
+		utils.NotFound(c, "文档不存在")
+		return
+	}
+
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限下载该文档")
+		return
+	}
+
+	utils.Success(c, signedDownloadURL(FileResourceDocument, doc.ID, 0))
+}
+
 // UpdateDocRequest 更新文档请求
 type UpdateDocRequest struct {
 	DocName string `json:"doc_name"`
 	DocType string `json:"doc_type"`
-	Status  string `json:"status"`
 	Remark  string `json:"remark"`
 }
 
@@ -279,16 +312,25 @@ func (dc *DocumentController) Update(c *gin.Context) {
 		utils.NotFound(c, "文档不存在")
 		return
 	}
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限修改该文档")
+		return
+	}
 
 	updates := make(map[string]interface{})
 	if req.DocName != "" {
 		updates["doc_name"] = req.DocName
 	}
-	if req.DocType != "" {
+	if req.DocType != "" && req.DocType != doc.DocType {
 		updates["doc_type"] = req.DocType
-	}
-	if req.Status != "" {
-		updates["status"] = req.Status
+		// 资料类型变化后原审核结论不再适用，需要重新审核
+		if doc.ApprovedAt != nil {
+			updates["approved_by"] = nil
+			updates["approved_at"] = nil
+			if doc.Status == "approved" {
+				updates["status"] = "pending"
+			}
+		}
 	}
 	if req.Remark != "" {
 		updates["remark"] = req.Remark
@@ -326,6 +368,10 @@ func (dc *DocumentController) Delete(c *gin.Context) {
 		utils.NotFound(c, "文档不存在")
 		return
 	}
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限删除该文档")
+		return
+	}
 
 	// 权限检查：
 	// 1. 如果是任务交付件，且任务已完成，只有项目经理有权限删除
@@ -357,22 +403,67 @@ func (dc *DocumentController) Delete(c *gin.Context) {
 		}
 	}
 
-	// 删除文件
-	if doc.FilePath != "" {
-		os.Remove(doc.FilePath)
-	}
-
 	if err := db.Unscoped().Delete(&doc).Error; err != nil {
 		utils.ServerError(c, "删除失败")
 		return
 	}
 
+	// 删除文件（克隆项目引用的同一文件仍在使用时保留）
+	removeUnreferencedFile(doc.FilePath)
+
 	// 记录日志
 	middleware.LogOperation(c, "delete", "document", "document", doc.ID, doc.DocName, "删除文档: "+doc.DocName, "success")
 
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// Approve 审核通过资料：项目负责人、子负责人、部门经理或管理员可操作，上传者不能审核自己上传的资料
+func (dc *DocumentController) Approve(c *gin.Context) {
+	db := config.GetDB()
+	var doc models.Document
+	if err := db.First(&doc, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "文档不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限审核该文档")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+	operatorID := userID.(uint)
+	var project models.Project
+	if err := db.First(&project, doc.ProjectID).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+	isReviewer := project.ManagerID == operatorID || (project.SubManagerID != nil && *project.SubManagerID == operatorID)
+	if !isReviewer && roleCode != config.RoleDeptManager && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "只有项目负责人、子负责人或部门经理才能审核资料")
+		return
+	}
+	if doc.UploadedBy == operatorID && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "不能审核自己上传的资料")
+		return
+	}
+	if doc.ApprovedAt != nil {
+		utils.Error(c, 400, "资料已审核通过")
+		return
+	}
+
+	updates := map[string]interface{}{"approved_by": operatorID, "approved_at": time.Now()}
+	if doc.Status != "archived" {
+		updates["status"] = "approved"
+	}
+	db.Model(&doc).Updates(updates)
+
+	// 记录日志
+	middleware.LogOperation(c, "approve", "document", "document", doc.ID, doc.DocName, "审核通过资料: "+doc.DocName, "success")
+
+	utils.SuccessWithMessage(c, "审核通过", nil)
+}
+
 // Archive 归档文档
 func (dc *DocumentController) Archive(c *gin.Context) {
 	id := c.Param("id")
@@ -383,6 +474,10 @@ func (dc *DocumentController) Archive(c *gin.Context) {
 		utils.NotFound(c, "文档不存在")
 		return
 	}
+	if !getProjectAccess(c).canView(doc.ProjectID) {
+		utils.Forbidden(c, "没有权限归档该文档")
+		return
+	}
 
 	db.Model(&doc).Update("status", "archived")
 
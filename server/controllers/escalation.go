@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/escalation"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEscalationPolicy 获取项目的逾期提醒策略（未单独配置时返回默认策略）
+func (pc *ProjectController) GetEscalationPolicy(c *gin.Context) {
+	db := config.GetDB()
+	var project models.Project
+	if err := db.First(&project, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(project.ID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	var count int64
+	db.Model(&models.EscalationPolicy{}).Where("project_id = ?", project.ID).Count(&count)
+	utils.Success(c, gin.H{
+		"policy":     escalation.PolicyFor(db, project.ID),
+		"is_default": count == 0,
+	})
+}
+
+// EscalationPolicyRequest 逾期提醒策略请求
+type EscalationPolicyRequest struct {
+	Enabled         bool `json:"enabled"`
+	RemindHours     int  `json:"remind_hours"`
+	ManagerDays     int  `json:"manager_days"`
+	DeptManagerDays int  `json:"dept_manager_days"`
+}
+
+// SaveEscalationPolicy 保存项目的逾期提醒策略（项目负责人）
+func (pc *ProjectController) SaveEscalationPolicy(c *gin.Context) {
+	var req EscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if req.RemindHours < 0 || req.ManagerDays < 0 || req.DeptManagerDays < 0 {
+		utils.BadRequest(c, "提醒时间不能为负数")
+		return
+	}
+	if req.ManagerDays > 0 && req.DeptManagerDays > 0 && req.DeptManagerDays < req.ManagerDays {
+		utils.BadRequest(c, "通知部门经理的时间不能早于通知项目负责人")
+		return
+	}
+
+	db := config.GetDB()
+	var project models.Project
+	if err := db.First(&project, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+	if !canManageProjectTasks(c, &project) {
+		utils.Forbidden(c, "只有项目负责人才能配置逾期提醒")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var policy models.EscalationPolicy
+	db.Where("project_id = ?", project.ID).First(&policy)
+	policy.ProjectID = project.ID
+	policy.Enabled = req.Enabled
+	policy.RemindHours = req.RemindHours
+	policy.ManagerDays = req.ManagerDays
+	policy.DeptManagerDays = req.DeptManagerDays
+	policy.UpdatedBy = userID.(uint)
+	if err := db.Save(&policy).Error; err != nil {
+		utils.ServerError(c, "保存失败")
+		return
+	}
+
+	// 记录日志
+	desc := "关闭逾期提醒"
+	if policy.Enabled {
+		desc = fmt.Sprintf("设置逾期提醒: 截止前%d小时提醒，逾期%d天通知项目负责人，逾期%d天通知部门经理",
+			policy.RemindHours, policy.ManagerDays, policy.DeptManagerDays)
+	}
+	middleware.LogOperation(c, "update_escalation", "project", "project", project.ID, project.Name, desc, "success")
+
+	utils.SuccessWithMessage(c, "保存成功", policy)
+}
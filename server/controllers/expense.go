@@ -36,7 +36,8 @@ func (ec *ExpenseController) List(c *gin.Context) {
 
 	query := db.Model(&models.Expense{}).
 		Preload("Project").
-		Preload("ReimbursedUser")
+		Preload("ReimbursedUser").
+		Scopes(getProjectAccess(c).expenseScope)
 
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
@@ -60,8 +61,6 @@ func (ec *ExpenseController) List(c *gin.Context) {
 		query = query.Where("reimbursed_by = ?", reimbursedBy)
 	}
 
-	// 所有用户都可以查看所有费用记录，不做权限过滤
-
 	query.Count(&total)
 	query.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_at DESC").Find(&expenses)
 
@@ -76,6 +75,7 @@ func (ec *ExpenseController) Get(c *gin.Context) {
 	var expense models.Expense
 	if err := db.Preload("Project").
 		Preload("ReimbursedUser").
+		Scopes(getProjectAccess(c).expenseScope).
 		First(&expense, id).Error; err != nil {
 		utils.NotFound(c, "费用记录不存在")
 		return
@@ -89,6 +89,30 @@ func (ec *ExpenseController) Get(c *gin.Context) {
 	utils.Success(c, expense)
 }
 
+// VoucherDownloadURL 获取费用凭证文件的签名下载地址（index 为凭证序号，从0开始）
+func (ec *ExpenseController) VoucherDownloadURL(c *gin.Context) {
+	id := c.Param("id")
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		utils.BadRequest(c, "凭证序号错误")
+		return
+	}
+
+	db := config.GetDB()
+	var expense models.Expense
+	if err := db.Scopes(getProjectAccess(c).expenseScope).First(&expense, id).Error; err != nil {
+		utils.NotFound(c, "费用记录不存在")
+		return
+	}
+
+	if index < 0 || index >= len(voucherPaths(&expense)) {
+		utils.NotFound(c, "凭证文件不存在")
+		return
+	}
+
+	utils.Success(c, signedDownloadURL(FileResourceVoucher, expense.ID, index))
+}
+
 type CreateExpenseRequest struct {
 	ProjectID            *uint   `json:"project_id"`
 	ProjectCode          string  `json:"project_code"`
@@ -113,6 +137,11 @@ func (ec *ExpenseController) Create(c *gin.Context) {
 		return
 	}
 
+	if req.ProjectID != nil && !getProjectAccess(c).canView(*req.ProjectID) {
+		utils.Forbidden(c, "没有权限为该项目录入费用")
+		return
+	}
+
 	userID, _ := c.Get("userID")
 	db := config.GetDB()
 
@@ -157,8 +186,9 @@ func (ec *ExpenseController) Update(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+	access := getProjectAccess(c)
 	var expense models.Expense
-	if err := db.First(&expense, id).Error; err != nil {
+	if err := db.Scopes(access.expenseScope).First(&expense, id).Error; err != nil {
 		utils.NotFound(c, "费用记录不存在")
 		return
 	}
@@ -170,6 +200,10 @@ func (ec *ExpenseController) Update(c *gin.Context) {
 		utils.Forbidden(c, "只能修改自己的费用记录")
 		return
 	}
+	if req.ProjectID != nil && !access.canView(*req.ProjectID) {
+		utils.Forbidden(c, "没有权限为该项目录入费用")
+		return
+	}
 
 	expense.ProjectID = req.ProjectID
 	expense.ProjectCode = req.ProjectCode
@@ -199,7 +233,7 @@ func (ec *ExpenseController) Delete(c *gin.Context) {
 
 	db := config.GetDB()
 	var expense models.Expense
-	if err := db.First(&expense, id).Error; err != nil {
+	if err := db.Scopes(getProjectAccess(c).expenseScope).First(&expense, id).Error; err != nil {
 		utils.NotFound(c, "费用记录不存在")
 		return
 	}
@@ -233,7 +267,7 @@ func (ec *ExpenseController) GetStatistics(c *gin.Context) {
 	projectID := c.Query("project_id")
 
 	db := config.GetDB()
-	query := db.Model(&models.Expense{})
+	query := db.Model(&models.Expense{}).Scopes(getProjectAccess(c).expenseScope)
 
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
@@ -255,10 +289,12 @@ func (ec *ExpenseController) GetStatistics(c *gin.Context) {
 // GetProjectComparison 获取项目费用对比统计
 func (ec *ExpenseController) GetProjectComparison(c *gin.Context) {
 	db := config.GetDB()
+	access := getProjectAccess(c)
 
-	// 获取所有项目及其预算
+	// 获取可见项目及其预算
 	var projects []models.Project
-	db.Select("id, name, innovation_code, labor_cost, direct_cost, outsourcing_cost, other_cost").Find(&projects)
+	db.Select("id, name, innovation_code, labor_cost, direct_cost, outsourcing_cost, other_cost").
+		Scopes(access.scope("id")).Find(&projects)
 
 	type ExpenseStat struct {
 		ProjectID    uint    `json:"project_id"`
@@ -273,6 +309,20 @@ func (ec *ExpenseController) GetProjectComparison(c *gin.Context) {
 		Group("project_id, expense_type").
 		Scan(&expenseStats)
 
+	// 已审批工时折算的人工成本（独立于报销导入的人工费用，不计入实际费用合计）
+	type LaborStat struct {
+		ProjectID uint
+		Hours     float64
+		Cost      float64
+	}
+	var laborStats []LaborStat
+	db.Model(&models.TimeEntry{}).
+		Select("time_entries.project_id, SUM(time_entries.hours) as hours, SUM(time_entries.cost) as cost").
+		Joins("JOIN timesheets ON timesheets.id = time_entries.timesheet_id").
+		Where("timesheets.status = ?", models.TimesheetApproved).
+		Group("time_entries.project_id").
+		Scan(&laborStats)
+
 	// 构建统计结果
 	type ProjectComparison struct {
 		ProjectID                uint    `json:"project_id"`
@@ -290,6 +340,8 @@ func (ec *ExpenseController) GetProjectComparison(c *gin.Context) {
 		OtherBudget              float64 `json:"other_budget"`
 		OtherActualInclTax       float64 `json:"other_actual_incl_tax"`
 		OtherActualExclTax       float64 `json:"other_actual_excl_tax"`
+		TimesheetHours           float64 `json:"timesheet_hours"`      // 已审批工时
+		TimesheetLaborCost       float64 `json:"timesheet_labor_cost"` // 工时折算人工成本
 		TotalBudget              float64 `json:"total_budget"`
 		TotalActualInclTax       float64 `json:"total_actual_incl_tax"`
 		TotalActualExclTax       float64 `json:"total_actual_excl_tax"`
@@ -327,6 +379,13 @@ func (ec *ExpenseController) GetProjectComparison(c *gin.Context) {
 			}
 		}
 
+		for _, stat := range laborStats {
+			if stat.ProjectID == project.ID {
+				comp.TimesheetHours = stat.Hours
+				comp.TimesheetLaborCost = stat.Cost
+			}
+		}
+
 		// 计算总计
 		comp.TotalBudget = comp.LaborBudget + comp.DirectBudget + comp.OutsourcingBudget + comp.OtherBudget
 		comp.TotalActualInclTax = comp.LaborActualInclTax + comp.DirectActualInclTax + comp.OutsourcingActualInclTax + comp.OtherActualInclTax
@@ -350,6 +409,7 @@ func (ec *ExpenseController) GetNonProjectExpenseStats(c *gin.Context) {
 
 	var stats []BusinessSceneStat
 	db.Model(&models.Expense{}).
+		Scopes(getProjectAccess(c).expenseScope).
 		Select("business_scene, SUM(reimbursement_amount) as total_incl_tax, SUM(allocation_amount) as total_excl_tax").
 		Where("project_id IS NULL AND (expense_type IS NULL OR expense_type = '')").
 		Group("business_scene").
@@ -623,15 +683,9 @@ func (ec *ExpenseController) ImportExpenses(c *gin.Context) {
 	}
 }
 
-// DeleteAll 一键删除所有费用记录（仅管理员）
+// DeleteAll 一键删除所有费用记录
 func (ec *ExpenseController) DeleteAll(c *gin.Context) {
-	// 权限检查：只有管理员可以一键删除
-	roleCode, _ := c.Get("roleCode")
-	if roleCode != config.RoleAdmin {
-		utils.Forbidden(c, "只有管理员可以执行一键删除操作")
-		return
-	}
-
+	// 权限检查由路由上的 expense:delete_all 权限完成
 	db := config.GetDB()
 
 	// 查询所有记录数量
@@ -678,6 +732,7 @@ func (ec *ExpenseController) Export(c *gin.Context) {
 	if err := db.
 		Preload("Project").
 		Preload("ReimbursedUser").
+		Scopes(getProjectAccess(c).expenseScope).
 		Order("created_at DESC").
 		Find(&expenses).Error; err != nil {
 		utils.ServerError(c, "查询费用记录失败")
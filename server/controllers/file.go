@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileController struct{}
+
+// 签名下载支持的资源类型
+const (
+	FileResourceDocument  = "document"  // 项目文档
+	FileResourceKnowledge = "knowledge" // 知识库资料
+	FileResourceContract  = "contract"  // 合同文件
+	FileResourceVoucher   = "voucher"   // 费用凭证
+)
+
+// Download 通过签名URL下载文件（无需登录态，签名只对单个文件有效且几分钟后过期）
+func (fc *FileController) Download(c *gin.Context) {
+	resource := c.Param("resource")
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	index, err2 := strconv.Atoi(c.DefaultQuery("file", "0"))
+	expires, err3 := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil ||
+		!utils.VerifyDownload(resource, uint(id), index, expires, c.Query("signature")) {
+		utils.Forbidden(c, "下载链接无效或已过期")
+		return
+	}
+
+	db := config.GetDB()
+	var filePath, fileName, mimeType string
+
+	switch resource {
+	case FileResourceDocument:
+		var doc models.Document
+		if err := db.First(&doc, id).Error; err != nil {
+			utils.NotFound(c, "文档不存在")
+			return
+		}
+		filePath, fileName, mimeType = doc.FilePath, doc.DocName, doc.MimeType
+	case FileResourceKnowledge:
+		var kb models.KnowledgeBase
+		if err := db.First(&kb, id).Error; err != nil {
+			utils.NotFound(c, "资料不存在")
+			return
+		}
+		filePath, fileName, mimeType = kb.FilePath, withExt(kb.Title, kb.FilePath), kb.MimeType
+		db.Model(&kb).UpdateColumn("download_count", kb.DownloadCount+1)
+	case FileResourceContract:
+		var contract models.Contract
+		if err := db.First(&contract, id).Error; err != nil {
+			utils.NotFound(c, "合同不存在")
+			return
+		}
+		filePath, fileName = contract.FilePath, withExt(contract.ContractName, contract.FilePath)
+	case FileResourceVoucher:
+		var expense models.Expense
+		if err := db.First(&expense, id).Error; err != nil {
+			utils.NotFound(c, "费用记录不存在")
+			return
+		}
+		paths := voucherPaths(&expense)
+		if index < 0 || index >= len(paths) {
+			utils.NotFound(c, "凭证文件不存在")
+			return
+		}
+		filePath = paths[index]
+		fileName = filepath.Base(filePath)
+	default:
+		utils.NotFound(c, "资源类型不存在")
+		return
+	}
+
+	if filePath == "" {
+		utils.NotFound(c, "文件不存在")
+		return
+	}
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		utils.NotFound(c, "文件不存在")
+		return
+	}
+
+	// inline=1 时在浏览器中直接预览
+	disposition := "attachment"
+	if c.Query("inline") == "1" {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(fileName)))
+	if mimeType != "" {
+		c.Header("Content-Type", mimeType)
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.File(filePath)
+}
+
+// signedDownloadURL 生成签名下载地址（相对于 /api）
+func signedDownloadURL(resource string, id uint, index int) gin.H {
+	expiresAt := time.Now().Add(config.DownloadURLExpireTime)
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	if index > 0 {
+		query.Set("file", strconv.Itoa(index))
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", utils.SignDownload(resource, id, index, expires))
+
+	return gin.H{
+		"url":        fmt.Sprintf("/files/%s/%d?%s", resource, id, query.Encode()),
+		"expires_at": expiresAt,
+	}
+}
+
+// voucherPaths 解析费用的凭证文件路径列表
+func voucherPaths(expense *models.Expense) []string {
+	var paths []string
+	for _, p := range strings.Split(expense.VoucherPath, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// withExt 下载文件名缺少扩展名时补充存储文件的扩展名
+func withExt(name, filePath string) string {
+	ext := filepath.Ext(filePath)
+	if ext == "" || strings.HasSuffix(strings.ToLower(name), strings.ToLower(ext)) {
+		return name
+	}
+	return name + ext
+}
+
+// removeUnreferencedFile 删除不再被任何资料或合同引用的文件（克隆项目时资料以引用方式共享同一文件）
+func removeUnreferencedFile(filePath string) {
+	if filePath == "" {
+		return
+	}
+	db := config.GetDB()
+	var docCount, contractCount int64
+	db.Model(&models.Document{}).Where("file_path = ?", filePath).Count(&docCount)
+	db.Model(&models.Contract{}).Where("file_path = ?", filePath).Count(&contractCount)
+	if docCount+contractCount == 0 {
+		os.Remove(filePath)
+	}
+}
@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/schedule"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GanttTask 甘特图任务条
+type GanttTask struct {
+	ID           uint       `json:"id"`
+	ParentID     *uint      `json:"parent_id"` // 父任务ID（子任务在甘特图中缩进显示）
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	Priority     int        `json:"priority"`
+	AssigneeID   uint       `json:"assignee_id"`
+	AssigneeName string     `json:"assignee_name"`
+	PlannedStart *time.Time `json:"planned_start"` // 计划开始（未填写时取排程的最早开始）
+	PlannedEnd   *time.Time `json:"planned_end"`   // 计划结束（工期结束时刻，未填写工期时取截止日期）
+	Duration     int        `json:"duration"`
+	Deadline     *time.Time `json:"deadline"`
+	ActualStart  *time.Time `json:"actual_start"`
+	ActualEnd    *time.Time `json:"actual_end"`
+	Progress     int        `json:"progress"` // 进度百分比
+	Slack        int        `json:"slack"`    // 总浮动时间（天）
+	Critical     bool       `json:"critical"` // 是否在关键路径上
+	Dependencies []uint     `json:"dependencies"`
+}
+
+// GanttPhase 甘特图阶段（任务分组），ID 为 0 的分组存放未归属阶段的任务
+type GanttPhase struct {
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	Order       int         `json:"order"`
+	Status      string      `json:"status"`
+	StartDate   *time.Time  `json:"start_date"`
+	EndDate     *time.Time  `json:"end_date"`
+	CompletedAt *time.Time  `json:"completed_at"`
+	Progress    int         `json:"progress"`
+	Tasks       []GanttTask `json:"tasks"`
+}
+
+// GanttLink 甘特图依赖连线
+type GanttLink struct {
+	ID     uint   `json:"id"`
+	Source uint   `json:"source"` // 前置任务ID
+	Target uint   `json:"target"` // 后续任务ID
+	Type   string `json:"type"`   // FS/SS/FF
+	Lag    int    `json:"lag"`
+}
+
+// Gantt 获取项目甘特图数据：阶段及其下的任务、依赖连线、今天和项目周期
+func (pc *ProjectController) Gantt(c *gin.Context) {
+	pid, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	db := config.GetDB()
+	var project models.Project
+	if err := db.First(&project, pid).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+
+	var phases []models.ProjectPhase
+	db.Where("project_id = ?", project.ID).Order("phase_order").Find(&phases)
+	var tasks []models.Task
+	db.Preload("Assignee").Where("project_id = ?", project.ID).Order("id").Find(&tasks)
+	var deps []models.TaskDependency
+	db.Where("project_id = ?", project.ID).Order("id").Find(&deps)
+
+	taskIDs := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	progress := taskProgressMap(tasks, checklistStats(db, taskIDs))
+
+	// 排程结果用于补全未填写的计划日期并标记关键路径（依赖存在循环时忽略）
+	scheduled := make(map[uint]schedule.TaskSchedule)
+	if result, err := schedule.ForProject(db, &project); err == nil {
+		for _, t := range result.Tasks {
+			scheduled[t.TaskID] = t
+		}
+	}
+
+	predecessors := make(map[uint][]uint)
+	links := make([]GanttLink, 0, len(deps))
+	for _, d := range deps {
+		predecessors[d.SuccessorID] = append(predecessors[d.SuccessorID], d.PredecessorID)
+		links = append(links, GanttLink{ID: d.ID, Source: d.PredecessorID, Target: d.SuccessorID, Type: d.Type, Lag: d.Lag})
+	}
+
+	ganttPhases := make([]GanttPhase, 0, len(phases))
+	phaseIndex := make(map[uint]int, len(phases))
+	for i, p := range phases {
+		phaseIndex[p.ID] = i
+		ganttPhases = append(ganttPhases, GanttPhase{
+			ID:          p.ID,
+			Name:        p.PhaseName,
+			Order:       p.PhaseOrder,
+			Status:      p.Status,
+			StartDate:   p.StartDate,
+			EndDate:     p.EndDate,
+			CompletedAt: p.CompletedAt,
+			Tasks:       []GanttTask{},
+		})
+	}
+
+	windowStart, windowEnd := project.InitiationDate, project.ClosingDate
+	extend := func(t *time.Time) {
+		if t == nil {
+			return
+		}
+		if windowStart == nil || t.Before(*windowStart) {
+			windowStart = t
+		}
+		if windowEnd == nil || t.After(*windowEnd) {
+			windowEnd = t
+		}
+	}
+
+	var unphased []GanttTask
+	for i := range tasks {
+		t := &tasks[i]
+		item := GanttTask{
+			ID:           t.ID,
+			ParentID:     t.ParentID,
+			Name:         t.TaskName,
+			Status:       t.Status,
+			Priority:     t.Priority,
+			AssigneeID:   t.AssigneeID,
+			PlannedStart: t.PlannedStart,
+			Duration:     schedule.TaskDuration(t),
+			Deadline:     t.Deadline,
+			ActualStart:  t.StartedAt,
+			ActualEnd:    t.CompletedAt,
+			Progress:     progress[t.ID],
+			Dependencies: predecessors[t.ID],
+		}
+		if t.Assignee != nil {
+			item.AssigneeName = t.Assignee.Name
+		}
+		if s, ok := scheduled[t.ID]; ok {
+			if item.PlannedStart == nil {
+				item.PlannedStart = &s.EarliestStart
+			}
+			item.Slack, item.Critical = s.Slack, s.Critical
+		}
+		if item.PlannedStart != nil && (t.Duration > 0 || t.Deadline == nil) {
+			end := item.PlannedStart.AddDate(0, 0, item.Duration)
+			item.PlannedEnd = &end
+		} else {
+			item.PlannedEnd = t.Deadline
+		}
+		if item.Dependencies == nil {
+			item.Dependencies = []uint{}
+		}
+		extend(item.PlannedStart)
+		extend(item.PlannedEnd)
+		extend(item.ActualEnd)
+
+		if idx, ok := phaseIndex[t.PhaseID]; ok {
+			ganttPhases[idx].Tasks = append(ganttPhases[idx].Tasks, item)
+		} else {
+			unphased = append(unphased, item)
+		}
+	}
+	if len(unphased) > 0 {
+		ganttPhases = append(ganttPhases, GanttPhase{ID: 0, Name: "未分阶段", Order: len(phases) + 1, Tasks: unphased})
+	}
+
+	for i := range ganttPhases {
+		p := &ganttPhases[i]
+		switch {
+		case p.Status == config.StatusCompleted:
+			p.Progress = 100
+		default:
+			// 阶段进度取顶层任务的平均进度（子任务已汇总到父任务）
+			total, count := 0, 0
+			for _, t := range p.Tasks {
+				if t.ParentID == nil {
+					total += t.Progress
+					count++
+				}
+			}
+			if count > 0 {
+				p.Progress = total / count
+			}
+		}
+		extend(p.StartDate)
+		extend(p.EndDate)
+	}
+
+	utils.Success(c, gin.H{
+		"project": gin.H{
+			"id":              project.ID,
+			"name":            project.Name,
+			"status":          project.Status,
+			"initiation_date": project.InitiationDate,
+			"closing_date":    project.ClosingDate,
+		},
+		"today":        time.Now(),
+		"window_start": windowStart,
+		"window_end":   windowEnd,
+		"phases":       ganttPhases,
+		"links":        links,
+	})
+}
+
+// GanttTaskUpdate 甘特图拖拽调整的任务日期
+type GanttTaskUpdate struct {
+	ID           uint   `json:"id" binding:"required"`
+	PlannedStart string `json:"planned_start"` // yyyy-mm-dd
+	Duration     *int   `json:"duration"`
+	Deadline     string `json:"deadline"` // yyyy-mm-dd
+}
+
+// GanttPhaseUpdate 甘特图拖拽调整的阶段日期
+type GanttPhaseUpdate struct {
+	ID        uint   `json:"id" binding:"required"`
+	StartDate string `json:"start_date"` // 仅未开始的阶段可调整开始日期
+	EndDate   string `json:"end_date"`
+}
+
+// RescheduleRequest 甘特图批量调整请求
+type RescheduleRequest struct {
+	Tasks  []GanttTaskUpdate  `json:"tasks" binding:"dive"`
+	Phases []GanttPhaseUpdate `json:"phases" binding:"dive"`
+}
+
+// parseGanttDate 解析日期，空字符串返回 nil
+func parseGanttDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Reschedule 甘特图拖拽后批量调整任务和阶段日期：全部校验通过后在一个事务中保存
+func (pc *ProjectController) Reschedule(c *gin.Context) {
+	var req RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if len(req.Tasks) == 0 && len(req.Phases) == 0 {
+		utils.BadRequest(c, "没有需要调整的内容")
+		return
+	}
+
+	db := config.GetDB()
+	var project models.Project
+	if err := db.First(&project, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+	if !canManageProjectTasks(c, &project) {
+		utils.Forbidden(c, "只有项目负责人才能调整项目计划")
+		return
+	}
+
+	var tasks []models.Task
+	db.Where("project_id = ?", project.ID).Find(&tasks)
+	taskByID := make(map[uint]*models.Task, len(tasks))
+	for i := range tasks {
+		taskByID[tasks[i].ID] = &tasks[i]
+	}
+
+	// 校验任务调整并应用到内存中的任务，用于后续依赖校验
+	var errs []string
+	taskUpdates := make(map[uint]map[string]interface{})
+	for _, u := range req.Tasks {
+		task, ok := taskByID[u.ID]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("任务%d不属于该项目", u.ID))
+			continue
+		}
+		if task.Status == config.TaskCompleted {
+			errs = append(errs, fmt.Sprintf("任务「%s」已完成，不能调整计划", task.TaskName))
+			continue
+		}
+		plannedStart, err1 := parseGanttDate(u.PlannedStart)
+		deadline, err2 := parseGanttDate(u.Deadline)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, fmt.Sprintf("任务「%s」日期格式错误", task.TaskName))
+			continue
+		}
+
+		updates := make(map[string]interface{})
+		if plannedStart != nil {
+			if project.InitiationDate != nil && plannedStart.Before(*project.InitiationDate) {
+				errs = append(errs, fmt.Sprintf("任务「%s」的计划开始早于项目立项日期", task.TaskName))
+				continue
+			}
+			task.PlannedStart = plannedStart
+			updates["planned_start"] = *plannedStart
+		}
+		if u.Duration != nil {
+			if *u.Duration < 0 {
+				errs = append(errs, fmt.Sprintf("任务「%s」的工期不能为负数", task.TaskName))
+				continue
+			}
+			task.Duration = *u.Duration
+			updates["duration"] = *u.Duration
+		}
+		if deadline != nil {
+			task.Deadline = deadline
+			updates["deadline"] = *deadline
+		}
+		if task.PlannedStart != nil && task.Deadline != nil && task.Deadline.Before(*task.PlannedStart) {
+			errs = append(errs, fmt.Sprintf("任务「%s」的截止日期早于计划开始", task.TaskName))
+			continue
+		}
+		if len(updates) > 0 {
+			taskUpdates[task.ID] = updates
+		}
+	}
+
+	// 依赖校验：调整后所有未完成任务的计划开始都不能早于前置任务的约束，
+	// 包括本次未调整、但因前置任务推迟而受影响的后续任务
+	if len(taskUpdates) > 0 && len(errs) == 0 {
+		var deps []models.TaskDependency
+		db.Where("project_id = ?", project.ID).Find(&deps)
+		nodes := make([]schedule.Node, 0, len(tasks))
+		for i := range tasks {
+			nodes = append(nodes, schedule.Node{ID: tasks[i].ID, Start: tasks[i].PlannedStart, Duration: schedule.TaskDuration(&tasks[i])})
+		}
+		start := time.Now()
+		if project.InitiationDate != nil {
+			start = *project.InitiationDate
+		}
+		result, err := schedule.Compute(start, nodes, schedule.Edges(deps))
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			for _, s := range result.Tasks {
+				task := taskByID[s.TaskID]
+				if task.PlannedStart == nil || task.Status == config.TaskCompleted || !s.EarliestStart.After(*task.PlannedStart) {
+					continue
+				}
+				if _, changed := taskUpdates[s.TaskID]; changed {
+					errs = append(errs, fmt.Sprintf("任务「%s」受前置任务约束，最早只能在%s开始", task.TaskName, s.EarliestStart.Format("2006-01-02")))
+				} else {
+					errs = append(errs, fmt.Sprintf("后续任务「%s」的计划开始（%s）早于前置任务约束，最早只能在%s开始，请一并调整",
+						task.TaskName, task.PlannedStart.Format("2006-01-02"), s.EarliestStart.Format("2006-01-02")))
+				}
+			}
+		}
+	}
+
+	// 校验阶段调整
+	phaseUpdates := make(map[uint]map[string]interface{})
+	for _, u := range req.Phases {
+		var phase models.ProjectPhase
+		if err := db.Where("id = ? AND project_id = ?", u.ID, project.ID).First(&phase).Error; err != nil {
+			errs = append(errs, fmt.Sprintf("阶段%d不属于该项目", u.ID))
+			continue
+		}
+		startDate, err1 := parseGanttDate(u.StartDate)
+		endDate, err2 := parseGanttDate(u.EndDate)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, fmt.Sprintf("阶段「%s」日期格式错误", phase.PhaseName))
+			continue
+		}
+		if phase.Status == config.StatusCompleted {
+			errs = append(errs, fmt.Sprintf("阶段「%s」已完成，不能调整计划", phase.PhaseName))
+			continue
+		}
+
+		updates := make(map[string]interface{})
+		if startDate != nil {
+			if phase.Status != config.StatusNotStarted {
+				errs = append(errs, fmt.Sprintf("阶段「%s」已开始，不能调整开始日期", phase.PhaseName))
+				continue
+			}
+			phase.StartDate = startDate
+			updates["start_date"] = *startDate
+		}
+		if endDate != nil {
+			phase.EndDate = endDate
+			updates["end_date"] = *endDate
+		}
+		if phase.StartDate != nil && phase.EndDate != nil && phase.EndDate.Before(*phase.StartDate) {
+			errs = append(errs, fmt.Sprintf("阶段「%s」的结束日期早于开始日期", phase.PhaseName))
+			continue
+		}
+		if len(updates) > 0 {
+			phaseUpdates[phase.ID] = updates
+		}
+	}
+
+	if len(errs) > 0 {
+		utils.ErrorWithData(c, 400, "计划调整校验未通过", gin.H{"errors": errs})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for id, updates := range taskUpdates {
+			if err := tx.Model(&models.Task{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		for id, updates := range phaseUpdates {
+			if err := tx.Model(&models.ProjectPhase{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ServerError(c, "保存计划调整失败")
+		return
+	}
+
+	// 记录日志
+	description := fmt.Sprintf("调整项目计划: %d个任务，%d个阶段", len(taskUpdates), len(phaseUpdates))
+	middleware.LogOperation(c, "reschedule", "project", "project", project.ID, project.Name, description, "success")
+
+	utils.SuccessWithMessage(c, "计划已更新", nil)
+}
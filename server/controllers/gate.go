@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 阶段门禁检查项
+const (
+	GateTasksCompleted    = "tasks_completed"    // 阶段内任务全部完成或审核通过
+	GateDocumentsApproved = "documents_approved" // 必需资料已提交且审核通过
+	GateSignOff           = "sign_off"           // 部门经理签核
+)
+
+// GateCriterion 阶段门禁检查结果
+type GateCriterion struct {
+	Code    string   `json:"code"`
+	Passed  bool     `json:"passed"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"` // 未满足的具体项（未完成的任务、缺少的资料类型等）
+}
+
+// evaluatePhaseGate 检查阶段是否满足完成条件
+func evaluatePhaseGate(db *gorm.DB, phase *models.ProjectPhase) []GateCriterion {
+	var criteria []GateCriterion
+
+	// 任务：全部完成（审核通过的任务即为已完成；重新开始的任务可能仍保留之前的审核结论，不能作为依据）
+	// 重复任务提前生成的、发生时间还未到的实例不计入
+	var pending []models.Task
+	db.Select("id, task_name, status").
+		Where("phase_id = ? AND status <> ?", phase.ID, config.TaskCompleted).
+		Where("recurrence_id IS NULL OR occurrence_at IS NULL OR occurrence_at <= ?", time.Now()).
+		Find(&pending)
+	tasks := GateCriterion{Code: GateTasksCompleted, Passed: len(pending) == 0, Message: "阶段内任务已全部完成"}
+	if !tasks.Passed {
+		tasks.Message = fmt.Sprintf("有%d个任务未完成", len(pending))
+		for _, t := range pending {
+			tasks.Details = append(tasks.Details, t.TaskName)
+		}
+	}
+	criteria = append(criteria, tasks)
+
+	// 必需资料：每种类型至少有一份审核通过的资料
+	if required := phase.DocTypeList(); len(required) > 0 {
+		var approved []string
+		db.Model(&models.Document{}).
+			Where("phase_id = ? AND doc_type IN ? AND approved_at IS NOT NULL", phase.ID, required).
+			Distinct().Pluck("doc_type", &approved)
+		have := make(map[string]bool, len(approved))
+		for _, t := range approved {
+			have[t] = true
+		}
+
+		docs := GateCriterion{Code: GateDocumentsApproved, Passed: true, Message: "必需资料已全部审核通过"}
+		for _, t := range required {
+			if !have[t] {
+				docs.Passed = false
+				docs.Details = append(docs.Details, t)
+			}
+		}
+		if !docs.Passed {
+			docs.Message = fmt.Sprintf("有%d类必需资料未提交或未审核通过", len(docs.Details))
+		}
+		criteria = append(criteria, docs)
+	}
+
+	// 签核：需要时必须由部门经理签核通过
+	if phase.RequireSignOff {
+		signOff := GateCriterion{Code: GateSignOff, Passed: phase.SignedOffAt != nil, Message: "部门经理已签核"}
+		if !signOff.Passed {
+			signOff.Message = "等待部门经理签核"
+		}
+		criteria = append(criteria, signOff)
+	}
+
+	return criteria
+}
+
+// unmetCriteria 筛选未满足的检查项
+func unmetCriteria(criteria []GateCriterion) []GateCriterion {
+	var unmet []GateCriterion
+	for _, c := range criteria {
+		if !c.Passed {
+			unmet = append(unmet, c)
+		}
+	}
+	return unmet
+}
+
+// recordGateDecision 记录阶段门禁决策
+func recordGateDecision(db *gorm.DB, phase *models.ProjectPhase, decision string, criteria []GateCriterion, comment string, operatorID uint) {
+	record := models.PhaseGateRecord{
+		ProjectID:  phase.ProjectID,
+		PhaseID:    phase.ID,
+		Decision:   decision,
+		Comment:    comment,
+		OperatorID: operatorID,
+	}
+	if len(criteria) > 0 {
+		data, _ := json.Marshal(criteria)
+		record.Criteria = string(data)
+	}
+	db.Create(&record)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "是"
+	}
+	return "否"
+}
+
+// GetPhaseGate 获取阶段门禁检查结果及决策记录
+func (pc *ProjectController) GetPhaseGate(c *gin.Context) {
+	projectID := c.Param("id")
+	pid, _ := strconv.ParseUint(projectID, 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	db := config.GetDB()
+	var phase models.ProjectPhase
+	if err := db.Where("id = ? AND project_id = ?", c.Param("phaseId"), projectID).First(&phase).Error; err != nil {
+		utils.NotFound(c, "阶段不存在")
+		return
+	}
+
+	criteria := evaluatePhaseGate(db, &phase)
+
+	var records []models.PhaseGateRecord
+	db.Preload("Operator").Where("phase_id = ?", phase.ID).Order("id DESC").Find(&records)
+
+	utils.Success(c, gin.H{
+		"passed":   len(unmetCriteria(criteria)) == 0,
+		"criteria": criteria,
+		"records":  records,
+	})
+}
+
+// SignOffPhaseRequest 阶段签核请求
+type SignOffPhaseRequest struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment"`
+}
+
+// SignOffPhase 部门经理签核阶段（通过或驳回）
+func (pc *ProjectController) SignOffPhase(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req SignOffPhaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	roleCode, _ := c.Get("roleCode")
+	if roleCode != config.RoleDeptManager && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "只有部门经理才能签核项目阶段")
+		return
+	}
+	pid, _ := strconv.ParseUint(projectID, 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	db := config.GetDB()
+	var phase models.ProjectPhase
+	if err := db.Where("id = ? AND project_id = ?", c.Param("phaseId"), projectID).First(&phase).Error; err != nil {
+		utils.NotFound(c, "阶段不存在")
+		return
+	}
+	if phase.Status == config.StatusCompleted {
+		utils.Error(c, 400, "阶段已完成，无需签核")
+		return
+	}
+	if !req.Approved && req.Comment == "" {
+		utils.BadRequest(c, "驳回时请填写意见")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	operatorID := userID.(uint)
+
+	decision := models.GateDecisionSignOff
+	updates := map[string]interface{}{"signed_off_by": operatorID, "signed_off_at": time.Now()}
+	if !req.Approved {
+		decision = models.GateDecisionSignOffRejected
+		updates = map[string]interface{}{"signed_off_by": nil, "signed_off_at": nil}
+	}
+	db.Model(&phase).Updates(updates)
+	db.First(&phase, phase.ID)
+
+	recordGateDecision(db, &phase, decision, evaluatePhaseGate(db, &phase), req.Comment, operatorID)
+
+	// 记录日志
+	description := "签核通过阶段: " + phase.PhaseName
+	if !req.Approved {
+		description = "签核驳回阶段: " + phase.PhaseName
+	}
+	middleware.LogOperation(c, "sign_off_phase", "project", "phase", phase.ID, phase.PhaseName, description, "success")
+
+	utils.SuccessWithMessage(c, "签核完成", phase)
+}
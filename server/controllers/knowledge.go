@@ -205,6 +205,19 @@ func (kc *KnowledgeController) Download(c *gin.Context) {
 	c.File(kb.FilePath)
 }
 
+// DownloadURL 获取资料的签名下载地址
+func (kc *KnowledgeController) DownloadURL(c *gin.Context) {
+	id := c.Param("id")
+
+	var kb models.KnowledgeBase
+	if err := config.GetDB().First(&kb, id).Error; err != nil {
+		utils.NotFound(c, "资料不存在")
+		return
+	}
+
+	utils.Success(c, signedDownloadURL(FileResourceKnowledge, kb.ID, 0))
+}
+
 // UpdateKBRequest 更新请求
 type UpdateKBRequest struct {
 	Title       string `json:"title"`
@@ -267,10 +280,10 @@ func (kc *KnowledgeController) Delete(c *gin.Context) {
 		return
 	}
 
-	// 权限检查：管理员和部门经理可以删除所有资料，其他用户只能删除自己上传的资料
+	// 权限检查：拥有知识库管理权限可以删除所有资料，其他用户只能删除自己上传的资料
 	userID, _ := c.Get("userID")
 	roleCode, _ := c.Get("roleCode")
-	isAdmin := roleCode == config.RoleAdmin || roleCode == config.RoleDeptManager
+	isAdmin := middleware.HasPermission(roleCode.(string), config.PermKBManage)
 	isOwner := kb.UploadedBy == userID.(uint)
 
 	if !isAdmin && !isOwner {
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LDAPController struct{}
+
+// LDAPGroupMappingRequest 组映射请求
+type LDAPGroupMappingRequest struct {
+	GroupDN  string `json:"group_dn" binding:"required"`
+	RoleCode string `json:"role_code" binding:"required"`
+	Priority int    `json:"priority"`
+	Remark   string `json:"remark"`
+}
+
+// ListGroupMappings 获取目录组到角色的映射列表
+func (lc *LDAPController) ListGroupMappings(c *gin.Context) {
+	var mappings []models.LDAPGroupMapping
+	config.GetDB().Order("priority DESC, id ASC").Find(&mappings)
+
+	utils.Success(c, gin.H{
+		"enabled": config.LDAPEnabled,
+		"list":    mappings,
+	})
+}
+
+// CreateGroupMapping 创建组映射
+func (lc *LDAPController) CreateGroupMapping(c *gin.Context) {
+	var req LDAPGroupMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写组DN和角色")
+		return
+	}
+	req.GroupDN = strings.TrimSpace(req.GroupDN)
+
+	db := config.GetDB()
+	var role models.Role
+	if err := db.Where("code = ?", req.RoleCode).First(&role).Error; err != nil {
+		utils.BadRequest(c, "角色不存在")
+		return
+	}
+
+	var existing models.LDAPGroupMapping
+	if db.Where("LOWER(group_dn) = ?", strings.ToLower(req.GroupDN)).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "该目录组已配置映射")
+		return
+	}
+
+	mapping := models.LDAPGroupMapping{
+		GroupDN:  req.GroupDN,
+		RoleCode: req.RoleCode,
+		Priority: req.Priority,
+		Remark:   req.Remark,
+	}
+	if err := db.Create(&mapping).Error; err != nil {
+		utils.ServerError(c, "创建失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "create", "role", "ldap_group_mapping", mapping.ID, mapping.GroupDN, "创建目录组映射: "+mapping.GroupDN+" -> "+mapping.RoleCode, "success")
+
+	utils.SuccessWithMessage(c, "创建成功", mapping)
+}
+
+// UpdateGroupMapping 更新组映射
+func (lc *LDAPController) UpdateGroupMapping(c *gin.Context) {
+	id := c.Param("id")
+
+	var req LDAPGroupMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写组DN和角色")
+		return
+	}
+	req.GroupDN = strings.TrimSpace(req.GroupDN)
+
+	db := config.GetDB()
+	var mapping models.LDAPGroupMapping
+	if err := db.First(&mapping, id).Error; err != nil {
+		utils.NotFound(c, "映射不存在")
+		return
+	}
+
+	var role models.Role
+	if err := db.Where("code = ?", req.RoleCode).First(&role).Error; err != nil {
+		utils.BadRequest(c, "角色不存在")
+		return
+	}
+
+	var existing models.LDAPGroupMapping
+	if db.Where("LOWER(group_dn) = ? AND id <> ?", strings.ToLower(req.GroupDN), mapping.ID).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "该目录组已配置映射")
+		return
+	}
+
+	if err := db.Model(&mapping).Updates(map[string]interface{}{
+		"group_dn":  req.GroupDN,
+		"role_code": req.RoleCode,
+		"priority":  req.Priority,
+		"remark":    req.Remark,
+	}).Error; err != nil {
+		utils.ServerError(c, "更新失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "update", "role", "ldap_group_mapping", mapping.ID, mapping.GroupDN, "更新目录组映射: "+mapping.GroupDN+" -> "+mapping.RoleCode, "success")
+
+	utils.SuccessWithMessage(c, "更新成功", mapping)
+}
+
+// DeleteGroupMapping 删除组映射
+func (lc *LDAPController) DeleteGroupMapping(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var mapping models.LDAPGroupMapping
+	if err := db.First(&mapping, id).Error; err != nil {
+		utils.NotFound(c, "映射不存在")
+		return
+	}
+
+	if err := db.Delete(&mapping).Error; err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "delete", "role", "ldap_group_mapping", mapping.ID, mapping.GroupDN, "删除目录组映射: "+mapping.GroupDN, "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
@@ -93,6 +93,7 @@ func (lc *LogController) GetModules(c *gin.Context) {
 	modules := []map[string]string{
 		{"value": "auth", "label": "认证"},
 		{"value": "user", "label": "用户管理"},
+		{"value": "role", "label": "角色管理"},
 		{"value": "project", "label": "项目管理"},
 		{"value": "task", "label": "任务管理"},
 		{"value": "document", "label": "文档管理"},
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NotificationController struct{}
+
+// notifyUsers 向多个用户发送站内通知（跳过发送人自己和重复的接收人）
+func notifyUsers(db *gorm.DB, userIDs []uint, n models.Notification) {
+	seen := make(map[uint]bool)
+	for _, id := range userIDs {
+		if id == 0 || id == n.SenderID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		notification := n
+		notification.UserID = id
+		db.Create(&notification)
+	}
+}
+
+// List 获取当前用户的通知列表（unread=1 只看未读）
+func (nc *NotificationController) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	userID, _ := c.Get("userID")
+
+	db := config.GetDB()
+	query := db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "1" {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var total int64
+	var notifications []models.Notification
+	query.Count(&total)
+	query.Preload("Sender").Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&notifications)
+
+	utils.SuccessPage(c, notifications, total, page, pageSize)
+}
+
+// UnreadCount 获取当前用户的未读通知数
+func (nc *NotificationController) UnreadCount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var count int64
+	config.GetDB().Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&count)
+
+	utils.Success(c, gin.H{"count": count})
+}
+
+// MarkRead 标记通知为已读
+func (nc *NotificationController) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	result := config.GetDB().Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND is_read = ?", c.Param("id"), userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "通知不存在或已读")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已标记为已读", nil)
+}
+
+// MarkAllRead 将当前用户的全部通知标记为已读
+func (nc *NotificationController) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	config.GetDB().Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+
+	utils.SuccessWithMessage(c, "已全部标记为已读", nil)
+}
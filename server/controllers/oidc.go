@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"project-flow/auth"
+	"project-flow/config"
+	"project-flow/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+type OIDCController struct{}
+
+// oidcStateCookie 授权请求上下文（state、nonce、PKCE verifier）的Cookie名称
+const oidcStateCookie = "oidc_state"
+
+// GetConfig 获取单点登录配置（登录页据此显示企业账号登录入口）
+func (oc *OIDCController) GetConfig(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"enabled": config.OIDCEnabled,
+	})
+}
+
+// Login 跳转到身份提供方进行授权
+func (oc *OIDCController) Login(c *gin.Context) {
+	if !config.OIDCEnabled {
+		utils.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	state, err1 := utils.GenerateRefreshToken()
+	nonce, err2 := utils.GenerateRefreshToken()
+	if err1 != nil || err2 != nil {
+		utils.ServerError(c, "生成授权请求失败")
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := auth.OIDCAuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("OIDC授权请求失败: %v", err)
+		utils.ServerError(c, "身份提供方暂不可用")
+		return
+	}
+
+	// 授权上下文保存在仅限回调路径的Cookie中，回调时校验state防止CSRF
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, strings.Join([]string{state, nonce, verifier}, "|"),
+		600, oidcCookiePath(), "", c.Request.TLS != nil, true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback 身份提供方授权回调：校验ID Token，关联系统用户并签发系统Token
+func (oc *OIDCController) Callback(c *gin.Context) {
+	if !config.OIDCEnabled {
+		utils.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath(), "", c.Request.TLS != nil, true)
+
+	if errMsg := c.Query("error"); errMsg != "" {
+		oidcRedirectError(c, "身份提供方拒绝了登录请求: "+errMsg)
+		return
+	}
+
+	parts := strings.Split(cookie, "|")
+	if len(parts) != 3 || parts[0] == "" || parts[0] != c.Query("state") {
+		oidcRedirectError(c, "登录请求已失效，请重新登录")
+		return
+	}
+
+	identity, err := auth.OIDCExchange(c.Request.Context(), c.Query("code"), parts[1], parts[2])
+	if err != nil {
+		log.Printf("OIDC登录失败: %v", err)
+		oidcRedirectError(c, "企业账号认证失败")
+		return
+	}
+
+	user, err := auth.LinkOIDCUser(identity)
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCUserNotLinked) {
+			oidcRedirectError(c, err.Error())
+		} else {
+			log.Printf("OIDC关联用户失败: %v", err)
+			oidcRedirectError(c, "企业账号认证失败")
+		}
+		return
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		oidcRedirectError(c, "账号已被锁定，请稍后再试")
+		return
+	}
+	if user.Status != 1 {
+		oidcRedirectError(c, "账号已被禁用")
+		return
+	}
+
+	// 需要双因素认证时只返回挑战Token，由前端完成第二步验证（VerifyLogin/SetupLogin）后再创建会话
+	challenge, err := twoFactorChallenge(user)
+	if err != nil {
+		oidcRedirectError(c, "生成Token失败")
+		return
+	}
+	if challenge != nil {
+		fragment := url.Values{}
+		fragment.Set("challenge_token", challenge["challenge_token"].(string))
+		fragment.Set("two_factor_required", strconv.FormatBool(challenge["two_factor_required"].(bool)))
+		fragment.Set("two_factor_setup_required", strconv.FormatBool(challenge["two_factor_setup_required"].(bool)))
+		fragment.Set("expires_in", strconv.FormatInt(challenge["expires_in"].(int64), 10))
+		c.Redirect(http.StatusFound, config.OIDCFrontendURL+"#"+fragment.Encode())
+		return
+	}
+
+	resp, err := issueTokens(c, user)
+	if err != nil {
+		oidcRedirectError(c, "生成Token失败")
+		return
+	}
+
+	recordLogin(c, user.ID, user.Name+" 通过单点登录进入系统")
+
+	// Token放在URL片段中，不会发送到服务器或出现在访问日志里
+	fragment := url.Values{}
+	fragment.Set("token", resp.Token)
+	fragment.Set("refresh_token", resp.RefreshToken)
+	fragment.Set("expires_in", strconv.FormatInt(resp.ExpiresIn, 10))
+	c.Redirect(http.StatusFound, config.OIDCFrontendURL+"#"+fragment.Encode())
+}
+
+// oidcCookiePath Cookie路径取回调地址所在目录（兼容前端代理添加的路径前缀）
+func oidcCookiePath() string {
+	u, err := url.Parse(config.OIDCRedirectURL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return path.Dir(u.Path)
+}
+
+// oidcRedirectError 携带错误信息跳转回前端登录页
+func oidcRedirectError(c *gin.Context, message string) {
+	fragment := url.Values{}
+	fragment.Set("error", message)
+	c.Redirect(http.StatusFound, config.OIDCFrontendURL+"#"+fragment.Encode())
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 阶段顺序：固定阶段为 1-3（立项、招标、合同）和 100-101（验收、结项），自定义阶段位于 4-99 之间。
+// 阶段推进一律按 phase_order 排序查找前后阶段，不依赖顺序号连续。
+
+// nextPhase 查找排在指定阶段之后的第一个阶段
+func nextPhase(db *gorm.DB, phase *models.ProjectPhase) (*models.ProjectPhase, bool) {
+	var next models.ProjectPhase
+	if db.Where("project_id = ? AND phase_order > ?", phase.ProjectID, phase.PhaseOrder).
+		Order("phase_order").Limit(1).Find(&next).RowsAffected == 0 {
+		return nil, false
+	}
+	return &next, true
+}
+
+// previousPhase 查找排在指定阶段之前的最后一个阶段
+func previousPhase(db *gorm.DB, phase *models.ProjectPhase) (*models.ProjectPhase, bool) {
+	var prev models.ProjectPhase
+	if db.Where("project_id = ? AND phase_order < ?", phase.ProjectID, phase.PhaseOrder).
+		Order("phase_order DESC").Limit(1).Find(&prev).RowsAffected == 0 {
+		return nil, false
+	}
+	return &prev, true
+}
+
+// advancePhase 阶段完成后开启下一阶段；没有后续阶段时项目结项
+func advancePhase(db *gorm.DB, phase *models.ProjectPhase) {
+	next, ok := nextPhase(db, phase)
+	if !ok {
+		db.Model(&models.Project{}).Where("id = ?", phase.ProjectID).Update("status", config.StatusCompleted)
+		return
+	}
+
+	if next.Status == config.StatusNotStarted {
+		updates := map[string]interface{}{"status": config.StatusInProgress}
+		if next.StartDate == nil {
+			updates["start_date"] = time.Now()
+		}
+		db.Model(next).Updates(updates)
+	}
+	db.Model(&models.Project{}).Where("id = ?", phase.ProjectID).Update("current_phase", next.PhaseName)
+}
+
+// ReorderPhasesRequest 调整自定义阶段顺序请求
+type ReorderPhasesRequest struct {
+	PhaseIDs []uint `json:"phase_ids" binding:"required"` // 项目全部自定义阶段ID，按新的顺序排列
+}
+
+// phaseProgress 阶段进度排序值（已完成 > 进行中 > 未开始）
+var phaseProgress = map[string]int{
+	config.StatusCompleted:  2,
+	config.StatusInProgress: 1,
+	config.StatusNotStarted: 0,
+}
+
+// ReorderPhases 调整自定义阶段顺序（固定阶段位置不变，已完成或进行中的阶段不能排到未开始的阶段之后）
+func (pc *ProjectController) ReorderPhases(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req ReorderPhasesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	db := config.GetDB()
+
+	// 检查项目并验证权限
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+	if project.ManagerID != userID.(uint) && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "只有项目负责人才能调整阶段顺序")
+		return
+	}
+
+	var custom []models.ProjectPhase
+	db.Where("project_id = ? AND is_fixed = ?", project.ID, false).Order("phase_order").Find(&custom)
+	if len(req.PhaseIDs) != len(custom) {
+		utils.BadRequest(c, "请提交全部自定义阶段")
+		return
+	}
+	if len(custom) > customPhaseOrderEnd-customPhaseOrderStart+1 {
+		utils.Error(c, 400, "自定义阶段数量过多")
+		return
+	}
+
+	byID := make(map[uint]*models.ProjectPhase, len(custom))
+	for i := range custom {
+		byID[custom[i].ID] = &custom[i]
+	}
+	ordered := make([]*models.ProjectPhase, 0, len(req.PhaseIDs))
+	for _, id := range req.PhaseIDs {
+		phase, ok := byID[id]
+		if !ok {
+			utils.BadRequest(c, "阶段不存在或不是自定义阶段")
+			return
+		}
+		delete(byID, id)
+		ordered = append(ordered, phase)
+	}
+
+	// 已开始的阶段必须排在未开始的阶段之前，否则推进顺序会错乱
+	for i := 1; i < len(ordered); i++ {
+		if phaseProgress[ordered[i].Status] > phaseProgress[ordered[i-1].Status] {
+			utils.Error(c, 400, fmt.Sprintf("阶段「%s」已开始，不能排在未完成的阶段「%s」之后", ordered[i].PhaseName, ordered[i-1].PhaseName))
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, phase := range ordered {
+			if err := tx.Model(phase).Update("phase_order", customPhaseOrderStart+i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ServerError(c, "调整阶段顺序失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "reorder_phases", "project", "project", project.ID, project.Name, "调整项目自定义阶段顺序", "success")
+
+	var phases []models.ProjectPhase
+	db.Where("project_id = ?", project.ID).Order("phase_order").Find(&phases)
+	utils.SuccessWithMessage(c, "调整成功", phases)
+}
+
+// ReopenPhaseRequest 重新打开阶段请求
+type ReopenPhaseRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReopenPhase 重新打开已完成的阶段（回退）：该阶段恢复为进行中，之后已开始的阶段全部重置为未开始，签核需重新进行
+func (pc *ProjectController) ReopenPhase(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req ReopenPhaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写重新打开的原因")
+		return
+	}
+
+	db := config.GetDB()
+
+	// 检查项目并验证权限
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+	if project.ManagerID != userID.(uint) && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "只有项目负责人才能重新打开项目阶段")
+		return
+	}
+
+	var phase models.ProjectPhase
+	if err := db.Where("id = ? AND project_id = ?", c.Param("phaseId"), projectID).First(&phase).Error; err != nil {
+		utils.NotFound(c, "阶段不存在")
+		return
+	}
+	if phase.Status != config.StatusCompleted {
+		utils.Error(c, 400, "只能重新打开已完成的阶段")
+		return
+	}
+
+	operatorID := userID.(uint)
+	var later []models.ProjectPhase
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&phase).Updates(map[string]interface{}{
+			"status":        config.StatusInProgress,
+			"completed_at":  nil,
+			"signed_off_by": nil,
+			"signed_off_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		recordGateDecision(tx, &phase, models.GateDecisionReopened, nil, req.Reason, operatorID)
+
+		// 之后已开始或已完成的阶段回退为未开始
+		tx.Where("project_id = ? AND phase_order > ? AND status <> ?", project.ID, phase.PhaseOrder, config.StatusNotStarted).
+			Order("phase_order").Find(&later)
+		for i := range later {
+			if err := tx.Model(&later[i]).Updates(map[string]interface{}{
+				"status":        config.StatusNotStarted,
+				"completed_at":  nil,
+				"signed_off_by": nil,
+				"signed_off_at": nil,
+			}).Error; err != nil {
+				return err
+			}
+			recordGateDecision(tx, &later[i], models.GateDecisionRolledBack, nil, "阶段「"+phase.PhaseName+"」重新打开: "+req.Reason, operatorID)
+		}
+
+		return tx.Model(&project).Updates(map[string]interface{}{
+			"current_phase": phase.PhaseName,
+			"status":        config.StatusInProgress,
+		}).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "重新打开阶段失败")
+		return
+	}
+
+	// 记录日志
+	description := fmt.Sprintf("重新打开阶段: %s，回退后续阶段%d个，原因: %s", phase.PhaseName, len(later), req.Reason)
+	middleware.LogOperation(c, "reopen_phase", "project", "phase", phase.ID, phase.PhaseName, description, "success")
+
+	utils.SuccessWithMessage(c, "阶段已重新打开", nil)
+}
@@ -1,15 +1,18 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
 	"project-flow/config"
 	"project-flow/middleware"
 	"project-flow/models"
 	"project-flow/utils"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ProjectController struct{}
@@ -29,6 +32,7 @@ type CreateProjectRequest struct {
 	DirectCost      float64 `json:"direct_cost" binding:"required"`      // 直接投入费用
 	OutsourcingCost float64 `json:"outsourcing_cost" binding:"required"` // 委托研发费用
 	OtherCost       float64 `json:"other_cost" binding:"required"`       // 其他费用
+	TemplateID      uint    `json:"template_id"`                         // 项目模板（可选），按模板生成阶段和任务
 }
 
 // UpdateProjectRequest 更新项目请求
@@ -49,7 +53,7 @@ type UpdateProjectRequest struct {
 	Status          string  `json:"status"`
 }
 
-// List 获取项目列表（所有用户可查看所有项目）
+// List 获取项目列表（按项目可见范围过滤）
 func (pc *ProjectController) List(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
@@ -63,9 +67,8 @@ func (pc *ProjectController) List(c *gin.Context) {
 	var projects []models.Project
 	var total int64
 
-	query := db.Model(&models.Project{}).Preload("Manager").Preload("SubManager").Preload("Creator")
-
-	// 所有用户可查看所有项目，不再根据角色过滤
+	query := db.Model(&models.Project{}).Preload("Manager").Preload("SubManager").Preload("Creator").
+		Scopes(getProjectAccess(c).scope("id"))
 
 	if keyword != "" {
 		query = query.Where("name LIKE ? OR project_no LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
@@ -116,6 +119,24 @@ func (pc *ProjectController) Create(c *gin.Context) {
 		return
 	}
 
+	// 加载项目模板
+	var template *models.ProjectTemplate
+	if req.TemplateID != 0 {
+		var err error
+		if template, err = loadProjectTemplate(db, req.TemplateID); err != nil {
+			utils.BadRequest(c, "项目模板不存在")
+			return
+		}
+		if !template.IsActive {
+			utils.BadRequest(c, "项目模板已停用")
+			return
+		}
+		if template.ProjectType != "" && template.ProjectType != req.ProjectType {
+			utils.BadRequest(c, "项目模板仅适用于"+template.ProjectType+"项目")
+			return
+		}
+	}
+
 	project := models.Project{
 		ProjectNo:       projectNo,
 		Name:            req.Name,
@@ -132,6 +153,9 @@ func (pc *ProjectController) Create(c *gin.Context) {
 		Status:          config.StatusInProgress,
 		CreatedBy:       userID.(uint),
 	}
+	if template != nil {
+		project.TemplateID = &template.ID
+	}
 
 	// 解析日期
 	if req.InitiationDate != "" {
@@ -143,35 +167,42 @@ func (pc *ProjectController) Create(c *gin.Context) {
 		project.ClosingDate = &t
 	}
 
-	if err := db.Create(&project).Error; err != nil {
-		utils.ServerError(c, "创建项目失败")
-		return
-	}
+	// 项目、阶段、模板任务和负责人成员在同一事务中创建
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
 
-	// 创建项目阶段（5个固定阶段：立项、招标、合同签订 + 验收、结项，中间由用户自定义）
-	phases := []models.ProjectPhase{
-		{ProjectID: project.ID, PhaseName: config.PhaseInitiation, PhaseOrder: 1, IsFixed: true, Status: config.StatusInProgress},
-		{ProjectID: project.ID, PhaseName: config.PhaseBidding, PhaseOrder: 2, IsFixed: true, Status: config.StatusNotStarted},
-		{ProjectID: project.ID, PhaseName: config.PhaseContract, PhaseOrder: 3, IsFixed: true, Status: config.StatusNotStarted},
-		// 中间阶段由项目总工自定义，顺序从4开始
-		{ProjectID: project.ID, PhaseName: config.PhaseAcceptance, PhaseOrder: 100, IsFixed: true, Status: config.StatusNotStarted},
-		{ProjectID: project.ID, PhaseName: config.PhaseClosing, PhaseOrder: 101, IsFixed: true, Status: config.StatusNotStarted},
-	}
-	db.Create(&phases)
+		// 创建项目阶段（固定阶段 + 模板中的自定义阶段及任务）
+		if err := createProjectPhases(tx, &project, template, userID.(uint)); err != nil {
+			return err
+		}
 
-	// 添加项目负责人为成员
-	if req.ManagerID != 0 {
-		member := models.ProjectMember{
-			ProjectID: project.ID,
-			UserID:    req.ManagerID,
-			RoleType:  "manager", // 项目负责人
-			JoinDate:  time.Now(),
+		// 添加项目负责人为成员
+		if req.ManagerID != 0 {
+			member := models.ProjectMember{
+				ProjectID: project.ID,
+				UserID:    req.ManagerID,
+				RoleType:  "manager", // 项目负责人
+				JoinDate:  time.Now(),
+			}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
 		}
-		db.Create(&member)
+		return nil
+	})
+	if err != nil {
+		utils.ServerError(c, "创建项目失败")
+		return
 	}
 
 	// 记录日志
-	middleware.LogOperation(c, "create", "project", "project", project.ID, project.Name, "创建项目: "+project.Name, "success")
+	description := "创建项目: " + project.Name
+	if template != nil {
+		description += "（模板: " + template.Name + "）"
+	}
+	middleware.LogOperation(c, "create", "project", "project", project.ID, project.Name, description, "success")
 
 	utils.SuccessWithMessage(c, "创建成功", project)
 }
@@ -187,6 +218,11 @@ func (pc *ProjectController) Get(c *gin.Context) {
 		return
 	}
 
+	if !getProjectAccess(c).canView(project.ID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
 	utils.Success(c, project)
 }
 
@@ -305,6 +341,11 @@ func (pc *ProjectController) Delete(c *gin.Context) {
 // GetPhases 获取项目阶段列表
 func (pc *ProjectController) GetPhases(c *gin.Context) {
 	projectID := c.Param("id")
+	pid, _ := strconv.ParseUint(projectID, 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
 
 	db := config.GetDB()
 	var phases []models.ProjectPhase
@@ -315,11 +356,14 @@ func (pc *ProjectController) GetPhases(c *gin.Context) {
 
 // UpdatePhaseRequest 更新阶段请求
 type UpdatePhaseRequest struct {
-	Status string `json:"status"`
-	Remark string `json:"remark"`
+	Status           string    `json:"status"`
+	Remark           string    `json:"remark"`
+	RequiredDocTypes *[]string `json:"required_doc_types"` // 门禁：本阶段必须提交并审核通过的资料类型
+	RequireSignOff   *bool     `json:"require_sign_off"`   // 门禁：完成前是否需要部门经理签核
 }
 
-// UpdatePhase 更新项目阶段状态（只有创建者或管理员可操作）
+// UpdatePhase 更新项目阶段状态（只有项目负责人或管理员可操作）；
+// 门禁条件只能由部门经理或管理员修改，防止项目负责人放宽约束自己的门禁
 func (pc *ProjectController) UpdatePhase(c *gin.Context) {
 	projectID := c.Param("id")
 	phaseID := c.Param("phaseId")
@@ -341,7 +385,12 @@ func (pc *ProjectController) UpdatePhase(c *gin.Context) {
 
 	userID, _ := c.Get("userID")
 	roleCode, _ := c.Get("roleCode")
-	if project.ManagerID != userID.(uint) && roleCode != config.RoleAdmin {
+	changesCriteria := req.RequiredDocTypes != nil || req.RequireSignOff != nil
+	if changesCriteria && roleCode != config.RoleDeptManager && roleCode != config.RoleAdmin {
+		utils.Forbidden(c, "只有部门经理或管理员才能修改阶段门禁条件")
+		return
+	}
+	if (req.Status != "" || req.Remark != "" || !changesCriteria) && project.ManagerID != userID.(uint) && roleCode != config.RoleAdmin {
 		utils.Forbidden(c, "只有项目负责人才能修改项目阶段")
 		return
 	}
@@ -352,6 +401,40 @@ func (pc *ProjectController) UpdatePhase(c *gin.Context) {
 		return
 	}
 
+	switch req.Status {
+	case "", phase.Status:
+		req.Status = ""
+	case config.StatusInProgress:
+		// 只有前一阶段完成后才能开始当前阶段，已完成的阶段需通过重新打开回退
+		if phase.Status == config.StatusCompleted {
+			utils.Error(c, 400, "阶段已完成，请使用重新打开")
+			return
+		}
+		if prev, ok := previousPhase(db, &phase); ok && prev.Status != config.StatusCompleted {
+			utils.Error(c, 400, "前一阶段尚未完成")
+			return
+		}
+	case config.StatusCompleted:
+		if phase.Status != config.StatusInProgress {
+			utils.Error(c, 400, "只能完成进行中的阶段")
+			return
+		}
+	default:
+		utils.BadRequest(c, "阶段状态无效")
+		return
+	}
+
+	// 完成阶段前检查门禁条件，未满足时返回未满足的检查项
+	if req.Status == config.StatusCompleted {
+		criteria := evaluatePhaseGate(db, &phase)
+		if unmet := unmetCriteria(criteria); len(unmet) > 0 {
+			recordGateDecision(db, &phase, models.GateDecisionBlocked, criteria, "", userID.(uint))
+			utils.ErrorWithData(c, 400, "阶段完成条件未满足", gin.H{"unmet": unmet})
+			return
+		}
+		recordGateDecision(db, &phase, models.GateDecisionPassed, criteria, req.Remark, userID.(uint))
+	}
+
 	updates := make(map[string]interface{})
 	if req.Status != "" {
 		updates["status"] = req.Status
@@ -367,24 +450,31 @@ func (pc *ProjectController) UpdatePhase(c *gin.Context) {
 	if req.Remark != "" {
 		updates["remark"] = req.Remark
 	}
+	// 门禁条件的每次修改都写入门禁决策记录
+	var criteriaChanges []string
+	if req.RequiredDocTypes != nil {
+		newTypes := nonNilStrings(*req.RequiredDocTypes)
+		docTypes, _ := json.Marshal(newTypes)
+		if string(docTypes) != phase.RequiredDocTypes {
+			updates["required_doc_types"] = string(docTypes)
+			criteriaChanges = append(criteriaChanges, fmt.Sprintf("必需资料: %v -> %v", phase.DocTypeList(), newTypes))
+		}
+	}
+	if req.RequireSignOff != nil && *req.RequireSignOff != phase.RequireSignOff {
+		updates["require_sign_off"] = *req.RequireSignOff
+		criteriaChanges = append(criteriaChanges, fmt.Sprintf("部门经理签核: %s -> %s", yesNo(phase.RequireSignOff), yesNo(*req.RequireSignOff)))
+	}
+	if len(criteriaChanges) > 0 {
+		recordGateDecision(db, &phase, models.GateDecisionCriteriaChanged, nil, strings.Join(criteriaChanges, "；"), userID.(uint))
+	}
 
-	db.Model(&phase).Updates(updates)
+	if len(updates) > 0 {
+		db.Model(&phase).Updates(updates)
+	}
 
-	// 如果当前阶段完成，自动开启下一阶段
+	// 如果当前阶段完成，按阶段顺序自动开启下一阶段，所有阶段完成时项目结项
 	if req.Status == config.StatusCompleted {
-		var nextPhase models.ProjectPhase
-		if db.Where("project_id = ? AND phase_order = ?", projectID, phase.PhaseOrder+1).First(&nextPhase).RowsAffected > 0 {
-			now := time.Now()
-			db.Model(&nextPhase).Updates(map[string]interface{}{
-				"status":     config.StatusInProgress,
-				"start_date": now,
-			})
-			// 更新项目当前阶段
-			db.Model(&models.Project{}).Where("id = ?", projectID).Update("current_phase", nextPhase.PhaseName)
-		} else {
-			// 所有阶段完成，项目结项
-			db.Model(&models.Project{}).Where("id = ?", projectID).Update("status", config.StatusCompleted)
-		}
+		advancePhase(db, &phase)
 	}
 
 	// 记录日志
@@ -458,6 +548,11 @@ func (pc *ProjectController) AddMember(c *gin.Context) {
 // GetMembers 获取项目成员列表
 func (pc *ProjectController) GetMembers(c *gin.Context) {
 	projectID := c.Param("id")
+	pid, _ := strconv.ParseUint(projectID, 10, 32)
+	if !getProjectAccess(c).canView(uint(pid)) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
 
 	db := config.GetDB()
 	var members []models.ProjectMember
@@ -512,16 +607,17 @@ func (pc *ProjectController) RemoveMember(c *gin.Context) {
 // GetStatistics 获取项目统计信息
 func (pc *ProjectController) GetStatistics(c *gin.Context) {
 	db := config.GetDB()
+	visible := getProjectAccess(c).scope("id")
 
 	var totalProjects int64
 	var inProgressProjects int64
 	var completedProjects int64
 	var notStartedProjects int64
 
-	db.Model(&models.Project{}).Count(&totalProjects)
-	db.Model(&models.Project{}).Where("status = ?", config.StatusInProgress).Count(&inProgressProjects)
-	db.Model(&models.Project{}).Where("status = ?", config.StatusCompleted).Count(&completedProjects)
-	db.Model(&models.Project{}).Where("status = ?", config.StatusNotStarted).Count(&notStartedProjects)
+	db.Model(&models.Project{}).Scopes(visible).Count(&totalProjects)
+	db.Model(&models.Project{}).Scopes(visible).Where("status = ?", config.StatusInProgress).Count(&inProgressProjects)
+	db.Model(&models.Project{}).Scopes(visible).Where("status = ?", config.StatusCompleted).Count(&completedProjects)
+	db.Model(&models.Project{}).Scopes(visible).Where("status = ?", config.StatusNotStarted).Count(&notStartedProjects)
 
 	// 各阶段项目数量
 	type PhaseCount struct {
@@ -529,7 +625,7 @@ func (pc *ProjectController) GetStatistics(c *gin.Context) {
 		Count int64  `json:"count"`
 	}
 	var phaseCounts []PhaseCount
-	db.Model(&models.Project{}).Select("current_phase as phase, count(*) as count").
+	db.Model(&models.Project{}).Scopes(visible).Select("current_phase as phase, count(*) as count").
 		Group("current_phase").Scan(&phaseCounts)
 
 	utils.Success(c, gin.H{
@@ -572,10 +668,23 @@ func (pc *ProjectController) AddPhase(c *gin.Context) {
 		return
 	}
 
+	// 自定义阶段位于合同和验收之间，验收已开始后不能再添加
+	var startedLater int64
+	db.Model(&models.ProjectPhase{}).Where("project_id = ? AND phase_order > ? AND status <> ?", projectID, customPhaseOrderEnd, config.StatusNotStarted).
+		Count(&startedLater)
+	if startedLater > 0 {
+		utils.Error(c, 400, "验收阶段已开始，无法添加自定义阶段")
+		return
+	}
+
 	// 获取当前最大的自定义阶段顺序（圈定范围：大于等于4且小于100）
 	var maxOrder int
-	db.Model(&models.ProjectPhase{}).Where("project_id = ? AND phase_order >= 4 AND phase_order < 100", projectID).
-		Select("COALESCE(MAX(phase_order), 3)").Scan(&maxOrder)
+	db.Model(&models.ProjectPhase{}).Where("project_id = ? AND phase_order >= ? AND phase_order <= ?", projectID, customPhaseOrderStart, customPhaseOrderEnd).
+		Select(fmt.Sprintf("COALESCE(MAX(phase_order), %d)", customPhaseOrderStart-1)).Scan(&maxOrder)
+	if maxOrder >= customPhaseOrderEnd {
+		utils.Error(c, 400, "自定义阶段数量过多")
+		return
+	}
 
 	newPhase := models.ProjectPhase{
 		ProjectID:  project.ID,
@@ -629,6 +738,12 @@ func (pc *ProjectController) DeletePhase(c *gin.Context) {
 		return
 	}
 
+	// 进行中的阶段不可删除，否则阶段推进会中断
+	if phase.Status == config.StatusInProgress {
+		utils.Error(c, 400, "进行中的阶段不可删除")
+		return
+	}
+
 	// 检查阶段下是否有任务
 	var taskCount int64
 	db.Model(&models.Task{}).Where("phase_id = ?", phaseID).Count(&taskCount)
@@ -648,3 +763,305 @@ func (pc *ProjectController) DeletePhase(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
+
+// 克隆项目时资料/合同的处理方式
+const (
+	CloneSkip      = "skip"      // 不复制
+	CloneReference = "reference" // 以引用方式复制（新记录指向同一文件，不复制文件本身）
+)
+
+// CloneProjectRequest 克隆项目请求
+type CloneProjectRequest struct {
+	Name           string `json:"name" binding:"required"`
+	ProjectNo      string `json:"project_no"`                         // 留空自动生成
+	ManagerID      uint   `json:"manager_id"`                         // 为空时沿用原项目负责人
+	InitiationDate string `json:"initiation_date" binding:"required"` // 新立项日期，阶段和任务的日期按相同偏移量平移
+	ClosingDate    string `json:"closing_date"`                       // 为空时按原项目平移
+	Documents      string `json:"documents"`                          // 项目资料：skip/reference，默认不复制
+	Contracts      string `json:"contracts"`                          // 合同：skip/reference，默认不复制
+}
+
+// Clone 克隆项目：复制预算、阶段、成员和任务，重置状态并按新的立项日期平移截止日期
+func (pc *ProjectController) Clone(c *gin.Context) {
+	var req CloneProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写项目名称和立项日期")
+		return
+	}
+	if req.Documents == "" {
+		req.Documents = CloneSkip
+	}
+	if req.Contracts == "" {
+		req.Contracts = CloneSkip
+	}
+	if (req.Documents != CloneSkip && req.Documents != CloneReference) ||
+		(req.Contracts != CloneSkip && req.Contracts != CloneReference) {
+		utils.BadRequest(c, "资料和合同的复制方式只能是 skip 或 reference")
+		return
+	}
+
+	initiationDate, err := time.Parse("2006-01-02", req.InitiationDate)
+	if err != nil {
+		utils.BadRequest(c, "立项日期格式错误")
+		return
+	}
+
+	db := config.GetDB()
+	var source models.Project
+	if err := db.First(&source, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(source.ID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	// 生成项目编号
+	projectNo := req.ProjectNo
+	if projectNo == "" {
+		projectNo = fmt.Sprintf("PRJ%s%04d", time.Now().Format("20060102"), time.Now().UnixNano()%10000)
+	}
+	var existing models.Project
+	if db.Where("project_no = ?", projectNo).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "项目编号已存在")
+		return
+	}
+
+	// 日期偏移量：新立项日期 - 原立项日期
+	var offset time.Duration
+	if source.InitiationDate != nil {
+		offset = initiationDate.Sub(*source.InitiationDate)
+	}
+	shift := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		shifted := t.Add(offset)
+		return &shifted
+	}
+
+	project := models.Project{
+		ProjectNo:       projectNo,
+		Name:            req.Name,
+		ProjectType:     source.ProjectType,
+		ManagerID:       source.ManagerID,
+		SubManagerID:    source.SubManagerID,
+		BudgetCode:      source.BudgetCode,
+		InitiationDate:  &initiationDate,
+		ClosingDate:     shift(source.ClosingDate),
+		LaborCost:       source.LaborCost,
+		DirectCost:      source.DirectCost,
+		OutsourcingCost: source.OutsourcingCost,
+		OtherCost:       source.OtherCost,
+		CurrentPhase:    config.PhaseInitiation,
+		Status:          config.StatusInProgress,
+		TemplateID:      source.TemplateID,
+		CreatedBy:       userID.(uint),
+	}
+	if req.ManagerID != 0 {
+		project.ManagerID = req.ManagerID
+	}
+	if req.ClosingDate != "" {
+		t, err := time.Parse("2006-01-02", req.ClosingDate)
+		if err != nil {
+			utils.BadRequest(c, "结项日期格式错误")
+			return
+		}
+		project.ClosingDate = &t
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+
+		// 阶段：保留名称、顺序和必需资料，状态重置（立项阶段进行中）
+		var phases []models.ProjectPhase
+		tx.Where("project_id = ?", source.ID).Order("phase_order").Find(&phases)
+		phaseMap := make(map[uint]uint, len(phases))
+		for _, p := range phases {
+			status := config.StatusNotStarted
+			if p.PhaseName == config.PhaseInitiation {
+				status = config.StatusInProgress
+			}
+			phase := models.ProjectPhase{
+				ProjectID:        project.ID,
+				PhaseName:        p.PhaseName,
+				PhaseOrder:       p.PhaseOrder,
+				IsFixed:          p.IsFixed,
+				Status:           status,
+				RequiredDocTypes: p.RequiredDocTypes,
+				RequireSignOff:   p.RequireSignOff,
+				EndDate:          shift(p.EndDate),
+				Remark:           p.Remark,
+			}
+			if err := tx.Create(&phase).Error; err != nil {
+				return err
+			}
+			phaseMap[p.ID] = phase.ID
+		}
+
+		// 成员：负责人变更时替换负责人成员
+		var members []models.ProjectMember
+		tx.Where("project_id = ?", source.ID).Find(&members)
+		hasManager := false
+		for _, m := range members {
+			if m.RoleType == "manager" {
+				if hasManager {
+					continue
+				}
+				m.UserID = project.ManagerID
+				hasManager = true
+			} else if m.UserID == project.ManagerID {
+				continue
+			}
+			member := models.ProjectMember{ProjectID: project.ID, UserID: m.UserID, RoleType: m.RoleType, JoinDate: time.Now()}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		if !hasManager {
+			member := models.ProjectMember{ProjectID: project.ID, UserID: project.ManagerID, RoleType: "manager", JoinDate: time.Now()}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+
+		// 任务：重置状态、审核信息和完成时间，截止日期平移；检查清单复制为未勾选
+		var tasks []models.Task
+		tx.Preload("Checklist").Where("project_id = ?", source.ID).Order("id").Find(&tasks)
+		taskMap := make(map[uint]uint, len(tasks))
+		for _, t := range tasks {
+			task := models.Task{
+				ProjectID:      project.ID,
+				PhaseID:        phaseMap[t.PhaseID],
+				TaskName:       t.TaskName,
+				Description:    t.Description,
+				TaskType:       t.TaskType,
+				AssigneeID:     t.AssigneeID,
+				AssigneeType:   t.AssigneeType,
+				Deadline:       shift(t.Deadline),
+				PlannedStart:   shift(t.PlannedStart),
+				Duration:       t.Duration,
+				EstimatedHours: t.EstimatedHours,
+				Status:         config.TaskNotStarted,
+				Priority:       t.Priority,
+				Deliverables:   t.Deliverables,
+				CreatedBy:      userID.(uint),
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			taskMap[t.ID] = task.ID
+			for _, item := range t.Checklist {
+				copied := models.TaskChecklistItem{TaskID: task.ID, Content: item.Content, SortOrder: item.SortOrder, CreatedBy: userID.(uint)}
+				if err := tx.Create(&copied).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// 子任务层级
+		for _, t := range tasks {
+			if t.ParentID == nil || taskMap[*t.ParentID] == 0 {
+				continue
+			}
+			if err := tx.Model(&models.Task{}).Where("id = ?", taskMap[t.ID]).Update("parent_id", taskMap[*t.ParentID]).Error; err != nil {
+				return err
+			}
+		}
+
+		// 任务依赖
+		var deps []models.TaskDependency
+		tx.Where("project_id = ?", source.ID).Find(&deps)
+		for _, d := range deps {
+			dep := models.TaskDependency{
+				ProjectID:     project.ID,
+				PredecessorID: taskMap[d.PredecessorID],
+				SuccessorID:   taskMap[d.SuccessorID],
+				Type:          d.Type,
+				Lag:           d.Lag,
+				CreatedBy:     userID.(uint),
+			}
+			if dep.PredecessorID == 0 || dep.SuccessorID == 0 {
+				continue
+			}
+			if err := tx.Create(&dep).Error; err != nil {
+				return err
+			}
+		}
+
+		// 项目资料（任务交付件属于原任务的执行结果，不复制）
+		if req.Documents == CloneReference {
+			var docs []models.Document
+			tx.Where("project_id = ? AND task_id IS NULL", source.ID).Find(&docs)
+			for _, d := range docs {
+				doc := models.Document{
+					ProjectID:  project.ID,
+					PhaseID:    phaseMap[d.PhaseID],
+					DocName:    d.DocName,
+					DocType:    d.DocType,
+					FilePath:   d.FilePath,
+					FileSize:   d.FileSize,
+					MimeType:   d.MimeType,
+					Version:    d.Version,
+					Status:     "pending",
+					UploadedBy: userID.(uint),
+					Remark:     d.Remark,
+				}
+				if err := tx.Create(&doc).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// 合同（合同编号唯一，追加新项目ID区分）
+		if req.Contracts == CloneReference {
+			var contracts []models.Contract
+			tx.Where("project_id = ?", source.ID).Find(&contracts)
+			for _, ct := range contracts {
+				contract := models.Contract{
+					ProjectID:     project.ID,
+					ContractNo:    cloneContractNo(ct.ContractNo, project.ID),
+					ContractName:  ct.ContractName,
+					PartyA:        ct.PartyA,
+					PartyB:        ct.PartyB,
+					Amount:        ct.Amount,
+					SignDate:      shift(ct.SignDate),
+					StartDate:     shift(ct.StartDate),
+					EndDate:       shift(ct.EndDate),
+					PaymentMethod: ct.PaymentMethod,
+					Status:        "draft",
+					FilePath:      ct.FilePath,
+					CreatedBy:     userID.(uint),
+				}
+				if err := tx.Create(&contract).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ServerError(c, "克隆项目失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "clone", "project", "project", project.ID, project.Name,
+		fmt.Sprintf("克隆项目: %s -> %s", source.Name, project.Name), "success")
+
+	utils.SuccessWithMessage(c, "克隆成功", project)
+}
+
+// cloneContractNo 生成克隆合同的编号（合同编号唯一，最长50个字符）
+func cloneContractNo(contractNo string, projectID uint) string {
+	suffix := fmt.Sprintf("-P%d", projectID)
+	if runes := []rune(contractNo); len(runes)+len(suffix) > 50 {
+		contractNo = string(runes[:50-len(suffix)])
+	}
+	return contractNo + suffix
+}
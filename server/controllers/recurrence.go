@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/recurrence"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RecurrenceController struct{}
+
+// previewCount 预览的发生次数
+const previewCount = 10
+
+// RecurrenceRequest 重复任务系列请求
+type RecurrenceRequest struct {
+	ProjectID    uint   `json:"project_id" binding:"required"`
+	PhaseID      uint   `json:"phase_id"` // 为0时按发生日期匹配项目阶段
+	TaskName     string `json:"task_name" binding:"required"`
+	Description  string `json:"description"`
+	TaskType     string `json:"task_type"`
+	AssigneeID   uint   `json:"assignee_id"`
+	Priority     int    `json:"priority"`
+	Deliverables string `json:"deliverables"`
+	Duration     int    `json:"duration"`
+	Rule         string `json:"rule" binding:"required"`     // RRULE，如 FREQ=WEEKLY;BYDAY=MO,TH
+	StartAt      string `json:"start_at" binding:"required"` // 第一次截止时间，格式 2006-01-02 15:04
+}
+
+// parseRecurrenceRule 校验重复规则和开始时间
+func parseRecurrenceRule(rule, startAt string) (*recurrence.Rule, time.Time, string) {
+	start, err := time.Parse("2006-01-02 15:04", startAt)
+	if err != nil {
+		return nil, start, "开始时间格式错误"
+	}
+	r, err := recurrence.Parse(rule, start)
+	if err != nil {
+		return nil, start, err.Error()
+	}
+	return r, start, ""
+}
+
+// validateRecurrence 校验系列的阶段、负责人和工期，返回错误信息
+func validateRecurrence(db *gorm.DB, req *RecurrenceRequest) string {
+	if req.PhaseID != 0 {
+		var count int64
+		db.Model(&models.ProjectPhase{}).Where("id = ? AND project_id = ?", req.PhaseID, req.ProjectID).Count(&count)
+		if count == 0 {
+			return "阶段不属于该项目"
+		}
+	}
+	if req.AssigneeID != 0 {
+		var assignee models.User
+		if err := db.Preload("Role").First(&assignee, req.AssigneeID).Error; err != nil {
+			return "任务负责人不存在"
+		}
+		if assignee.Role != nil && assignee.Role.Code == config.RoleAdmin {
+			return "系统管理员不能作为任务负责人"
+		}
+	}
+	if req.Duration < 0 {
+		return "工期不能为负数"
+	}
+	return ""
+}
+
+// loadRecurrenceProject 加载项目，manage 为 true 时要求项目负责人或管理员权限
+func loadRecurrenceProject(c *gin.Context, db *gorm.DB, projectID uint, manage bool) (*models.Project, bool) {
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return nil, false
+	}
+	if !getProjectAccess(c).canView(project.ID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return nil, false
+	}
+	if manage && !canManageProjectTasks(c, &project) {
+		utils.Forbidden(c, "只有项目负责人才能管理重复任务")
+		return nil, false
+	}
+	return &project, true
+}
+
+// List 获取项目的重复任务系列
+func (rc *RecurrenceController) List(c *gin.Context) {
+	projectID, _ := strconv.ParseUint(c.Query("project_id"), 10, 32)
+	db := config.GetDB()
+	if _, ok := loadRecurrenceProject(c, db, uint(projectID), false); !ok {
+		return
+	}
+
+	var series []models.TaskRecurrence
+	db.Preload("Assignee").Where("project_id = ?", projectID).Order("id DESC").Find(&series)
+	utils.Success(c, series)
+}
+
+// PreviewRecurrenceRequest 预览重复规则请求
+type PreviewRecurrenceRequest struct {
+	Rule    string `json:"rule" binding:"required"`
+	StartAt string `json:"start_at" binding:"required"`
+}
+
+// Preview 预览重复规则接下来的发生时间
+func (rc *RecurrenceController) Preview(c *gin.Context) {
+	var req PreviewRecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写重复规则和开始时间")
+		return
+	}
+	rule, start, msg := parseRecurrenceRule(req.Rule, req.StartAt)
+	if msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	from := time.Now()
+	if start.After(from) {
+		from = start
+	}
+	dates := rule.Between(from, from.AddDate(10, 0, 0))
+	if len(dates) > previewCount {
+		dates = dates[:previewCount]
+	}
+	utils.Success(c, dates)
+}
+
+// Create 创建重复任务系列并立即生成近期的任务实例
+func (rc *RecurrenceController) Create(c *gin.Context) {
+	var req RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写任务名称、重复规则和开始时间")
+		return
+	}
+
+	db := config.GetDB()
+	project, ok := loadRecurrenceProject(c, db, req.ProjectID, true)
+	if !ok {
+		return
+	}
+	_, start, msg := parseRecurrenceRule(req.Rule, req.StartAt)
+	if msg == "" {
+		msg = validateRecurrence(db, &req)
+	}
+	if msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	now := time.Now()
+	series := models.TaskRecurrence{
+		ProjectID:     req.ProjectID,
+		PhaseID:       req.PhaseID,
+		TaskName:      req.TaskName,
+		Description:   req.Description,
+		TaskType:      req.TaskType,
+		AssigneeID:    req.AssigneeID,
+		Priority:      req.Priority,
+		Deliverables:  req.Deliverables,
+		Duration:      req.Duration,
+		Rule:          req.Rule,
+		StartAt:       start,
+		EffectiveFrom: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, start.Location()),
+		Status:        models.RecurrenceActive,
+		CreatedBy:     userID.(uint),
+	}
+	if series.Priority == 0 {
+		series.Priority = 2
+	}
+	if err := db.Create(&series).Error; err != nil {
+		utils.ServerError(c, "创建重复任务失败")
+		return
+	}
+	generated, _ := recurrence.Generate(db, &series, now)
+
+	// 记录日志
+	middleware.LogOperation(c, "create_recurrence", "task", "project", project.ID, project.Name,
+		fmt.Sprintf("创建重复任务: %s（%s），生成%d个实例", series.TaskName, series.Rule, generated), "success")
+
+	utils.SuccessWithMessage(c, fmt.Sprintf("创建成功，已生成%d个任务", generated), series)
+}
+
+// loadRecurrence 加载生效中的重复任务系列并校验管理权限
+func loadRecurrence(c *gin.Context, db *gorm.DB) (*models.TaskRecurrence, *models.Project, bool) {
+	var series models.TaskRecurrence
+	if err := db.First(&series, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "重复任务不存在")
+		return nil, nil, false
+	}
+	project, ok := loadRecurrenceProject(c, db, series.ProjectID, true)
+	if !ok {
+		return nil, nil, false
+	}
+	if series.Status != models.RecurrenceActive {
+		utils.Error(c, 400, "重复任务已停止")
+		return nil, nil, false
+	}
+	return &series, project, true
+}
+
+// Update 修改重复任务系列：只影响以后的实例，尚未开始的未来实例按新设置重新生成
+func (rc *RecurrenceController) Update(c *gin.Context) {
+	var req RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写任务名称、重复规则和开始时间")
+		return
+	}
+
+	db := config.GetDB()
+	series, project, ok := loadRecurrence(c, db)
+	if !ok {
+		return
+	}
+	req.ProjectID = series.ProjectID
+	_, start, msg := parseRecurrenceRule(req.Rule, req.StartAt)
+	if msg == "" {
+		msg = validateRecurrence(db, &req)
+	}
+	if msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+	if req.Priority == 0 {
+		req.Priority = 2
+	}
+
+	now := time.Now()
+	removed := recurrence.DeleteFuture(db, series.ID, now)
+	db.Model(series).Updates(map[string]interface{}{
+		"phase_id":        req.PhaseID,
+		"task_name":       req.TaskName,
+		"description":     req.Description,
+		"task_type":       req.TaskType,
+		"assignee_id":     req.AssigneeID,
+		"priority":        req.Priority,
+		"deliverables":    req.Deliverables,
+		"duration":        req.Duration,
+		"rule":            req.Rule,
+		"start_at":        start,
+		"effective_from":  now,
+		"generated_until": nil,
+	})
+	series.EffectiveFrom, series.GeneratedUntil = now, nil
+	generated, _ := recurrence.Generate(db, series, now)
+
+	// 记录日志
+	middleware.LogOperation(c, "update_recurrence", "task", "project", project.ID, project.Name,
+		fmt.Sprintf("修改重复任务: %s（%s），重新生成%d个未来实例（原%d个）", series.TaskName, series.Rule, generated, removed), "success")
+
+	utils.SuccessWithMessage(c, "修改成功", series)
+}
+
+// Stop 停止重复任务系列：不再生成实例，并删除尚未开始的未来实例
+func (rc *RecurrenceController) Stop(c *gin.Context) {
+	db := config.GetDB()
+	series, project, ok := loadRecurrence(c, db)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	db.Model(series).Updates(map[string]interface{}{"status": models.RecurrenceStopped, "stopped_at": now})
+	removed := recurrence.DeleteFuture(db, series.ID, now)
+
+	// 记录日志
+	middleware.LogOperation(c, "stop_recurrence", "task", "project", project.ID, project.Name,
+		fmt.Sprintf("停止重复任务: %s，删除%d个未开始的未来实例", series.TaskName, removed), "success")
+
+	utils.SuccessWithMessage(c, "已停止", nil)
+}
@@ -0,0 +1,526 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 审核人类型名称
+var reviewerTypeLabels = map[string]string{
+	models.ReviewerProjectManager: "项目负责人",
+	models.ReviewerSubManager:     "项目子负责人",
+	models.ReviewerDeptManager:    "部门经理",
+	models.ReviewerUser:           "指定审核人",
+}
+
+// defaultReviewSteps 项目未配置审核链时的默认审核步骤：项目负责人审核
+func defaultReviewSteps() []models.ReviewChainStep {
+	return []models.ReviewChainStep{{StepOrder: 1, Name: "项目负责人审核", ReviewerType: models.ReviewerProjectManager}}
+}
+
+// findReviewChain 查找任务适用的审核链：优先匹配任务类型，其次为项目默认审核链
+func findReviewChain(db *gorm.DB, task *models.Task) []models.ReviewChainStep {
+	var chain models.ReviewChain
+	err := db.Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("step_order") }).
+		Where("project_id = ? AND task_type IN ?", task.ProjectID, []string{task.TaskType, ""}).
+		Order("task_type DESC").First(&chain).Error
+	if err != nil || len(chain.Steps) == 0 {
+		return defaultReviewSteps()
+	}
+	return chain.Steps
+}
+
+// stepReviewerID 审核步骤对应的确定审核人（部门经理类型返回0）
+func stepReviewerID(project *models.Project, reviewerType string, reviewerID *uint) uint {
+	switch reviewerType {
+	case models.ReviewerProjectManager:
+		return project.ManagerID
+	case models.ReviewerSubManager:
+		if project.SubManagerID != nil {
+			return *project.SubManagerID
+		}
+	case models.ReviewerUser:
+		if reviewerID != nil {
+			return *reviewerID
+		}
+	}
+	return 0
+}
+
+// canReviewStep 当前用户能否审核该步骤：必须是步骤对应的审核人（管理员可代为审核），且不能是任务负责人
+func canReviewStep(c *gin.Context, task *models.Task, step *models.TaskReviewStep) bool {
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+	if task.AssigneeID == userID.(uint) {
+		return false
+	}
+	if roleCode == config.RoleAdmin {
+		return true
+	}
+	if step.ReviewerType == models.ReviewerDeptManager {
+		return roleCode == config.RoleDeptManager && getProjectAccess(c).canView(task.ProjectID)
+	}
+	return stepReviewerID(task.Project, step.ReviewerType, step.ReviewerID) == userID.(uint)
+}
+
+// notifyReviewers 通知当前步骤的审核人（部门经理类型无法确定具体人员，不发送）
+func notifyReviewers(db *gorm.DB, task *models.Task, step *models.TaskReviewStep, senderID uint) {
+	reviewerID := stepReviewerID(task.Project, step.ReviewerType, step.ReviewerID)
+	notifyUsers(db, []uint{reviewerID}, models.Notification{
+		Type:       models.NotificationReview,
+		Title:      fmt.Sprintf("任务「%s」等待你审核（%s）", task.TaskName, step.Name),
+		TargetType: models.CommentTargetTask,
+		TargetID:   task.ID,
+		ProjectID:  task.ProjectID,
+		SenderID:   senderID,
+	})
+}
+
+// activeReviewRound 任务进行中的审核轮次
+func activeReviewRound(db *gorm.DB, taskID uint) (*models.TaskReviewRound, bool) {
+	var round models.TaskReviewRound
+	err := db.Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("step_order") }).
+		Where("task_id = ? AND status = ?", taskID, models.ReviewPending).
+		Order("round DESC").First(&round).Error
+	return &round, err == nil && round.CurrentStep >= 1 && round.CurrentStep <= len(round.Steps)
+}
+
+// cancelReviewRound 撤回审核：取消任务进行中的审核轮次及其未完成的步骤
+func cancelReviewRound(tx *gorm.DB, taskID uint) error {
+	round, ok := activeReviewRound(tx, taskID)
+	if !ok {
+		return nil
+	}
+	if err := tx.Model(&models.TaskReviewStep{}).
+		Where("round_id = ? AND status IN ?", round.ID, []string{models.ReviewPending, models.ReviewWaiting}).
+		Update("status", models.ReviewCancelled).Error; err != nil {
+		return err
+	}
+	return tx.Model(round).Updates(map[string]interface{}{"status": models.ReviewCancelled, "completed_at": time.Now()}).Error
+}
+
+// SubmitReviewRequest 提交审核请求
+type SubmitReviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+// SubmitReview 提交任务审核，按适用的审核链开始新一轮审核
+func (tc *TaskController) SubmitReview(c *gin.Context) {
+	var req SubmitReviewRequest
+	c.ShouldBindJSON(&req)
+
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !canOperateTask(c, &task) {
+		utils.Forbidden(c, "只有任务负责人或项目经理才能提交审核")
+		return
+	}
+	if _, ok := activeReviewRound(db, task.ID); ok {
+		utils.Error(c, 400, "任务正在审核中")
+		return
+	}
+	actor, ok := checkTransition(c, &task, config.TaskSubmitted, taskActors(c, &task), true)
+	if !ok {
+		return
+	}
+	if hasOpenChildren(db, task.ID) {
+		utils.Error(c, 400, "存在未完成的子任务，不能提交审核")
+		return
+	}
+	if blocking := blockingPredecessors(db, &task, config.TaskCompleted); len(blocking) > 0 {
+		utils.ErrorWithData(c, 400, "前置任务尚未完成", gin.H{"blocking": blocking})
+		return
+	}
+
+	// 步骤审核人为任务负责人本人或无法确定（如项目未设置子负责人）时，改由部门经理审核
+	chainSteps := findReviewChain(db, &task)
+	steps := make([]models.TaskReviewStep, len(chainSteps))
+	for i, s := range chainSteps {
+		steps[i] = models.TaskReviewStep{StepOrder: i + 1, Name: s.Name, ReviewerType: s.ReviewerType, ReviewerID: s.ReviewerID, Status: models.ReviewWaiting}
+		if steps[i].Name == "" {
+			steps[i].Name = reviewerTypeLabels[s.ReviewerType] + "审核"
+		}
+		if reviewerID := stepReviewerID(task.Project, s.ReviewerType, s.ReviewerID); s.ReviewerType != models.ReviewerDeptManager &&
+			(reviewerID == 0 || reviewerID == task.AssigneeID) {
+			steps[i].ReviewerType, steps[i].ReviewerID = models.ReviewerDeptManager, nil
+			steps[i].Name += "（部门经理代审）"
+		}
+	}
+	steps[0].Status = models.ReviewPending
+
+	var lastRound int
+	db.Model(&models.TaskReviewRound{}).Where("task_id = ?", task.ID).Select("COALESCE(MAX(round), 0)").Scan(&lastRound)
+
+	userID, _ := c.Get("userID")
+	round := models.TaskReviewRound{
+		TaskID:      task.ID,
+		Round:       lastRound + 1,
+		Status:      models.ReviewPending,
+		CurrentStep: 1,
+		SubmittedBy: userID.(uint),
+		Comment:     req.Comment,
+		Steps:       steps,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&round).Error; err != nil {
+			return err
+		}
+		return applyTaskStatus(tx, &task, config.TaskSubmitted, actor, userID.(uint), req.Comment,
+			map[string]interface{}{"review_status": models.ReviewPending})
+	})
+	if err != nil {
+		utils.ServerError(c, "提交审核失败")
+		return
+	}
+	rollupParentStatus(db, &task, config.TaskSubmitted)
+	notifyReviewers(db, &task, &round.Steps[0], userID.(uint))
+
+	// 记录日志
+	middleware.LogOperation(c, "submit_review", "task", "task", task.ID, task.TaskName,
+		fmt.Sprintf("提交审核（第%d轮）: %s", round.Round, task.TaskName), "success")
+
+	utils.SuccessWithMessage(c, "已提交审核", round)
+}
+
+// ReviewRequest 审核请求
+type ReviewRequest struct {
+	Status  string `json:"status" binding:"required"` // approved/rejected
+	Comment string `json:"comment"`
+}
+
+// ReviewTask 审核当前步骤：通过则进入下一步骤，全部通过后任务完成；驳回则结束本轮审核
+func (tc *TaskController) ReviewTask(c *gin.Context) {
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Status != models.ReviewApproved && req.Status != models.ReviewRejected) {
+		utils.BadRequest(c, "请选择审核结果")
+		return
+	}
+	if req.Status == models.ReviewRejected && req.Comment == "" {
+		utils.BadRequest(c, "驳回时请填写审核意见")
+		return
+	}
+
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	round, ok := activeReviewRound(db, task.ID)
+	if !ok {
+		utils.Error(c, 400, "任务未提交审核")
+		return
+	}
+	step := &round.Steps[round.CurrentStep-1]
+
+	userID, _ := c.Get("userID")
+	operatorID := userID.(uint)
+	if task.AssigneeID == operatorID {
+		utils.Forbidden(c, "不能审核自己负责的任务")
+		return
+	}
+	if !canReviewStep(c, &task, step) {
+		utils.Forbidden(c, fmt.Sprintf("当前审核步骤为「%s」，你不是该步骤的审核人", step.Name))
+		return
+	}
+
+	now := time.Now()
+	final := req.Status == models.ReviewRejected || round.CurrentStep == len(round.Steps)
+	to := config.TaskRejected
+	if req.Status == models.ReviewApproved {
+		to = config.TaskCompleted
+	}
+	if final {
+		if _, ok := checkTransition(c, &task, to, []string{ActorReviewer}, true); !ok {
+			return
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(step).Updates(map[string]interface{}{
+			"status":      req.Status,
+			"comment":     req.Comment,
+			"reviewed_by": operatorID,
+			"reviewed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if !final {
+			if err := tx.Model(&round.Steps[round.CurrentStep]).Update("status", models.ReviewPending).Error; err != nil {
+				return err
+			}
+			return tx.Model(round).Update("current_step", round.CurrentStep+1).Error
+		}
+
+		if req.Status == models.ReviewRejected {
+			if err := tx.Model(&models.TaskReviewStep{}).
+				Where("round_id = ? AND status = ?", round.ID, models.ReviewWaiting).
+				Update("status", models.ReviewCancelled).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(round).Updates(map[string]interface{}{"status": req.Status, "completed_at": now}).Error; err != nil {
+			return err
+		}
+
+		// 任务上的审核字段保存最近一次审核结论，完整记录见审核轮次
+		return applyTaskStatus(tx, &task, to, ActorReviewer, operatorID, req.Comment, map[string]interface{}{
+			"review_status":  req.Status,
+			"review_comment": req.Comment,
+			"reviewed_by":    operatorID,
+			"reviewed_at":    now,
+		})
+	})
+	if err != nil {
+		utils.ServerError(c, "审核失败")
+		return
+	}
+
+	action := "审核通过"
+	if req.Status == models.ReviewRejected {
+		action = "审核驳回"
+	}
+	if final {
+		rollupParentStatus(db, &task, to)
+		notifyUsers(db, []uint{task.AssigneeID}, models.Notification{
+			Type:       models.NotificationReview,
+			Title:      fmt.Sprintf("任务「%s」%s", task.TaskName, action),
+			Content:    req.Comment,
+			TargetType: models.CommentTargetTask,
+			TargetID:   task.ID,
+			ProjectID:  task.ProjectID,
+			SenderID:   operatorID,
+		})
+	} else {
+		notifyReviewers(db, &task, &round.Steps[round.CurrentStep], operatorID)
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "review", "task", "task", task.ID, task.TaskName,
+		fmt.Sprintf("%s（第%d轮 %s）: %s", action, round.Round, step.Name, task.TaskName), "success")
+
+	utils.SuccessWithMessage(c, "审核完成", nil)
+}
+
+// GetReviews 获取任务的审核历史（全部轮次）
+func (tc *TaskController) GetReviews(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	var rounds []models.TaskReviewRound
+	db.Preload("Submitter").
+		Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("step_order") }).
+		Preload("Steps.ReviewedUser").
+		Where("task_id = ?", task.ID).Order("round DESC").Find(&rounds)
+
+	// 当前用户能否审核进行中的步骤
+	canReview := false
+	for _, r := range rounds {
+		if r.Status == models.ReviewPending {
+			canReview = canReviewStep(c, &task, &r.Steps[r.CurrentStep-1])
+		}
+	}
+
+	utils.Success(c, gin.H{"rounds": rounds, "can_review": canReview})
+}
+
+// GetPendingReviews 获取等待当前用户审核的任务
+func (tc *TaskController) GetPendingReviews(c *gin.Context) {
+	db := config.GetDB()
+	var rounds []models.TaskReviewRound
+	db.Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("step_order") }).
+		Where("status = ?", models.ReviewPending).Order("id").Find(&rounds)
+
+	taskIDs := make([]uint, 0, len(rounds))
+	for _, r := range rounds {
+		taskIDs = append(taskIDs, r.TaskID)
+	}
+	var tasks []models.Task
+	if len(taskIDs) > 0 {
+		db.Preload("Project").Preload("Assignee").Where("id IN ?", taskIDs).Find(&tasks)
+	}
+	byID := make(map[uint]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	result := make([]gin.H, 0)
+	for _, r := range rounds {
+		task, ok := byID[r.TaskID]
+		if !ok || task.Project == nil {
+			continue
+		}
+		step := &r.Steps[r.CurrentStep-1]
+		if canReviewStep(c, task, step) {
+			result = append(result, gin.H{"task": task, "round": r.Round, "step": step, "submitted_at": r.CreatedAt})
+		}
+	}
+	utils.Success(c, result)
+}
+
+// ReviewChainStepRequest 审核链步骤
+type ReviewChainStepRequest struct {
+	Name         string `json:"name"`
+	ReviewerType string `json:"reviewer_type" binding:"required"`
+	ReviewerID   *uint  `json:"reviewer_id"`
+}
+
+// SaveReviewChainRequest 保存审核链请求
+type SaveReviewChainRequest struct {
+	TaskType string                   `json:"task_type"` // 为空表示项目默认审核链
+	Steps    []ReviewChainStepRequest `json:"steps" binding:"required,min=1,dive"`
+}
+
+// loadReviewChainProject 加载项目，查看以外的操作需要项目负责人或管理员权限
+func loadReviewChainProject(c *gin.Context, db *gorm.DB, manage bool) (*models.Project, bool) {
+	var project models.Project
+	if err := db.First(&project, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "项目不存在")
+		return nil, false
+	}
+	if !getProjectAccess(c).canView(project.ID) {
+		utils.Forbidden(c, "没有权限查看该项目")
+		return nil, false
+	}
+	if manage && !canManageProjectTasks(c, &project) {
+		utils.Forbidden(c, "只有项目负责人才能配置审核链")
+		return nil, false
+	}
+	return &project, true
+}
+
+// GetReviewChains 获取项目的审核链配置
+func (pc *ProjectController) GetReviewChains(c *gin.Context) {
+	db := config.GetDB()
+	project, ok := loadReviewChainProject(c, db, false)
+	if !ok {
+		return
+	}
+
+	var chains []models.ReviewChain
+	db.Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("step_order") }).Preload("Steps.Reviewer").
+		Where("project_id = ?", project.ID).Order("task_type").Find(&chains)
+
+	utils.Success(c, gin.H{"chains": chains, "default_steps": defaultReviewSteps()})
+}
+
+// SaveReviewChain 保存项目审核链（同一任务类型已有审核链时整体替换步骤）
+func (pc *ProjectController) SaveReviewChain(c *gin.Context) {
+	var req SaveReviewChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请至少配置一个审核步骤")
+		return
+	}
+
+	db := config.GetDB()
+	project, ok := loadReviewChainProject(c, db, true)
+	if !ok {
+		return
+	}
+
+	steps := make([]models.ReviewChainStep, len(req.Steps))
+	for i, s := range req.Steps {
+		label, ok := reviewerTypeLabels[s.ReviewerType]
+		if !ok {
+			utils.BadRequest(c, fmt.Sprintf("第%d步审核人类型无效", i+1))
+			return
+		}
+		step := models.ReviewChainStep{StepOrder: i + 1, Name: s.Name, ReviewerType: s.ReviewerType}
+		if s.ReviewerType == models.ReviewerUser {
+			var count int64
+			if s.ReviewerID != nil {
+				db.Model(&models.User{}).Where("id = ? AND status = ?", *s.ReviewerID, 1).Count(&count)
+			}
+			if count == 0 {
+				utils.BadRequest(c, fmt.Sprintf("第%d步请选择有效的审核人", i+1))
+				return
+			}
+			step.ReviewerID = s.ReviewerID
+		}
+		if step.Name == "" {
+			step.Name = label + "审核"
+		}
+		steps[i] = step
+	}
+
+	userID, _ := c.Get("userID")
+	var chain models.ReviewChain
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(models.ReviewChain{ProjectID: project.ID, TaskType: req.TaskType}).
+			Attrs(models.ReviewChain{CreatedBy: userID.(uint)}).FirstOrCreate(&chain).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("chain_id = ?", chain.ID).Delete(&models.ReviewChainStep{}).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].ChainID = chain.ID
+		}
+		if err := tx.Create(&steps).Error; err != nil {
+			return err
+		}
+		return tx.Model(&chain).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "保存审核链失败")
+		return
+	}
+	chain.Steps = steps
+
+	// 记录日志
+	scope := "默认"
+	if req.TaskType != "" {
+		scope = req.TaskType
+	}
+	middleware.LogOperation(c, "save_review_chain", "project", "project", project.ID, project.Name,
+		fmt.Sprintf("保存审核链（%s，%d步）", scope, len(steps)), "success")
+
+	utils.SuccessWithMessage(c, "保存成功", chain)
+}
+
+// DeleteReviewChain 删除项目审核链（删除后该类型任务使用项目默认审核链）
+func (pc *ProjectController) DeleteReviewChain(c *gin.Context) {
+	db := config.GetDB()
+	project, ok := loadReviewChainProject(c, db, true)
+	if !ok {
+		return
+	}
+
+	chainID, _ := strconv.ParseUint(c.Param("chainId"), 10, 32)
+	var chain models.ReviewChain
+	if err := db.Where("id = ? AND project_id = ?", chainID, project.ID).First(&chain).Error; err != nil {
+		utils.NotFound(c, "审核链不存在")
+		return
+	}
+	db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chain_id = ?", chain.ID).Delete(&models.ReviewChainStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&chain).Error
+	})
+
+	// 记录日志
+	middleware.LogOperation(c, "delete_review_chain", "project", "project", project.ID, project.Name, "删除审核链", "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
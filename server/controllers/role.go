@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"encoding/json"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct{}
+
+// RoleRequest 创建/更新角色请求
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Code        string   `json:"code"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	// 是否要求该角色的用户启用双因素认证（内置角色也可设置）
+	RequireTwoFactor *bool `json:"require_two_factor"`
+}
+
+// GetPermissions 获取系统支持的全部权限
+func (rc *RoleController) GetPermissions(c *gin.Context) {
+	utils.Success(c, config.AllPermissions)
+}
+
+// Get 获取角色详情
+func (rc *RoleController) Get(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var role models.Role
+	if err := db.First(&role, id).Error; err != nil {
+		utils.NotFound(c, "角色不存在")
+		return
+	}
+
+	utils.Success(c, role)
+}
+
+// Create 创建自定义角色
+func (rc *RoleController) Create(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" || req.Code == "" {
+		utils.BadRequest(c, "请填写角色名称和编码")
+		return
+	}
+
+	permissions, ok := encodePermissions(req.Permissions)
+	if !ok {
+		utils.BadRequest(c, "包含不支持的权限标识")
+		return
+	}
+
+	db := config.GetDB()
+	var existing models.Role
+	if db.Where("code = ? OR name = ?", req.Code, req.Name).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "角色名称或编码已存在")
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Code:        req.Code,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	if req.RequireTwoFactor != nil {
+		role.RequireTwoFactor = *req.RequireTwoFactor
+	}
+	if err := db.Create(&role).Error; err != nil {
+		utils.ServerError(c, "创建角色失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "create", "role", "role", role.ID, role.Name, "创建角色: "+role.Name, "success")
+
+	utils.SuccessWithMessage(c, "创建成功", role)
+}
+
+// Update 更新角色（内置角色只能修改名称、描述和双因素认证要求）
+func (rc *RoleController) Update(c *gin.Context) {
+	id := c.Param("id")
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	db := config.GetDB()
+	var role models.Role
+	if err := db.First(&role, id).Error; err != nil {
+		utils.NotFound(c, "角色不存在")
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" && req.Name != role.Name {
+		var existing models.Role
+		if db.Where("name = ? AND id <> ?", req.Name, role.ID).First(&existing).RowsAffected > 0 {
+			utils.Error(c, 400, "角色名称已存在")
+			return
+		}
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Code != "" && req.Code != role.Code {
+		if role.IsSystem {
+			utils.Error(c, 400, "内置角色不能修改编码")
+			return
+		}
+		var existing models.Role
+		if db.Where("code = ? AND id <> ?", req.Code, role.ID).First(&existing).RowsAffected > 0 {
+			utils.Error(c, 400, "角色编码已存在")
+			return
+		}
+		updates["code"] = req.Code
+	}
+	if req.Permissions != nil {
+		if role.IsSystem {
+			utils.Error(c, 400, "内置角色的权限由系统维护，请创建自定义角色")
+			return
+		}
+		permissions, ok := encodePermissions(req.Permissions)
+		if !ok {
+			utils.BadRequest(c, "包含不支持的权限标识")
+			return
+		}
+		updates["permissions"] = permissions
+	}
+
+	if req.RequireTwoFactor != nil {
+		updates["require_two_factor"] = *req.RequireTwoFactor
+	}
+
+	if err := db.Model(&role).Updates(updates).Error; err != nil {
+		utils.ServerError(c, "更新失败")
+		return
+	}
+
+	// 角色编码变更后，原Token中的角色编码失效，需要该角色下的用户重新登录
+	if code, ok := updates["code"]; ok && code != role.Code {
+		var userIDs []uint
+		db.Model(&models.User{}).Where("role_id = ?", role.ID).Pluck("id", &userIDs)
+		for _, uid := range userIDs {
+			revokeUserSessions(uid)
+		}
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "update", "role", "role", role.ID, role.Name, "更新角色: "+role.Name, "success")
+
+	utils.SuccessWithMessage(c, "更新成功", nil)
+}
+
+// Delete 删除自定义角色
+func (rc *RoleController) Delete(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var role models.Role
+	if err := db.First(&role, id).Error; err != nil {
+		utils.NotFound(c, "角色不存在")
+		return
+	}
+
+	if role.IsSystem {
+		utils.Error(c, 400, "内置角色不可删除")
+		return
+	}
+
+	var userCount int64
+	db.Model(&models.User{}).Where("role_id = ?", role.ID).Count(&userCount)
+	if userCount > 0 {
+		utils.Error(c, 400, "该角色下存在用户，无法删除")
+		return
+	}
+
+	if err := db.Unscoped().Delete(&role).Error; err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "delete", "role", "role", role.ID, role.Name, "删除角色: "+role.Name, "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// encodePermissions 校验权限列表并序列化为存储格式
+func encodePermissions(perms []string) (string, bool) {
+	seen := make(map[string]bool)
+	list := make([]string, 0, len(perms))
+	for _, p := range perms {
+		if !config.IsValidPermission(p) {
+			return "", false
+		}
+		if !seen[p] {
+			seen[p] = true
+			list = append(list, p)
+		}
+	}
+	data, _ := json.Marshal(list)
+	return string(data), true
+}
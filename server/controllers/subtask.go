@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxTaskDepth 任务最大层级（顶层任务为第1层）
+const maxTaskDepth = 3
+
+// checklistStat 检查清单统计
+type checklistStat struct {
+	Total   int
+	Checked int
+}
+
+// checklistStats 统计任务检查清单的总数和已勾选数
+func checklistStats(db *gorm.DB, taskIDs []uint) map[uint]checklistStat {
+	stats := make(map[uint]checklistStat)
+	if len(taskIDs) == 0 {
+		return stats
+	}
+	var rows []struct {
+		TaskID  uint
+		Total   int
+		Checked int
+	}
+	db.Model(&models.TaskChecklistItem{}).
+		Select("task_id, COUNT(*) AS total, SUM(CASE WHEN checked THEN 1 ELSE 0 END) AS checked").
+		Where("task_id IN ?", taskIDs).Group("task_id").Scan(&rows)
+	for _, r := range rows {
+		stats[r.TaskID] = checklistStat{Total: r.Total, Checked: r.Checked}
+	}
+	return stats
+}
+
+// taskProgressMap 计算任务进度：已完成为100；有子任务时取子任务平均进度；
+// 有检查清单时按勾选比例；否则进行中按50计。tasks 需包含全部子孙任务
+func taskProgressMap(tasks []models.Task, stats map[uint]checklistStat) map[uint]int {
+	byID := make(map[uint]*models.Task, len(tasks))
+	children := make(map[uint][]uint)
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+		if tasks[i].ParentID != nil {
+			children[*tasks[i].ParentID] = append(children[*tasks[i].ParentID], tasks[i].ID)
+		}
+	}
+
+	progress := make(map[uint]int, len(tasks))
+	var compute func(id uint) int
+	compute = func(id uint) int {
+		if p, ok := progress[id]; ok {
+			return p
+		}
+		t := byID[id]
+		p := 0
+		switch {
+		case t.Status == config.TaskCompleted:
+			p = 100
+		case len(children[id]) > 0:
+			total := 0
+			for _, child := range children[id] {
+				total += compute(child)
+			}
+			p = total / len(children[id])
+		case stats[id].Total > 0:
+			p = stats[id].Checked * 100 / stats[id].Total
+		case t.Status != config.TaskNotStarted:
+			p = 50
+		}
+		progress[id] = p
+		return p
+	}
+	for id := range byID {
+		compute(id)
+	}
+	return progress
+}
+
+// loadDescendants 加载任务的全部子孙任务
+func loadDescendants(db *gorm.DB, taskIDs []uint) []models.Task {
+	var all []models.Task
+	ids := taskIDs
+	for depth := 0; depth < maxTaskDepth && len(ids) > 0; depth++ {
+		var children []models.Task
+		db.Preload("Assignee").Where("parent_id IN ?", ids).Order("id").Find(&children)
+		ids = make([]uint, 0, len(children))
+		for _, child := range children {
+			ids = append(ids, child.ID)
+		}
+		all = append(all, children...)
+	}
+	return all
+}
+
+// buildTaskTree 为任务挂载子任务树并汇总进度
+func buildTaskTree(db *gorm.DB, roots []models.Task) []models.Task {
+	if len(roots) == 0 {
+		return roots
+	}
+	ids := make([]uint, 0, len(roots))
+	for _, t := range roots {
+		ids = append(ids, t.ID)
+	}
+	descendants := loadDescendants(db, ids)
+
+	all := append(append([]models.Task{}, roots...), descendants...)
+	allIDs := make([]uint, 0, len(all))
+	for _, t := range all {
+		allIDs = append(allIDs, t.ID)
+	}
+	progress := taskProgressMap(all, checklistStats(db, allIDs))
+
+	children := make(map[uint][]models.Task)
+	for _, t := range descendants {
+		children[*t.ParentID] = append(children[*t.ParentID], t)
+	}
+	var build func(t models.Task) models.Task
+	build = func(t models.Task) models.Task {
+		for _, child := range children[t.ID] {
+			t.Children = append(t.Children, build(child))
+		}
+		t.Progress = progress[t.ID]
+		return t
+	}
+
+	result := make([]models.Task, len(roots))
+	for i := range roots {
+		result[i] = build(roots[i])
+	}
+	return result
+}
+
+// taskDepth 任务所在层级
+func taskDepth(db *gorm.DB, task *models.Task) int {
+	depth := 1
+	for parentID := task.ParentID; parentID != nil && depth <= maxTaskDepth; depth++ {
+		var parent models.Task
+		if db.Select("id, parent_id").First(&parent, *parentID).Error != nil {
+			break
+		}
+		parentID = parent.ParentID
+	}
+	return depth
+}
+
+// loadParentTask 加载并校验父任务，失败时返回错误信息
+func loadParentTask(db *gorm.DB, parentID, projectID uint) (*models.Task, string) {
+	var parent models.Task
+	if err := db.First(&parent, parentID).Error; err != nil {
+		return nil, "父任务不存在"
+	}
+	if parent.ProjectID != projectID {
+		return nil, "父任务不属于该项目"
+	}
+	if parent.Status == config.TaskCompleted {
+		return nil, "父任务已完成，不能再添加子任务"
+	}
+	if taskDepth(db, &parent) >= maxTaskDepth {
+		return nil, fmt.Sprintf("子任务最多%d层", maxTaskDepth)
+	}
+	return &parent, ""
+}
+
+// hasOpenChildren 是否存在未完成的子任务
+func hasOpenChildren(db *gorm.DB, taskID uint) bool {
+	var count int64
+	db.Model(&models.Task{}).Where("parent_id = ? AND status <> ?", taskID, config.TaskCompleted).Count(&count)
+	return count > 0
+}
+
+// rollupParentStatus 子任务状态变化时向上汇总父任务状态：
+// 子任务开始后父任务随之开始；子任务重新打开时已完成的父任务恢复为进行中（记录为系统变更）
+func rollupParentStatus(db *gorm.DB, task *models.Task, status string) {
+	for parentID, depth := task.ParentID, 0; parentID != nil && depth < maxTaskDepth; depth++ {
+		var parent models.Task
+		if db.First(&parent, *parentID).Error != nil {
+			return
+		}
+		comment := ""
+		switch {
+		case status != config.TaskNotStarted && status != config.TaskBlocked && parent.Status == config.TaskNotStarted:
+			comment = "子任务已开始"
+		case status != config.TaskCompleted && parent.Status == config.TaskCompleted:
+			comment = "子任务重新打开"
+		default:
+			return
+		}
+		if applyTaskStatus(db, &parent, config.TaskInProgress, ActorSystem, 0, comment, nil) != nil {
+			return
+		}
+		parentID = parent.ParentID
+	}
+}
+
+// canOperateTask 任务负责人、项目负责人或管理员可操作任务
+func canOperateTask(c *gin.Context, task *models.Task) bool {
+	userID, _ := c.Get("userID")
+	return task.AssigneeID == userID.(uint) || canManageProjectTasks(c, task.Project)
+}
+
+// loadChecklistTask 加载检查清单所属任务并校验操作权限
+func loadChecklistTask(c *gin.Context, db *gorm.DB) (*models.Task, bool) {
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return nil, false
+	}
+	if !canOperateTask(c, &task) {
+		utils.Forbidden(c, "只有任务负责人或项目经理才能修改检查清单")
+		return nil, false
+	}
+	return &task, true
+}
+
+// GetChecklist 获取任务检查清单
+func (tc *TaskController) GetChecklist(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	var items []models.TaskChecklistItem
+	db.Where("task_id = ?", task.ID).Order("sort_order, id").Find(&items)
+	utils.Success(c, items)
+}
+
+// ChecklistItemRequest 检查清单项请求
+type ChecklistItemRequest struct {
+	Content   string `json:"content"`
+	Checked   *bool  `json:"checked"`
+	SortOrder *int   `json:"sort_order"`
+}
+
+// AddChecklistItem 添加检查清单项
+func (tc *TaskController) AddChecklistItem(c *gin.Context) {
+	var req ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Content == "" {
+		utils.BadRequest(c, "请输入检查项内容")
+		return
+	}
+
+	db := config.GetDB()
+	task, ok := loadChecklistTask(c, db)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	item := models.TaskChecklistItem{TaskID: task.ID, Content: req.Content, CreatedBy: userID.(uint)}
+	if req.SortOrder != nil {
+		item.SortOrder = *req.SortOrder
+	} else {
+		db.Model(&models.TaskChecklistItem{}).Where("task_id = ?", task.ID).
+			Select("COALESCE(MAX(sort_order), 0) + 1").Scan(&item.SortOrder)
+	}
+	if err := db.Create(&item).Error; err != nil {
+		utils.ServerError(c, "添加检查项失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "添加成功", item)
+}
+
+// UpdateChecklistItem 修改或勾选检查清单项
+func (tc *TaskController) UpdateChecklistItem(c *gin.Context) {
+	var req ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	db := config.GetDB()
+	task, ok := loadChecklistTask(c, db)
+	if !ok {
+		return
+	}
+
+	var item models.TaskChecklistItem
+	if err := db.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).First(&item).Error; err != nil {
+		utils.NotFound(c, "检查项不存在")
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Content != "" {
+		updates["content"] = req.Content
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+	if req.Checked != nil && *req.Checked != item.Checked {
+		updates["checked"] = *req.Checked
+		if *req.Checked {
+			userID, _ := c.Get("userID")
+			updates["checked_by"] = userID.(uint)
+			updates["checked_at"] = time.Now()
+		} else {
+			updates["checked_by"] = nil
+			updates["checked_at"] = nil
+		}
+	}
+	db.Model(&item).Updates(updates)
+
+	utils.SuccessWithMessage(c, "更新成功", item)
+}
+
+// DeleteChecklistItem 删除检查清单项
+func (tc *TaskController) DeleteChecklistItem(c *gin.Context) {
+	db := config.GetDB()
+	task, ok := loadChecklistTask(c, db)
+	if !ok {
+		return
+	}
+
+	result := db.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).Delete(&models.TaskChecklistItem{})
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "检查项不存在")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "delete_checklist_item", "task", "task", task.ID, task.TaskName, "删除检查项", "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
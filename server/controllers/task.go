@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"project-flow/config"
 	"project-flow/middleware"
 	"project-flow/models"
@@ -22,10 +24,13 @@ func (tc *TaskController) GetTaskStatistics(c *gin.Context) {
 	var inProgress int64
 	var completed int64
 	var rejected int64
+	var submitted int64
+	var blocked int64
 
-	// 所有人都查看全部任务的统计情况，不做权限过滤
+	// 按项目可见范围统计
+	visible := getProjectAccess(c).scope("project_id")
 	getBaseQuery := func() *gorm.DB {
-		return db.Model(&models.Task{})
+		return db.Model(&models.Task{}).Scopes(visible)
 	}
 
 	// 总数
@@ -36,6 +41,14 @@ func (tc *TaskController) GetTaskStatistics(c *gin.Context) {
 	getBaseQuery().Where("status = ?", config.TaskInProgress).Count(&inProgress)
 	getBaseQuery().Where("status = ?", config.TaskCompleted).Count(&completed)
 	getBaseQuery().Where("status = ?", config.TaskRejected).Count(&rejected)
+	getBaseQuery().Where("status = ?", config.TaskSubmitted).Count(&submitted)
+	getBaseQuery().Where("status = ?", config.TaskBlocked).Count(&blocked)
+
+	// 逾期统计：未完成且已过截止时间；已升级为逾期提醒项目负责人及以上的单独统计
+	var overdue, escalated int64
+	now := time.Now()
+	getBaseQuery().Where("status <> ? AND deadline < ?", config.TaskCompleted, now).Count(&overdue)
+	getBaseQuery().Where("status <> ? AND deadline < ? AND escalation_level >= ?", config.TaskCompleted, now, models.EscalationManager).Count(&escalated)
 
 	stats := map[string]int64{
 		"total":       total,
@@ -43,6 +56,10 @@ func (tc *TaskController) GetTaskStatistics(c *gin.Context) {
 		"in_progress": inProgress,
 		"completed":   completed,
 		"rejected":    rejected,
+		"submitted":   submitted,
+		"blocked":     blocked,
+		"overdue":     overdue,
+		"escalated":   escalated,
 	}
 
 	utils.Success(c, stats)
@@ -52,29 +69,38 @@ type TaskController struct{}
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	ProjectID    uint   `json:"project_id" binding:"required"`
-	PhaseID      uint   `json:"phase_id"`
-	TaskName     string `json:"task_name" binding:"required"`
-	Description  string `json:"description"`
-	TaskType     string `json:"task_type"`
-	AssigneeID   uint   `json:"assignee_id"`
-	AssigneeType string `json:"assignee_type"`
-	Deadline     string `json:"deadline"`
-	Priority     int    `json:"priority"`
-	Deliverables string `json:"deliverables"`
+	ProjectID      uint    `json:"project_id" binding:"required"`
+	PhaseID        uint    `json:"phase_id"`
+	TaskName       string  `json:"task_name" binding:"required"`
+	Description    string  `json:"description"`
+	TaskType       string  `json:"task_type"`
+	AssigneeID     uint    `json:"assignee_id"`
+	AssigneeType   string  `json:"assignee_type"`
+	Deadline       string  `json:"deadline"`
+	PlannedStart   string  `json:"planned_start"`   // 计划开始日期
+	Duration       int     `json:"duration"`        // 计划工期（天）
+	EstimatedHours float64 `json:"estimated_hours"` // 预估工时（小时）
+	Priority       int     `json:"priority"`
+	Deliverables   string  `json:"deliverables"`
+	ParentID       *uint   `json:"parent_id"` // 父任务ID，子任务沿用父任务的项目和阶段
+
+	Children []CreateTaskRequest `json:"children"` // 子任务（仅批量创建时支持）
 }
 
 // UpdateTaskRequest 更新任务请求
 type UpdateTaskRequest struct {
-	TaskName     string `json:"task_name"`
-	Description  string `json:"description"`
-	TaskType     string `json:"task_type"`
-	AssigneeID   uint   `json:"assignee_id"`
-	AssigneeType string `json:"assignee_type"`
-	Deadline     string `json:"deadline"`
-	Priority     int    `json:"priority"`
-	Deliverables string `json:"deliverables"`
-	Status       string `json:"status"`
+	TaskName       string   `json:"task_name"`
+	Description    string   `json:"description"`
+	TaskType       string   `json:"task_type"`
+	AssigneeID     uint     `json:"assignee_id"`
+	AssigneeType   string   `json:"assignee_type"`
+	Deadline       string   `json:"deadline"`
+	PlannedStart   string   `json:"planned_start"`
+	Duration       *int     `json:"duration"`
+	EstimatedHours *float64 `json:"estimated_hours"` // 预估工时（小时）
+	Priority       int      `json:"priority"`
+	Deliverables   string   `json:"deliverables"`
+	Status         string   `json:"status"`
 }
 
 // List 获取任务列表
@@ -86,15 +112,15 @@ func (tc *TaskController) List(c *gin.Context) {
 	status := c.Query("status")
 	assigneeID := c.Query("assignee_id")
 	keyword := c.Query("keyword")
+	tree := c.Query("tree") == "1" // 树形输出：只分页顶层任务，子任务挂在 children 下
 
 	db := config.GetDB()
 
 	var tasks []models.Task
 	var total int64
 
-	query := db.Model(&models.Task{}).Preload("Project.Manager").Preload("Project").Preload("Phase").Preload("Assignee")
-
-	// 所有角色都可以查看所有任务
+	query := db.Model(&models.Task{}).Preload("Project.Manager").Preload("Project").Preload("Phase").Preload("Assignee").
+		Scopes(getProjectAccess(c).scope("tasks.project_id"))
 
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
@@ -111,10 +137,20 @@ func (tc *TaskController) List(c *gin.Context) {
 	if keyword != "" {
 		query = query.Where("task_name LIKE ?", "%"+keyword+"%")
 	}
+	if tree {
+		query = query.Where("tasks.parent_id IS NULL")
+	}
 
 	query.Count(&total)
 	query.Offset((page - 1) * pageSize).Limit(pageSize).Order("tasks.id DESC").Find(&tasks)
 
+	tasks = buildTaskTree(db, tasks)
+	if !tree {
+		for i := range tasks {
+			tasks[i].Children = nil
+		}
+	}
+
 	utils.SuccessPage(c, tasks, total, page, pageSize)
 }
 
@@ -155,24 +191,45 @@ func (tc *TaskController) Create(c *gin.Context) {
 		}
 	}
 
+	var parentID *uint
+	if req.ParentID != nil {
+		parent, msg := loadParentTask(db, *req.ParentID, req.ProjectID)
+		if parent == nil {
+			utils.BadRequest(c, msg)
+			return
+		}
+		parentID, req.PhaseID = &parent.ID, parent.PhaseID
+	}
+
 	task := models.Task{
-		ProjectID:    req.ProjectID,
-		PhaseID:      req.PhaseID,
-		TaskName:     req.TaskName,
-		Description:  req.Description,
-		TaskType:     req.TaskType,
-		AssigneeID:   req.AssigneeID,
-		AssigneeType: req.AssigneeType,
-		Priority:     req.Priority,
-		Deliverables: req.Deliverables,
-		Status:       config.TaskNotStarted,
-		CreatedBy:    userID.(uint),
+		ProjectID:      req.ProjectID,
+		PhaseID:        req.PhaseID,
+		ParentID:       parentID,
+		TaskName:       req.TaskName,
+		Description:    req.Description,
+		TaskType:       req.TaskType,
+		AssigneeID:     req.AssigneeID,
+		AssigneeType:   req.AssigneeType,
+		Priority:       req.Priority,
+		Deliverables:   req.Deliverables,
+		Duration:       req.Duration,
+		EstimatedHours: req.EstimatedHours,
+		Status:         config.TaskNotStarted,
+		CreatedBy:      userID.(uint),
 	}
 
 	if req.Deadline != "" {
 		t, _ := time.Parse("2006-01-02 15:04", req.Deadline)
 		task.Deadline = &t
 	}
+	if req.PlannedStart != "" {
+		t, _ := time.Parse("2006-01-02", req.PlannedStart)
+		task.PlannedStart = &t
+	}
+	if task.Duration < 0 || task.EstimatedHours < 0 {
+		utils.BadRequest(c, "工期和预估工时不能为负数")
+		return
+	}
 
 	if err := db.Create(&task).Error; err != nil {
 		utils.ServerError(c, "创建任务失败")
@@ -217,39 +274,87 @@ func (tc *TaskController) BatchCreate(c *gin.Context) {
 		}
 	}
 
+	// 递归创建任务及其子任务，任一失败则全部回滚
 	var createdTasks []models.Task
-	for _, t := range req.Tasks {
+	var create func(tx *gorm.DB, t CreateTaskRequest, parent *models.Task) (*models.Task, error)
+	create = func(tx *gorm.DB, t CreateTaskRequest, parent *models.Task) (*models.Task, error) {
+		if t.TaskName == "" {
+			return nil, errors.New("请填写任务名称")
+		}
 		if t.AssigneeID != 0 {
 			var assignee models.User
-			if err := db.Preload("Role").First(&assignee, t.AssigneeID).Error; err != nil {
-				utils.BadRequest(c, "任务负责人不存在")
-				return
+			if err := tx.Preload("Role").First(&assignee, t.AssigneeID).Error; err != nil {
+				return nil, errors.New("任务负责人不存在")
 			}
 			if assignee.Role != nil && assignee.Role.Code == config.RoleAdmin {
-				utils.BadRequest(c, "系统管理员不能作为任务负责人")
-				return
+				return nil, errors.New("系统管理员不能作为任务负责人")
 			}
+		}
+		if parent == nil && t.ParentID != nil {
+			var msg string
+			if parent, msg = loadParentTask(tx, *t.ParentID, t.ProjectID); parent == nil {
+				return nil, errors.New(msg)
+			}
+		}
+		if parent != nil {
+			if taskDepth(tx, parent) >= maxTaskDepth {
+				return nil, fmt.Errorf("子任务最多%d层", maxTaskDepth)
+			}
+			t.ProjectID, t.PhaseID = parent.ProjectID, parent.PhaseID
 		}
 
 		task := models.Task{
-			ProjectID:    t.ProjectID,
-			PhaseID:      t.PhaseID,
-			TaskName:     t.TaskName,
-			Description:  t.Description,
-			TaskType:     t.TaskType,
-			AssigneeID:   t.AssigneeID,
-			AssigneeType: t.AssigneeType,
-			Priority:     t.Priority,
-			Deliverables: t.Deliverables,
-			Status:       config.TaskNotStarted,
-			CreatedBy:    userID.(uint),
+			ProjectID:      t.ProjectID,
+			PhaseID:        t.PhaseID,
+			TaskName:       t.TaskName,
+			Description:    t.Description,
+			TaskType:       t.TaskType,
+			AssigneeID:     t.AssigneeID,
+			AssigneeType:   t.AssigneeType,
+			Priority:       t.Priority,
+			Deliverables:   t.Deliverables,
+			Duration:       t.Duration,
+			EstimatedHours: t.EstimatedHours,
+			Status:         config.TaskNotStarted,
+			CreatedBy:      userID.(uint),
 		}
 		if t.Deadline != "" {
 			deadline, _ := time.Parse("2006-01-02", t.Deadline)
 			task.Deadline = &deadline
 		}
-		db.Create(&task)
-		createdTasks = append(createdTasks, task)
+		if t.PlannedStart != "" {
+			plannedStart, _ := time.Parse("2006-01-02", t.PlannedStart)
+			task.PlannedStart = &plannedStart
+		}
+		if parent != nil {
+			task.ParentID = &parent.ID
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return nil, err
+		}
+		for _, child := range t.Children {
+			created, err := create(tx, child, &task)
+			if err != nil {
+				return nil, err
+			}
+			task.Children = append(task.Children, *created)
+		}
+		return &task, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range req.Tasks {
+			task, err := create(tx, t, nil)
+			if err != nil {
+				return err
+			}
+			createdTasks = append(createdTasks, *task)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
 	}
 
 	utils.SuccessWithMessage(c, "批量创建成功", createdTasks)
@@ -266,6 +371,14 @@ func (tc *TaskController) Get(c *gin.Context) {
 		return
 	}
 
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	db.Where("task_id = ?", task.ID).Order("sort_order, id").Find(&task.Checklist)
+	task = buildTaskTree(db, []models.Task{task})[0]
+
 	utils.Success(c, task)
 }
 
@@ -281,7 +394,7 @@ func (tc *TaskController) Update(c *gin.Context) {
 
 	db := config.GetDB()
 	var task models.Task
-	if err := db.First(&task, id).Error; err != nil {
+	if err := db.Preload("Project").First(&task, id).Error; err != nil {
 		utils.NotFound(c, "任务不存在")
 		return
 	}
@@ -317,21 +430,41 @@ func (tc *TaskController) Update(c *gin.Context) {
 	if req.Deliverables != "" {
 		updates["deliverables"] = req.Deliverables
 	}
-	if req.Status != "" {
-		updates["status"] = req.Status
-		if req.Status == config.TaskCompleted {
-			now := time.Now()
-			updates["completed_at"] = now
-		}
-	}
 	if req.Deadline != "" {
 		t, _ := time.Parse("2006-01-02", req.Deadline)
 		updates["deadline"] = t
 	}
+	if req.PlannedStart != "" {
+		t, _ := time.Parse("2006-01-02", req.PlannedStart)
+		updates["planned_start"] = t
+	}
+	if req.Duration != nil {
+		if *req.Duration < 0 {
+			utils.BadRequest(c, "工期不能为负数")
+			return
+		}
+		updates["duration"] = *req.Duration
+	}
+	if req.EstimatedHours != nil {
+		if *req.EstimatedHours < 0 {
+			utils.BadRequest(c, "预估工时不能为负数")
+			return
+		}
+		updates["estimated_hours"] = *req.EstimatedHours
+	}
 
-	if err := db.Model(&task).Updates(updates).Error; err != nil {
-		utils.ServerError(c, "更新失败")
-		return
+	// 状态变更同样遵循任务状态机，并记录状态历史
+	if req.Status != "" && req.Status != task.Status {
+		if _, ok := changeTaskStatus(c, db, &task, req.Status, "", false); !ok {
+			return
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := db.Model(&task).Updates(updates).Error; err != nil {
+			utils.ServerError(c, "更新失败")
+			return
+		}
 	}
 
 	// 记录日志
@@ -366,10 +499,21 @@ func (tc *TaskController) Delete(c *gin.Context) {
 		}
 	}
 
+	var childCount int64
+	db.Model(&models.Task{}).Where("parent_id = ?", task.ID).Count(&childCount)
+	if childCount > 0 {
+		utils.Error(c, 400, "该任务下存在子任务，请先删除子任务")
+		return
+	}
+
 	if err := db.Unscoped().Delete(&task).Error; err != nil {
 		utils.ServerError(c, "删除失败")
 		return
 	}
+	db.Where("predecessor_id = ? OR successor_id = ?", task.ID, task.ID).Delete(&models.TaskDependency{})
+	db.Where("task_id = ?", task.ID).Delete(&models.TaskChecklistItem{})
+	// 已填报的工时保留在项目上
+	db.Model(&models.TimeEntry{}).Where("task_id = ?", task.ID).Update("task_id", nil)
 
 	// 记录日志
 	middleware.LogOperation(c, "delete", "task", "task", task.ID, task.TaskName, "删除任务: "+task.TaskName, "success")
@@ -379,7 +523,9 @@ func (tc *TaskController) Delete(c *gin.Context) {
 
 // UpdateStatusRequest 更新任务状态请求
 type UpdateStatusRequest struct {
-	Status string `json:"status" binding:"required"`
+	Status   string `json:"status" binding:"required"`
+	Comment  string `json:"comment"`  // 变更说明，变更为受阻时必填
+	Override bool   `json:"override"` // 项目负责人强制变更，忽略未完成的前置任务
 }
 
 // UpdateStatus 更新任务状态
@@ -392,7 +538,6 @@ func (tc *TaskController) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
 	db := config.GetDB()
 	var task models.Task
 	if err := db.Preload("Project").First(&task, id).Error; err != nil {
@@ -400,87 +545,16 @@ func (tc *TaskController) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	// 权限检查：
-	// 1. 任务未完成时，任务负责人或项目经理有权限更改状态
-	// 2. 任务已完成时，只有项目经理（项目负责人）有权限重新开始
-	if task.Status != config.TaskCompleted {
-		// 任务未完成，任务负责人或项目经理有权限
-		if task.AssigneeID != userID.(uint) && task.Project.ManagerID != userID.(uint) {
-			utils.Forbidden(c, "只有任务负责人或项目经理才能更改任务状态")
-			return
-		}
-	} else {
-		// 任务已完成，只有项目经理有权限重新开始
-		if task.Project.ManagerID != userID.(uint) {
-			utils.Forbidden(c, "只有项目经理才能重新开始已完成的任务")
-			return
-		}
-	}
-
-	updates := map[string]interface{}{"status": req.Status}
-	if req.Status == config.TaskCompleted {
-		now := time.Now()
-		updates["completed_at"] = now
-	}
-
-	db.Model(&task).Updates(updates)
-
-	// 记录日志
-	middleware.LogOperation(c, "update_status", "task", "task", task.ID, task.TaskName, "更新任务状态: "+req.Status, "success")
-
-	utils.SuccessWithMessage(c, "更新成功", nil)
-}
-
-// ReviewRequest 审核请求
-type ReviewRequest struct {
-	Status  string `json:"status" binding:"required"` // approved/rejected
-	Comment string `json:"comment"`
-}
-
-// ReviewTask 审核任务交付件
-func (tc *TaskController) ReviewTask(c *gin.Context) {
-	id := c.Param("id")
-
-	var req ReviewRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, "请选择审核结果")
+	// 按任务状态机校验：允许的状态变更取决于当前状态和操作人角色（任务负责人/项目负责人）
+	description, ok := changeTaskStatus(c, db, &task, req.Status, req.Comment, req.Override)
+	if !ok {
 		return
 	}
 
-	userID, _ := c.Get("userID")
-	db := config.GetDB()
-
-	var task models.Task
-	if err := db.First(&task, id).Error; err != nil {
-		utils.NotFound(c, "任务不存在")
-		return
-	}
-
-	now := time.Now()
-	updates := map[string]interface{}{
-		"review_status":  req.Status,
-		"review_comment": req.Comment,
-		"reviewed_by":    userID,
-		"reviewed_at":    now,
-	}
-
-	if req.Status == "approved" {
-		updates["status"] = config.TaskCompleted
-		updates["completed_at"] = now
-	} else if req.Status == "rejected" {
-		updates["status"] = config.TaskRejected
-	}
-
-	db.Model(&task).Updates(updates)
-
 	// 记录日志
-	action := "审核通过"
-	if req.Status == "rejected" {
-		action = "审核驳回"
-	}
-	middleware.LogOperation(c, "review", "task", "task", task.ID, task.TaskName, action+": "+task.TaskName, "success")
+	middleware.LogOperation(c, "update_status", "task", "task", task.ID, task.TaskName, description, "success")
 
-	utils.SuccessWithMessage(c, "审核完成", nil)
+	utils.SuccessWithMessage(c, "更新成功", nil)
 }
 
 // GetMyTasks 获取我的任务
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 任务状态变更的操作角色
+const (
+	ActorAssignee = "assignee" // 任务负责人
+	ActorManager  = "manager"  // 项目负责人或管理员
+	ActorReviewer = "reviewer" // 当前审核步骤的审核人
+	ActorSystem   = "system"   // 系统自动变更（如子任务带动父任务）
+)
+
+// 任务状态名称
+var taskStatusLabels = map[string]string{
+	config.TaskNotStarted: "未开始",
+	config.TaskInProgress: "进行中",
+	config.TaskSubmitted:  "已提交审核",
+	config.TaskBlocked:    "受阻",
+	config.TaskCompleted:  "已完成",
+	config.TaskRejected:   "被驳回",
+}
+
+// statusTransition 允许的状态变更
+type statusTransition struct {
+	From      string
+	To        string
+	Actors    []string
+	ViaReview bool // 只能通过提交审核、审核接口变更
+}
+
+// taskTransitions 任务状态机：未列出的状态变更一律不允许
+var taskTransitions = []statusTransition{
+	{From: config.TaskNotStarted, To: config.TaskInProgress, Actors: []string{ActorAssignee, ActorManager, ActorSystem}},
+	{From: config.TaskNotStarted, To: config.TaskBlocked, Actors: []string{ActorAssignee, ActorManager}},
+	{From: config.TaskNotStarted, To: config.TaskSubmitted, Actors: []string{ActorAssignee, ActorManager}, ViaReview: true},
+	{From: config.TaskInProgress, To: config.TaskNotStarted, Actors: []string{ActorManager}},
+	{From: config.TaskInProgress, To: config.TaskBlocked, Actors: []string{ActorAssignee, ActorManager}},
+	{From: config.TaskInProgress, To: config.TaskSubmitted, Actors: []string{ActorAssignee, ActorManager}, ViaReview: true},
+	{From: config.TaskInProgress, To: config.TaskCompleted, Actors: []string{ActorManager}}, // 项目负责人可直接完成，负责人需提交审核
+	{From: config.TaskBlocked, To: config.TaskInProgress, Actors: []string{ActorAssignee, ActorManager}},
+	{From: config.TaskBlocked, To: config.TaskNotStarted, Actors: []string{ActorManager}},
+	{From: config.TaskSubmitted, To: config.TaskInProgress, Actors: []string{ActorAssignee, ActorManager}}, // 撤回审核
+	{From: config.TaskSubmitted, To: config.TaskCompleted, Actors: []string{ActorReviewer}, ViaReview: true},
+	{From: config.TaskSubmitted, To: config.TaskRejected, Actors: []string{ActorReviewer}, ViaReview: true},
+	{From: config.TaskRejected, To: config.TaskInProgress, Actors: []string{ActorAssignee, ActorManager}},
+	{From: config.TaskRejected, To: config.TaskSubmitted, Actors: []string{ActorAssignee, ActorManager}, ViaReview: true},
+	{From: config.TaskCompleted, To: config.TaskInProgress, Actors: []string{ActorManager, ActorSystem}}, // 重新开始
+}
+
+// findTransition 查找状态变更规则
+func findTransition(from, to string) *statusTransition {
+	for i := range taskTransitions {
+		if taskTransitions[i].From == from && taskTransitions[i].To == to {
+			return &taskTransitions[i]
+		}
+	}
+	return nil
+}
+
+// taskActors 当前用户在任务上的操作角色（需预加载 Project）
+func taskActors(c *gin.Context, task *models.Task) []string {
+	userID, _ := c.Get("userID")
+	var actors []string
+	if task.AssigneeID == userID.(uint) {
+		actors = append(actors, ActorAssignee)
+	}
+	if canManageProjectTasks(c, task.Project) {
+		actors = append(actors, ActorManager)
+	}
+	return actors
+}
+
+// allowedTransitions 指定角色可以直接变更到的状态（不含只能通过审核流程变更的状态）
+func allowedTransitions(from string, actors []string) []string {
+	allowed := []string{}
+	for _, t := range taskTransitions {
+		if t.From == from && !t.ViaReview && hasActor(t.Actors, actors) {
+			allowed = append(allowed, t.To)
+		}
+	}
+	return allowed
+}
+
+func hasActor(allowed, actors []string) bool {
+	for _, a := range actors {
+		for _, b := range allowed {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkTransition 校验状态变更，返回执行变更的角色；不允许时已写入结构化错误响应
+// viaReview 表示由提交审核、审核接口发起
+func checkTransition(c *gin.Context, task *models.Task, to string, actors []string, viaReview bool) (string, bool) {
+	from := task.Status
+	fail := func(reason, message string) (string, bool) {
+		utils.ErrorWithData(c, 400, message, gin.H{
+			"error":   "invalid_transition",
+			"reason":  reason,
+			"from":    from,
+			"to":      to,
+			"actors":  actors,
+			"allowed": allowedTransitions(from, actors),
+		})
+		return "", false
+	}
+
+	if _, ok := taskStatusLabels[to]; !ok {
+		return fail("unknown_status", "无效的任务状态: "+to)
+	}
+	if to == from {
+		return fail("unchanged", "任务已是「"+taskStatusLabels[to]+"」状态")
+	}
+	t := findTransition(from, to)
+	if t == nil {
+		return fail("not_allowed", "任务不能从「"+taskStatusLabels[from]+"」变更为「"+taskStatusLabels[to]+"」")
+	}
+	if t.ViaReview && !viaReview {
+		return fail("review_required", "请通过提交审核、审核操作变更为「"+taskStatusLabels[to]+"」")
+	}
+	for _, a := range actors {
+		if hasActor(t.Actors, []string{a}) {
+			return a, true
+		}
+	}
+	if len(actors) == 0 {
+		utils.Forbidden(c, "只有任务负责人或项目经理才能更改任务状态")
+		return "", false
+	}
+	return fail("actor_not_allowed", "你没有权限将任务从「"+taskStatusLabels[from]+"」变更为「"+taskStatusLabels[to]+"」")
+}
+
+// applyTaskStatus 执行状态变更并记录状态历史，extra 为同时更新的其他字段
+func applyTaskStatus(tx *gorm.DB, task *models.Task, to, actor string, operatorID uint, comment string, extra map[string]interface{}) error {
+	from := task.Status
+	now := time.Now()
+	updates := map[string]interface{}{"status": to}
+	if to != config.TaskNotStarted && to != config.TaskBlocked && task.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	if to == config.TaskCompleted {
+		updates["completed_at"] = now
+	} else if from == config.TaskCompleted {
+		updates["completed_at"] = nil
+	}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	if err := tx.Model(task).Updates(updates).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.TaskStatusHistory{
+		TaskID:     task.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      actor,
+		OperatorID: operatorID,
+		Comment:    comment,
+	}).Error
+}
+
+// changeTaskStatus 由任务负责人或项目负责人直接变更任务状态（需预加载 Project），
+// 校验状态机、子任务和前置任务后执行变更；返回操作日志描述，失败时已写入错误响应
+func changeTaskStatus(c *gin.Context, db *gorm.DB, task *models.Task, to, comment string, override bool) (string, bool) {
+	actor, ok := checkTransition(c, task, to, taskActors(c, task), false)
+	if !ok {
+		return "", false
+	}
+	if to == config.TaskBlocked && comment == "" {
+		utils.BadRequest(c, "请填写受阻原因")
+		return "", false
+	}
+	if to == config.TaskCompleted && hasOpenChildren(db, task.ID) {
+		utils.Error(c, 400, "存在未完成的子任务，不能完成该任务")
+		return "", false
+	}
+
+	// 前置任务未满足时拒绝变更，项目负责人可强制变更
+	description := "更新任务状态: " + taskStatusLabels[task.Status] + " -> " + taskStatusLabels[to]
+	if blocking := blockingPredecessors(db, task, to); len(blocking) > 0 {
+		if !override || !canManageProjectTasks(c, task.Project) {
+			utils.ErrorWithData(c, 400, "前置任务尚未完成", gin.H{"blocking": blocking})
+			return "", false
+		}
+		description += "（项目负责人强制变更，忽略未完成的前置任务）"
+	}
+
+	userID, _ := c.Get("userID")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var extra map[string]interface{}
+		if task.Status == config.TaskSubmitted {
+			// 撤回审核：取消进行中的审核轮次
+			if err := cancelReviewRound(tx, task.ID); err != nil {
+				return err
+			}
+			extra = map[string]interface{}{"review_status": models.ReviewCancelled}
+			description += "（撤回审核）"
+		}
+		return applyTaskStatus(tx, task, to, actor, userID.(uint), comment, extra)
+	})
+	if err != nil {
+		utils.ServerError(c, "更新失败")
+		return "", false
+	}
+	rollupParentStatus(db, task, to)
+	return description, true
+}
+
+// GetStatusTransitions 获取当前用户可以将任务变更到的状态
+func (tc *TaskController) GetStatusTransitions(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.Preload("Project").First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	actors := taskActors(c, &task)
+	canSubmit := false
+	for _, t := range taskTransitions {
+		if t.From == task.Status && t.To == config.TaskSubmitted && hasActor(t.Actors, actors) {
+			canSubmit = true
+		}
+	}
+	utils.Success(c, gin.H{
+		"status":     task.Status,
+		"actors":     actors,
+		"allowed":    allowedTransitions(task.Status, actors),
+		"can_submit": canSubmit,
+	})
+}
+
+// GetStatusHistory 获取任务状态变更记录
+func (tc *TaskController) GetStatusHistory(c *gin.Context) {
+	db := config.GetDB()
+	var task models.Task
+	if err := db.First(&task, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	if !getProjectAccess(c).canView(task.ProjectID) {
+		utils.Forbidden(c, "没有权限查看该任务")
+		return
+	}
+
+	var history []models.TaskStatusHistory
+	db.Preload("Operator").Where("task_id = ?", task.ID).Order("id DESC").Find(&history)
+	utils.Success(c, history)
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProjectTemplateController struct{}
+
+// 模板任务的默认责任人（项目中的角色）
+const (
+	AssigneeRoleManager    = "manager"     // 项目负责人
+	AssigneeRoleSubManager = "sub_manager" // 子负责人
+)
+
+// 自定义阶段的顺序范围（固定阶段占用 1~3 和 100~101）
+const (
+	customPhaseOrderStart = 4
+	customPhaseOrderEnd   = 99
+)
+
+// TemplateTaskRequest 模板任务骨架
+type TemplateTaskRequest struct {
+	TaskName       string `json:"task_name"`
+	Description    string `json:"description"`
+	TaskType       string `json:"task_type"`
+	Priority       int    `json:"priority"`
+	Deliverables   string `json:"deliverables"`
+	DeadlineOffset int    `json:"deadline_offset"` // 相对立项日期的天数
+	AssigneeRole   string `json:"assignee_role"`   // manager/sub_manager，为空不指派
+}
+
+// TemplatePhaseRequest 模板阶段
+type TemplatePhaseRequest struct {
+	PhaseName        string                `json:"phase_name"`
+	RequiredDocTypes []string              `json:"required_doc_types"`
+	RequireSignOff   bool                  `json:"require_sign_off"`
+	Tasks            []TemplateTaskRequest `json:"tasks"`
+}
+
+// ProjectTemplateRequest 创建/更新项目模板请求
+type ProjectTemplateRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	ProjectType string                 `json:"project_type"`
+	Description string                 `json:"description"`
+	IsActive    *bool                  `json:"is_active"`
+	Phases      []TemplatePhaseRequest `json:"phases"`
+}
+
+// List 获取项目模板列表（active=1 时只返回启用的模板）
+func (tc *ProjectTemplateController) List(c *gin.Context) {
+	query := config.GetDB().Model(&models.ProjectTemplate{})
+	if c.Query("active") == "1" {
+		query = query.Where("is_active = ?", true)
+	}
+	if projectType := c.Query("project_type"); projectType != "" {
+		query = query.Where("project_type = ? OR project_type = ''", projectType)
+	}
+
+	var templates []models.ProjectTemplate
+	query.Order("id DESC").Find(&templates)
+
+	utils.Success(c, templates)
+}
+
+// Get 获取项目模板详情（含阶段和任务骨架）
+func (tc *ProjectTemplateController) Get(c *gin.Context) {
+	template, err := loadProjectTemplate(config.GetDB(), c.Param("id"))
+	if err != nil {
+		utils.NotFound(c, "模板不存在")
+		return
+	}
+
+	utils.Success(c, template)
+}
+
+// Create 创建项目模板
+func (tc *ProjectTemplateController) Create(c *gin.Context) {
+	var req ProjectTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请输入模板名称")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	db := config.GetDB()
+
+	var existing models.ProjectTemplate
+	if db.Where("name = ?", req.Name).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "模板名称已存在")
+		return
+	}
+
+	template := models.ProjectTemplate{
+		Name:        req.Name,
+		ProjectType: req.ProjectType,
+		Description: req.Description,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedBy:   userID.(uint),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		return saveTemplatePhases(tx, template.ID, req.Phases)
+	})
+	if err != nil {
+		utils.ServerError(c, "创建模板失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "create", "project", "project_template", template.ID, template.Name, "创建项目模板: "+template.Name, "success")
+
+	utils.SuccessWithMessage(c, "创建成功", template)
+}
+
+// Update 更新项目模板（阶段和任务骨架整体替换，不影响已创建的项目）
+func (tc *ProjectTemplateController) Update(c *gin.Context) {
+	var req ProjectTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请输入模板名称")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	db := config.GetDB()
+	var template models.ProjectTemplate
+	if err := db.First(&template, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "模板不存在")
+		return
+	}
+
+	var existing models.ProjectTemplate
+	if db.Where("name = ? AND id <> ?", req.Name, template.ID).First(&existing).RowsAffected > 0 {
+		utils.Error(c, 400, "模板名称已存在")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":         req.Name,
+		"project_type": req.ProjectType,
+		"description":  req.Description,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&template).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Phases == nil {
+			return nil
+		}
+		if err := deleteTemplatePhases(tx, template.ID); err != nil {
+			return err
+		}
+		return saveTemplatePhases(tx, template.ID, req.Phases)
+	})
+	if err != nil {
+		utils.ServerError(c, "更新模板失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "update", "project", "project_template", template.ID, req.Name, "更新项目模板: "+req.Name, "success")
+
+	utils.SuccessWithMessage(c, "更新成功", nil)
+}
+
+// Delete 删除项目模板（已创建的项目不受影响）
+func (tc *ProjectTemplateController) Delete(c *gin.Context) {
+	db := config.GetDB()
+	var template models.ProjectTemplate
+	if err := db.First(&template, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "模板不存在")
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTemplatePhases(tx, template.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "delete", "project", "project_template", template.ID, template.Name, "删除项目模板: "+template.Name, "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// validate 校验模板内容，返回错误提示（为空表示通过）
+func (req *ProjectTemplateRequest) validate() string {
+	if req.ProjectType != "" && req.ProjectType != "成本性" && req.ProjectType != "资本性" {
+		return "项目类型必须是成本性或资本性"
+	}
+
+	seen := make(map[string]bool)
+	custom := 0
+	for i, phase := range req.Phases {
+		phase.PhaseName = strings.TrimSpace(phase.PhaseName)
+		if phase.PhaseName == "" {
+			return fmt.Sprintf("第%d个阶段缺少名称", i+1)
+		}
+		if seen[phase.PhaseName] {
+			return "阶段名称重复: " + phase.PhaseName
+		}
+		seen[phase.PhaseName] = true
+		if !isFixedPhase(phase.PhaseName) {
+			custom++
+		}
+		req.Phases[i].PhaseName = phase.PhaseName
+
+		for _, task := range phase.Tasks {
+			if strings.TrimSpace(task.TaskName) == "" {
+				return fmt.Sprintf("阶段 %s 中存在未填写名称的任务", phase.PhaseName)
+			}
+			if task.AssigneeRole != "" && task.AssigneeRole != AssigneeRoleManager && task.AssigneeRole != AssigneeRoleSubManager {
+				return "不支持的默认责任人: " + task.AssigneeRole
+			}
+			if task.DeadlineOffset < 0 {
+				return "任务截止日期偏移天数不能为负数"
+			}
+		}
+	}
+	if custom > customPhaseOrderEnd-customPhaseOrderStart+1 {
+		return "自定义阶段数量过多"
+	}
+	return ""
+}
+
+// isFixedPhase 判断是否为固定阶段编码
+func isFixedPhase(name string) bool {
+	_, ok := fixedPhaseOrders[name]
+	return ok
+}
+
+// fixedPhaseOrders 固定阶段及其顺序（中间的自定义阶段顺序从4开始）
+var fixedPhaseOrders = map[string]int{
+	config.PhaseInitiation: 1,
+	config.PhaseBidding:    2,
+	config.PhaseContract:   3,
+	config.PhaseAcceptance: 100,
+	config.PhaseClosing:    101,
+}
+
+// saveTemplatePhases 按请求顺序保存模板阶段和任务骨架
+func saveTemplatePhases(tx *gorm.DB, templateID uint, phases []TemplatePhaseRequest) error {
+	for i, p := range phases {
+		docTypes, _ := json.Marshal(nonNilStrings(p.RequiredDocTypes))
+		phase := models.ProjectTemplatePhase{
+			TemplateID:       templateID,
+			PhaseName:        p.PhaseName,
+			PhaseOrder:       i + 1,
+			RequiredDocTypes: string(docTypes),
+			RequireSignOff:   p.RequireSignOff,
+		}
+		if err := tx.Create(&phase).Error; err != nil {
+			return err
+		}
+
+		for j, t := range p.Tasks {
+			priority := t.Priority
+			if priority == 0 {
+				priority = 2
+			}
+			task := models.ProjectTemplateTask{
+				TemplatePhaseID: phase.ID,
+				TaskName:        strings.TrimSpace(t.TaskName),
+				Description:     t.Description,
+				TaskType:        t.TaskType,
+				Priority:        priority,
+				Deliverables:    t.Deliverables,
+				DeadlineOffset:  t.DeadlineOffset,
+				AssigneeRole:    t.AssigneeRole,
+				SortOrder:       j + 1,
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteTemplatePhases 删除模板的全部阶段和任务骨架
+func deleteTemplatePhases(tx *gorm.DB, templateID uint) error {
+	phaseIDs := tx.Model(&models.ProjectTemplatePhase{}).Select("id").Where("template_id = ?", templateID)
+	if err := tx.Where("template_phase_id IN (?)", phaseIDs).Delete(&models.ProjectTemplateTask{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("template_id = ?", templateID).Delete(&models.ProjectTemplatePhase{}).Error
+}
+
+// loadProjectTemplate 加载模板及其有序的阶段和任务骨架
+func loadProjectTemplate(db *gorm.DB, id interface{}) (*models.ProjectTemplate, error) {
+	var template models.ProjectTemplate
+	err := db.Preload("Phases", func(db *gorm.DB) *gorm.DB {
+		return db.Order("phase_order")
+	}).Preload("Phases.Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order")
+	}).First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// createProjectPhases 创建项目阶段：固定阶段始终创建，模板中的其他阶段按顺序作为自定义阶段，
+// 并按模板任务骨架生成任务（template 为空时只创建固定阶段）
+func createProjectPhases(tx *gorm.DB, project *models.Project, template *models.ProjectTemplate, createdBy uint) error {
+	// 5个固定阶段：立项、招标、合同签订 + 验收、结项，中间由用户自定义
+	phases := []models.ProjectPhase{
+		{ProjectID: project.ID, PhaseName: config.PhaseInitiation, PhaseOrder: 1, IsFixed: true, Status: config.StatusInProgress},
+		{ProjectID: project.ID, PhaseName: config.PhaseBidding, PhaseOrder: 2, IsFixed: true, Status: config.StatusNotStarted},
+		{ProjectID: project.ID, PhaseName: config.PhaseContract, PhaseOrder: 3, IsFixed: true, Status: config.StatusNotStarted},
+		{ProjectID: project.ID, PhaseName: config.PhaseAcceptance, PhaseOrder: 100, IsFixed: true, Status: config.StatusNotStarted},
+		{ProjectID: project.ID, PhaseName: config.PhaseClosing, PhaseOrder: 101, IsFixed: true, Status: config.StatusNotStarted},
+	}
+	if template == nil {
+		return tx.Create(&phases).Error
+	}
+
+	// 模板阶段 -> 项目阶段下标
+	phaseIndex := make(map[uint]int)
+	nextOrder := customPhaseOrderStart
+	for _, tp := range template.Phases {
+		idx := -1
+		for i := range phases {
+			if phases[i].IsFixed && phases[i].PhaseName == tp.PhaseName {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			phases = append(phases, models.ProjectPhase{
+				ProjectID:  project.ID,
+				PhaseName:  tp.PhaseName,
+				PhaseOrder: nextOrder,
+				Status:     config.StatusNotStarted,
+			})
+			idx = len(phases) - 1
+			nextOrder++
+		}
+		phases[idx].RequiredDocTypes = tp.RequiredDocTypes
+		phases[idx].RequireSignOff = tp.RequireSignOff
+		phaseIndex[tp.ID] = idx
+	}
+	if err := tx.Create(&phases).Error; err != nil {
+		return err
+	}
+
+	var tasks []models.Task
+	for _, tp := range template.Phases {
+		phase := phases[phaseIndex[tp.ID]]
+		for _, tt := range tp.Tasks {
+			task := models.Task{
+				ProjectID:    project.ID,
+				PhaseID:      phase.ID,
+				TaskName:     tt.TaskName,
+				Description:  tt.Description,
+				TaskType:     tt.TaskType,
+				AssigneeID:   templateAssignee(project, tt.AssigneeRole),
+				Priority:     tt.Priority,
+				Deliverables: tt.Deliverables,
+				Status:       config.TaskNotStarted,
+				CreatedBy:    createdBy,
+			}
+			if tt.DeadlineOffset > 0 && project.InitiationDate != nil {
+				deadline := project.InitiationDate.Add(time.Duration(tt.DeadlineOffset) * 24 * time.Hour)
+				task.Deadline = &deadline
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	return tx.Create(&tasks).Error
+}
+
+// templateAssignee 按模板中的默认责任人角色确定任务责任人
+func templateAssignee(project *models.Project, role string) uint {
+	switch role {
+	case AssigneeRoleManager:
+		return project.ManagerID
+	case AssigneeRoleSubManager:
+		if project.SubManagerID != nil {
+			return *project.SubManagerID
+		}
+	}
+	return 0
+}
+
+// nonNilStrings 保证序列化结果为 [] 而不是 null
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
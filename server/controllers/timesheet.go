@@ -0,0 +1,518 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TimesheetController struct{}
+
+// weekStartOf 所在周的周一
+func weekStartOf(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+// hourlyRateFor 用户的小时费率：按用户配置优先，其次按职能组，最后使用默认费率
+func hourlyRateFor(db *gorm.DB, userID uint) float64 {
+	var rate models.LaborRate
+	if db.Where("user_id = ?", userID).First(&rate).Error == nil {
+		return rate.HourlyRate
+	}
+	var user models.User
+	if db.Select("id, function_group").First(&user, userID).Error == nil && user.FunctionGroup != "" {
+		if db.Where("user_id IS NULL AND function_group = ?", user.FunctionGroup).First(&rate).Error == nil {
+			return rate.HourlyRate
+		}
+	}
+	return config.DefaultHourlyRate
+}
+
+// timesheetEditable 工时单是否允许修改（未提交或被驳回）
+func timesheetEditable(sheet *models.Timesheet) bool {
+	return sheet.Status == models.TimesheetDraft || sheet.Status == models.TimesheetRejected
+}
+
+// refreshTimesheetHours 重新汇总工时单的总工时
+func refreshTimesheetHours(db *gorm.DB, sheetID uint) {
+	var total float64
+	db.Model(&models.TimeEntry{}).Where("timesheet_id = ?", sheetID).Select("COALESCE(SUM(hours), 0)").Scan(&total)
+	db.Model(&models.Timesheet{}).Where("id = ?", sheetID).Update("total_hours", total)
+}
+
+// GetMyWeek 获取当前用户某一周的工时单（week_start 为空时取本周）
+func (tc *TimesheetController) GetMyWeek(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	weekStart := weekStartOf(time.Now())
+	if s := c.Query("week_start"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			utils.BadRequest(c, "日期格式错误")
+			return
+		}
+		weekStart = weekStartOf(t)
+	}
+
+	var sheets []models.Timesheet
+	config.GetDB().Preload("Project", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, manager_id")
+	}).Preload("Reviewer").Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("work_date, id")
+	}).Preload("Entries.Task", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, task_name")
+	}).Preload("Entries.Phase", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, phase_name")
+	}).Where("user_id = ? AND week_start = ?", userID, weekStart).Order("project_id").Find(&sheets)
+
+	utils.Success(c, gin.H{
+		"week_start": weekStart.Format("2006-01-02"),
+		"timesheets": sheets,
+	})
+}
+
+// TimeEntryRequest 工时记录请求
+type TimeEntryRequest struct {
+	ProjectID   uint    `json:"project_id" binding:"required"`
+	PhaseID     *uint   `json:"phase_id"`
+	TaskID      *uint   `json:"task_id"`
+	WorkDate    string  `json:"work_date" binding:"required"` // 格式 2006-01-02
+	Hours       float64 `json:"hours" binding:"required"`
+	Description string  `json:"description"`
+}
+
+// validateTimeEntry 校验工时记录，返回工作日期和错误信息；关联任务时阶段取任务所在阶段
+func validateTimeEntry(c *gin.Context, db *gorm.DB, req *TimeEntryRequest, userID uint, excludeID uint) (time.Time, string) {
+	workDate, err := time.ParseInLocation("2006-01-02", req.WorkDate, time.Local)
+	if err != nil {
+		return workDate, "日期格式错误"
+	}
+	if workDate.After(time.Now()) {
+		return workDate, "不能填报未来日期的工时"
+	}
+	if req.Hours <= 0 || req.Hours > config.MaxDailyHours {
+		return workDate, fmt.Sprintf("工时必须大于0且不超过%g小时", config.MaxDailyHours)
+	}
+	var project models.Project
+	if err := db.Select("id").First(&project, req.ProjectID).Error; err != nil {
+		return workDate, "项目不存在"
+	}
+	if !getProjectAccess(c).canView(project.ID) {
+		return workDate, "没有权限在该项目填报工时"
+	}
+
+	if req.TaskID != nil && *req.TaskID != 0 {
+		var task models.Task
+		if err := db.Select("id, project_id, phase_id").First(&task, *req.TaskID).Error; err != nil || task.ProjectID != req.ProjectID {
+			return workDate, "任务不属于该项目"
+		}
+		phaseID := task.PhaseID
+		req.PhaseID = &phaseID
+	} else {
+		req.TaskID = nil
+		if req.PhaseID != nil && *req.PhaseID != 0 {
+			var count int64
+			db.Model(&models.ProjectPhase{}).Where("id = ? AND project_id = ?", *req.PhaseID, req.ProjectID).Count(&count)
+			if count == 0 {
+				return workDate, "阶段不属于该项目"
+			}
+		} else {
+			req.PhaseID = nil
+		}
+	}
+
+	// 单日工时合计（所有项目）不超过上限
+	var dayTotal float64
+	db.Model(&models.TimeEntry{}).Where("user_id = ? AND work_date = ? AND id <> ?", userID, workDate, excludeID).
+		Select("COALESCE(SUM(hours), 0)").Scan(&dayTotal)
+	if dayTotal+req.Hours > config.MaxDailyHours {
+		return workDate, fmt.Sprintf("%s 已填报%g小时，单日合计不能超过%g小时", req.WorkDate, dayTotal, config.MaxDailyHours)
+	}
+	return workDate, ""
+}
+
+// CreateEntry 填报工时（自动归入当周该项目的工时单）
+func (tc *TimesheetController) CreateEntry(c *gin.Context) {
+	var req TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写项目、日期和工时")
+		return
+	}
+	userID, _ := c.Get("userID")
+	uid := userID.(uint)
+
+	db := config.GetDB()
+	workDate, msg := validateTimeEntry(c, db, &req, uid, 0)
+	if msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	sheet := models.Timesheet{UserID: uid, ProjectID: req.ProjectID, WeekStart: weekStartOf(workDate)}
+	if err := db.Where(&sheet).Attrs(models.Timesheet{Status: models.TimesheetDraft}).FirstOrCreate(&sheet).Error; err != nil {
+		utils.ServerError(c, "保存失败")
+		return
+	}
+	if !timesheetEditable(&sheet) {
+		utils.Error(c, 400, "该周工时已提交，不能再修改")
+		return
+	}
+
+	entry := models.TimeEntry{
+		TimesheetID: sheet.ID,
+		UserID:      uid,
+		ProjectID:   req.ProjectID,
+		PhaseID:     req.PhaseID,
+		TaskID:      req.TaskID,
+		WorkDate:    workDate,
+		Hours:       req.Hours,
+		Description: req.Description,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		utils.ServerError(c, "保存失败")
+		return
+	}
+	refreshTimesheetHours(db, sheet.ID)
+
+	utils.SuccessWithMessage(c, "保存成功", entry)
+}
+
+// loadOwnEntry 加载当前用户可修改的工时记录
+func loadOwnEntry(c *gin.Context, db *gorm.DB) (*models.TimeEntry, *models.Timesheet, bool) {
+	userID, _ := c.Get("userID")
+	var entry models.TimeEntry
+	if err := db.First(&entry, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "工时记录不存在")
+		return nil, nil, false
+	}
+	if entry.UserID != userID.(uint) {
+		utils.Forbidden(c, "只能修改自己的工时")
+		return nil, nil, false
+	}
+	var sheet models.Timesheet
+	db.First(&sheet, entry.TimesheetID)
+	if !timesheetEditable(&sheet) {
+		utils.Error(c, 400, "该周工时已提交，不能再修改")
+		return nil, nil, false
+	}
+	return &entry, &sheet, true
+}
+
+// UpdateEntry 修改工时记录（不能改到其他项目或其他周）
+func (tc *TimesheetController) UpdateEntry(c *gin.Context) {
+	var req TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写项目、日期和工时")
+		return
+	}
+
+	db := config.GetDB()
+	entry, sheet, ok := loadOwnEntry(c, db)
+	if !ok {
+		return
+	}
+	req.ProjectID = entry.ProjectID
+	workDate, msg := validateTimeEntry(c, db, &req, entry.UserID, entry.ID)
+	if msg == "" && !weekStartOf(workDate).Equal(weekStartOf(sheet.WeekStart)) {
+		msg = "只能修改为同一周内的日期"
+	}
+	if msg != "" {
+		utils.BadRequest(c, msg)
+		return
+	}
+
+	db.Model(entry).Updates(map[string]interface{}{
+		"phase_id":    req.PhaseID,
+		"task_id":     req.TaskID,
+		"work_date":   workDate,
+		"hours":       req.Hours,
+		"description": req.Description,
+	})
+	refreshTimesheetHours(db, sheet.ID)
+
+	utils.SuccessWithMessage(c, "保存成功", entry)
+}
+
+// DeleteEntry 删除工时记录
+func (tc *TimesheetController) DeleteEntry(c *gin.Context) {
+	db := config.GetDB()
+	entry, sheet, ok := loadOwnEntry(c, db)
+	if !ok {
+		return
+	}
+	db.Delete(entry)
+	refreshTimesheetHours(db, sheet.ID)
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Submit 提交周工时单，由项目负责人审批
+func (tc *TimesheetController) Submit(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	db := config.GetDB()
+
+	var sheet models.Timesheet
+	if err := db.Preload("Project").First(&sheet, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "工时单不存在")
+		return
+	}
+	if sheet.UserID != userID.(uint) {
+		utils.Forbidden(c, "只能提交自己的工时")
+		return
+	}
+	if !timesheetEditable(&sheet) {
+		utils.Error(c, 400, "该周工时已提交")
+		return
+	}
+	var count int64
+	db.Model(&models.TimeEntry{}).Where("timesheet_id = ?", sheet.ID).Count(&count)
+	if count == 0 {
+		utils.Error(c, 400, "该周没有填报工时")
+		return
+	}
+
+	now := time.Now()
+	db.Model(&sheet).Updates(map[string]interface{}{
+		"status":         models.TimesheetSubmitted,
+		"submitted_at":   now,
+		"reviewed_by":    nil,
+		"reviewed_at":    nil,
+		"review_comment": "",
+	})
+
+	var user models.User
+	db.Select("id, name").First(&user, sheet.UserID)
+	week := sheet.WeekStart.Format("2006-01-02")
+	notifyUsers(db, []uint{sheet.Project.ManagerID}, models.Notification{
+		Type:       "timesheet_submit",
+		Title:      "工时待审批",
+		Content:    fmt.Sprintf("%s 提交了项目「%s」%s 当周的工时（%g小时）", user.Name, sheet.Project.Name, week, sheet.TotalHours),
+		TargetType: "timesheet",
+		TargetID:   sheet.ID,
+		ProjectID:  sheet.ProjectID,
+		SenderID:   sheet.UserID,
+	})
+
+	// 记录日志
+	middleware.LogOperation(c, "submit_timesheet", "timesheet", "project", sheet.ProjectID, sheet.Project.Name,
+		fmt.Sprintf("提交工时: %s 当周 %g小时", week, sheet.TotalHours), "success")
+
+	utils.SuccessWithMessage(c, "提交成功", nil)
+}
+
+// ListReviews 获取待当前用户审批的工时单（项目负责人看本人负责的项目，管理员看全部）
+func (tc *TimesheetController) ListReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	userID, _ := c.Get("userID")
+	roleCode, _ := c.Get("roleCode")
+
+	db := config.GetDB()
+	query := db.Model(&models.Timesheet{}).Where("status = ?", c.DefaultQuery("status", models.TimesheetSubmitted))
+	if roleCode != config.RoleAdmin {
+		query = query.Where("project_id IN (?)", db.Model(&models.Project{}).Select("id").Where("manager_id = ?", userID))
+	}
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var total int64
+	var sheets []models.Timesheet
+	query.Count(&total)
+	query.Preload("User").Preload("Reviewer").Preload("Project", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, manager_id")
+	}).Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("work_date, id")
+	}).Preload("Entries.Task", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, task_name")
+	}).Preload("Entries.Phase", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, phase_name")
+	}).Order("submitted_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&sheets)
+
+	utils.SuccessPage(c, sheets, total, page, pageSize)
+}
+
+// ReviewTimesheetRequest 审批工时请求
+type ReviewTimesheetRequest struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment"`
+}
+
+// Review 审批工时单：通过时按当前费率计算人工成本并固定到工时记录上
+func (tc *TimesheetController) Review(c *gin.Context) {
+	var req ReviewTimesheetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	userID, _ := c.Get("userID")
+	uid := userID.(uint)
+
+	db := config.GetDB()
+	var sheet models.Timesheet
+	if err := db.Preload("Project").Preload("Entries").First(&sheet, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "工时单不存在")
+		return
+	}
+	if !canManageProjectTasks(c, sheet.Project) {
+		utils.Forbidden(c, "只有项目负责人才能审批工时")
+		return
+	}
+	if sheet.UserID == uid {
+		utils.Forbidden(c, "不能审批自己的工时")
+		return
+	}
+	if sheet.Status != models.TimesheetSubmitted {
+		utils.Error(c, 400, "该工时单不在待审批状态")
+		return
+	}
+	if !req.Approved && req.Comment == "" {
+		utils.BadRequest(c, "驳回时请填写原因")
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":         models.TimesheetRejected,
+		"reviewed_by":    uid,
+		"reviewed_at":    now,
+		"review_comment": req.Comment,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if req.Approved {
+			rate := hourlyRateFor(tx, sheet.UserID)
+			total := 0.0
+			for _, entry := range sheet.Entries {
+				cost := math.Round(entry.Hours*rate*100) / 100
+				total += cost
+				if err := tx.Model(&entry).Updates(map[string]interface{}{"hourly_rate": rate, "cost": cost}).Error; err != nil {
+					return err
+				}
+			}
+			updates["status"] = models.TimesheetApproved
+			updates["labor_cost"] = total
+		}
+		return tx.Model(&sheet).Updates(updates).Error
+	})
+	if err != nil {
+		utils.ServerError(c, "审批失败")
+		return
+	}
+
+	result, title := "通过", "工时审批通过"
+	if !req.Approved {
+		result, title = "驳回", "工时被驳回"
+	}
+	week := sheet.WeekStart.Format("2006-01-02")
+	notifyUsers(db, []uint{sheet.UserID}, models.Notification{
+		Type:       "timesheet_review",
+		Title:      title,
+		Content:    fmt.Sprintf("项目「%s」%s 当周的工时已%s。%s", sheet.Project.Name, week, result, req.Comment),
+		TargetType: "timesheet",
+		TargetID:   sheet.ID,
+		ProjectID:  sheet.ProjectID,
+		SenderID:   uid,
+	})
+
+	// 记录日志
+	middleware.LogOperation(c, "review_timesheet", "timesheet", "project", sheet.ProjectID, sheet.Project.Name,
+		fmt.Sprintf("审批工时（%s）: 用户%d %s 当周 %g小时", result, sheet.UserID, week, sheet.TotalHours), "success")
+
+	utils.SuccessWithMessage(c, "审批成功", nil)
+}
+
+// GetLaborRates 获取人工费率配置
+func (tc *TimesheetController) GetLaborRates(c *gin.Context) {
+	var rates []models.LaborRate
+	config.GetDB().Preload("User").Order("user_id IS NOT NULL, function_group, user_id").Find(&rates)
+	utils.Success(c, gin.H{
+		"rates":        rates,
+		"default_rate": config.DefaultHourlyRate,
+	})
+}
+
+// LaborRateRequest 人工费率请求（user_id 和 function_group 二选一）
+type LaborRateRequest struct {
+	UserID        *uint   `json:"user_id"`
+	FunctionGroup string  `json:"function_group"`
+	HourlyRate    float64 `json:"hourly_rate"`
+	Remark        string  `json:"remark"`
+}
+
+// SaveLaborRate 保存人工费率（同一用户或职能组已有配置时覆盖），只影响之后审批的工时
+func (tc *TimesheetController) SaveLaborRate(c *gin.Context) {
+	var req LaborRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if req.HourlyRate < 0 {
+		utils.BadRequest(c, "费率不能为负数")
+		return
+	}
+	if req.UserID != nil && *req.UserID == 0 {
+		req.UserID = nil
+	}
+	if (req.UserID == nil) == (req.FunctionGroup == "") {
+		utils.BadRequest(c, "请选择用户或职能组（二选一）")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	db := config.GetDB()
+	var rate models.LaborRate
+	target := "职能组 " + req.FunctionGroup
+	if req.UserID != nil {
+		var user models.User
+		if err := db.First(&user, *req.UserID).Error; err != nil {
+			utils.NotFound(c, "用户不存在")
+			return
+		}
+		target = "用户 " + user.Name
+		db.Where("user_id = ?", *req.UserID).First(&rate)
+		req.FunctionGroup = ""
+	} else {
+		db.Where("user_id IS NULL AND function_group = ?", req.FunctionGroup).First(&rate)
+	}
+
+	rate.UserID = req.UserID
+	rate.FunctionGroup = req.FunctionGroup
+	rate.HourlyRate = req.HourlyRate
+	rate.Remark = req.Remark
+	rate.UpdatedBy = userID.(uint)
+	if err := db.Save(&rate).Error; err != nil {
+		utils.ServerError(c, "保存失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "save_labor_rate", "expense", "labor_rate", rate.ID, target,
+		fmt.Sprintf("设置人工费率: %s %.2f元/小时", target, rate.HourlyRate), "success")
+
+	utils.SuccessWithMessage(c, "保存成功", rate)
+}
+
+// DeleteLaborRate 删除人工费率配置
+func (tc *TimesheetController) DeleteLaborRate(c *gin.Context) {
+	db := config.GetDB()
+	var rate models.LaborRate
+	if err := db.First(&rate, c.Param("id")).Error; err != nil {
+		utils.NotFound(c, "费率配置不存在")
+		return
+	}
+	db.Delete(&rate)
+
+	// 记录日志
+	middleware.LogOperation(c, "delete_labor_rate", "expense", "labor_rate", rate.ID, rate.FunctionGroup,
+		fmt.Sprintf("删除人工费率配置: %.2f元/小时", rate.HourlyRate), "success")
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
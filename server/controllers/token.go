@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenController struct{}
+
+// CreateAccessTokenRequest 创建个人访问Token请求
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 有效期（天），为空时使用默认值
+}
+
+// GetScopes 获取Token支持的授权范围
+func (tc *AccessTokenController) GetScopes(c *gin.Context) {
+	utils.Success(c, config.AllTokenScopes)
+}
+
+// List 获取当前用户的个人访问Token
+func (tc *AccessTokenController) List(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var tokens []models.PersonalAccessToken
+	config.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens)
+
+	utils.Success(c, tokens)
+}
+
+// Create 创建个人访问Token（明文只在创建时返回一次）
+func (tc *AccessTokenController) Create(c *gin.Context) {
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Scopes) == 0 {
+		utils.BadRequest(c, "请填写Token名称并选择授权范围")
+		return
+	}
+
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		if !config.IsValidTokenScope(s) {
+			utils.BadRequest(c, "包含不支持的授权范围: "+s)
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = config.AccessTokenDefaultExpire
+	}
+	if req.ExpiresInDays < 1 || req.ExpiresInDays > config.AccessTokenMaxExpire {
+		utils.BadRequest(c, "有效期需在1~"+strconv.Itoa(config.AccessTokenMaxExpire)+"天之间")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	db := config.GetDB()
+
+	var activeCount int64
+	db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Count(&activeCount)
+	if activeCount >= config.AccessTokenMaxPerUser {
+		utils.Error(c, 400, "有效Token数量已达上限，请先吊销不再使用的Token")
+		return
+	}
+
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		utils.ServerError(c, "生成Token失败")
+		return
+	}
+	plain := config.AccessTokenPrefix + secret
+	scopesJSON, _ := json.Marshal(scopes)
+
+	token := models.PersonalAccessToken{
+		UserID:      userID.(uint),
+		Name:        req.Name,
+		TokenHash:   utils.HashToken(plain),
+		TokenPrefix: plain[:len(config.AccessTokenPrefix)+8],
+		Scopes:      string(scopesJSON),
+		ExpiresAt:   time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		utils.ServerError(c, "创建Token失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "create_token", "auth", "access_token", token.ID, token.Name, "创建个人访问Token: "+token.Name, "success")
+
+	utils.SuccessWithMessage(c, "创建成功，请立即复制保存，Token只显示一次", gin.H{
+		"token": plain,
+		"info":  token,
+	})
+}
+
+// Revoke 吊销个人访问Token
+func (tc *AccessTokenController) Revoke(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := c.Get("userID")
+
+	db := config.GetDB()
+	var token models.PersonalAccessToken
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&token).Error; err != nil {
+		utils.NotFound(c, "Token不存在")
+		return
+	}
+
+	if token.RevokedAt == nil {
+		db.Model(&token).Update("revoked_at", time.Now())
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "revoke_token", "auth", "access_token", token.ID, token.Name, "吊销个人访问Token: "+token.Name, "success")
+
+	utils.SuccessWithMessage(c, "已吊销", nil)
+}
@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"project-flow/config"
+	"project-flow/middleware"
+	"project-flow/models"
+	"project-flow/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct{}
+
+// TwoFactorLoginRequest 登录第二步验证请求（动态码和恢复码二选一）
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorCodeRequest 动态码请求
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// VerifyLogin 登录第二步：校验动态码或恢复码后创建会话
+func (tc *TwoFactorController) VerifyLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		utils.BadRequest(c, "请输入动态验证码或恢复码")
+		return
+	}
+
+	user, ok := loadChallengeUser(c, req.ChallengeToken, utils.ChallengeTwoFactor)
+	if !ok {
+		return
+	}
+
+	if !verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		registerLoginFailure(c, user)
+		utils.Error(c, 401, "验证码错误")
+		return
+	}
+
+	completeLogin(c, user, user.Name+" 登录系统（双因素认证）")
+}
+
+// SetupLogin 登录时强制绑定：为尚未启用双因素认证的用户生成密钥
+func (tc *TwoFactorController) SetupLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	user, ok := loadChallengeUser(c, req.ChallengeToken, utils.ChallengeTwoFactorSetup)
+	if !ok {
+		return
+	}
+
+	enrollment, err := startEnrollment(user)
+	if err != nil {
+		utils.ServerError(c, "生成密钥失败")
+		return
+	}
+
+	utils.Success(c, enrollment)
+}
+
+// ActivateLogin 登录时强制绑定：校验首个动态码，启用双因素认证并创建会话
+func (tc *TwoFactorController) ActivateLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		utils.BadRequest(c, "请输入动态验证码")
+		return
+	}
+
+	user, ok := loadChallengeUser(c, req.ChallengeToken, utils.ChallengeTwoFactorSetup)
+	if !ok {
+		return
+	}
+
+	codes, ok := activateTwoFactor(c, user, req.Code)
+	if !ok {
+		return
+	}
+
+	config.GetDB().Model(user).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	})
+
+	resp, err := issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成Token失败")
+		return
+	}
+	recordLogin(c, user.ID, user.Name+" 绑定双因素认证并登录系统")
+
+	utils.Success(c, gin.H{
+		"token":          resp.Token,
+		"refresh_token":  resp.RefreshToken,
+		"expires_in":     resp.ExpiresIn,
+		"user":           resp.User,
+		"recovery_codes": codes,
+	})
+}
+
+// Status 获取当前用户的双因素认证状态
+func (tc *TwoFactorController) Status(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := config.GetDB()
+	var user models.User
+	if err := db.Preload("Role").First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	var remaining int64
+	db.Model(&models.UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+
+	utils.Success(c, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"required":                 user.Role != nil && user.Role.RequireTwoFactor,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// Setup 生成新的TOTP密钥（需调用 Enable 校验动态码后才生效）
+func (tc *TwoFactorController) Setup(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := config.GetDB().First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	if user.TOTPEnabled {
+		utils.Error(c, 400, "已启用双因素认证，如需更换请先停用")
+		return
+	}
+
+	enrollment, err := startEnrollment(&user)
+	if err != nil {
+		utils.ServerError(c, "生成密钥失败")
+		return
+	}
+
+	utils.Success(c, enrollment)
+}
+
+// Enable 校验动态码并启用双因素认证，返回恢复码（仅展示一次）
+func (tc *TwoFactorController) Enable(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		utils.BadRequest(c, "请输入动态验证码")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := config.GetDB().First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	if user.TOTPEnabled {
+		utils.Error(c, 400, "已启用双因素认证")
+		return
+	}
+
+	codes, ok := activateTwoFactor(c, &user, req.Code)
+	if !ok {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "双因素认证已启用，请妥善保存恢复码", gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// Disable 停用双因素认证（角色要求启用时不可停用）
+func (tc *TwoFactorController) Disable(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		utils.BadRequest(c, "请输入动态验证码或恢复码")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := config.GetDB().Preload("Role").First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	if !user.TOTPEnabled {
+		utils.Error(c, 400, "未启用双因素认证")
+		return
+	}
+	if user.Role != nil && user.Role.RequireTwoFactor {
+		utils.Error(c, 400, "当前角色要求必须启用双因素认证")
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+		utils.Error(c, 400, "验证码错误")
+		return
+	}
+
+	resetTwoFactor(user.ID)
+
+	// 记录日志
+	middleware.LogOperation(c, "disable_2fa", "auth", "user", user.ID, user.Name, "停用双因素认证", "success")
+
+	utils.SuccessWithMessage(c, "双因素认证已停用", nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（原恢复码全部作废）
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		utils.BadRequest(c, "请输入动态验证码")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := config.GetDB().First(&user, userID).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	if !user.TOTPEnabled {
+		utils.Error(c, 400, "未启用双因素认证")
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, "") {
+		utils.Error(c, 400, "验证码错误")
+		return
+	}
+
+	codes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		utils.ServerError(c, "生成恢复码失败")
+		return
+	}
+
+	// 记录日志
+	middleware.LogOperation(c, "regenerate_recovery_codes", "auth", "user", user.ID, user.Name, "重新生成双因素认证恢复码", "success")
+
+	utils.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// twoFactorChallenge 判断登录是否需要第二步验证，需要时返回挑战信息，否则返回nil
+func twoFactorChallenge(user *models.User) (gin.H, error) {
+	purpose := ""
+	if user.TOTPEnabled {
+		purpose = utils.ChallengeTwoFactor
+	} else if user.Role != nil && user.Role.RequireTwoFactor {
+		purpose = utils.ChallengeTwoFactorSetup
+	} else {
+		return nil, nil
+	}
+
+	token, err := utils.GenerateChallengeToken(user.ID, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"two_factor_required":       purpose == utils.ChallengeTwoFactor,
+		"two_factor_setup_required": purpose == utils.ChallengeTwoFactorSetup,
+		"challenge_token":           token,
+		"expires_in":                int64(config.ChallengeExpireTime.Seconds()),
+	}, nil
+}
+
+// loadChallengeUser 校验挑战Token并加载用户，失败时直接写入响应
+func loadChallengeUser(c *gin.Context, token, purpose string) (*models.User, bool) {
+	claims, err := utils.ParseChallengeToken(token, purpose)
+	if err != nil {
+		utils.Unauthorized(c, "验证已过期，请重新登录")
+		return nil, false
+	}
+
+	var user models.User
+	if err := config.GetDB().Preload("Role").First(&user, claims.UserID).Error; err != nil {
+		utils.Unauthorized(c, "验证已过期，请重新登录")
+		return nil, false
+	}
+
+	// 已启用双因素认证的用户不能通过绑定流程覆盖原有密钥
+	if purpose == utils.ChallengeTwoFactorSetup && user.TOTPEnabled {
+		utils.Unauthorized(c, "验证已过期，请重新登录")
+		return nil, false
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		utils.Error(c, 403, "账号已被锁定，请稍后再试")
+		return nil, false
+	}
+	if user.Status != 1 {
+		utils.Error(c, 403, "账号已被禁用")
+		return nil, false
+	}
+	return &user, true
+}
+
+// verifySecondFactor 校验动态码或恢复码（恢复码使用后立即作废）
+func verifySecondFactor(user *models.User, code, recoveryCode string) bool {
+	db := config.GetDB()
+
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep)
+		if !ok {
+			return false
+		}
+		// 条件更新保证并发请求中同一时间步只能成功一次
+		result := db.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+
+	if recoveryCode != "" {
+		result := db.Model(&models.UserRecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		return result.RowsAffected > 0
+	}
+
+	return false
+}
+
+// startEnrollment 生成新密钥并保存为待验证状态
+func startEnrollment(user *models.User) (*utils.TOTPEnrollment, error) {
+	enrollment, err := utils.GenerateTOTP(user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.GetDB().Model(user).Updates(map[string]interface{}{
+		"totp_secret":    enrollment.Secret,
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// activateTwoFactor 校验待验证密钥的首个动态码并启用双因素认证，返回新的恢复码
+func activateTwoFactor(c *gin.Context, user *models.User, code string) ([]string, bool) {
+	if user.TOTPSecret == "" {
+		utils.Error(c, 400, "请先生成双因素认证密钥")
+		return nil, false
+	}
+	if !verifySecondFactor(user, code, "") {
+		utils.Error(c, 400, "验证码错误，请确认验证器App的时间是否准确")
+		return nil, false
+	}
+
+	if err := config.GetDB().Model(user).Update("totp_enabled", true).Error; err != nil {
+		utils.ServerError(c, "启用失败")
+		return nil, false
+	}
+	user.TOTPEnabled = true
+
+	codes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		utils.ServerError(c, "生成恢复码失败")
+		return nil, false
+	}
+
+	// 记录日志
+	middleware.LogOperationByUser(c, user.ID, "enable_2fa", "auth", "user", user.ID, user.Name, "启用双因素认证", "success")
+
+	return codes, true
+}
+
+// issueRecoveryCodes 生成新的恢复码并作废原有恢复码
+func issueRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(config.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.GetDB()
+	db.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{})
+	for _, code := range codes {
+		record := models.UserRecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)}
+		if err := db.Create(&record).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// resetTwoFactor 清除用户的双因素认证配置和恢复码
+func resetTwoFactor(userID uint) {
+	db := config.GetDB()
+	db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	})
+	db.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{})
+}
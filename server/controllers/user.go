@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"fmt"
 	"project-flow/config"
 	"project-flow/middleware"
 	"project-flow/models"
 	"project-flow/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-sql-driver/mysql"
@@ -15,25 +17,27 @@ type UserController struct{}
 
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
-	Username      string `json:"username" binding:"required"`
-	Password      string `json:"password" binding:"required"`
-	Name          string `json:"name" binding:"required"`
-	Email         string `json:"email"`
-	Phone         string `json:"phone"`
-	Department    string `json:"department"`
-	FunctionGroup string `json:"function_group"`
-	RoleID        uint   `json:"role_id" binding:"required"`
+	Username       string  `json:"username" binding:"required"`
+	Password       string  `json:"password" binding:"required"`
+	Name           string  `json:"name" binding:"required"`
+	Email          string  `json:"email"`
+	Phone          string  `json:"phone"`
+	Department     string  `json:"department"`
+	FunctionGroup  string  `json:"function_group"`
+	RoleID         uint    `json:"role_id" binding:"required"`
+	WeeklyCapacity float64 `json:"weekly_capacity"` // 每周可用工时（小时），为0时使用默认值
 }
 
 // UpdateUserRequest 更新用户请求
 type UpdateUserRequest struct {
-	Name          string `json:"name"`
-	Email         string `json:"email"`
-	Phone         string `json:"phone"`
-	Department    string `json:"department"`
-	FunctionGroup string `json:"function_group"`
-	RoleID        uint   `json:"role_id"`
-	Status        *int   `json:"status"`
+	Name           string   `json:"name"`
+	Email          string   `json:"email"`
+	Phone          string   `json:"phone"`
+	Department     string   `json:"department"`
+	FunctionGroup  string   `json:"function_group"`
+	RoleID         uint     `json:"role_id"`
+	Status         *int     `json:"status"`
+	WeeklyCapacity *float64 `json:"weekly_capacity"` // 每周可用工时（小时），为0时使用默认值
 }
 
 // List 获取用户列表
@@ -74,9 +78,14 @@ func (uc *UserController) Create(c *gin.Context) {
 		return
 	}
 
-	// 验证密码复杂度
-	if !utils.ValidatePassword(req.Password) {
-		utils.BadRequest(c, "密码至少8位，需包含大小写字母、数字和特殊符号")
+	// 按密码策略校验初始密码
+	if err := utils.ValidatePassword(req.Password, req.Username); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if req.WeeklyCapacity < 0 || req.WeeklyCapacity > 168 {
+		utils.BadRequest(c, "每周可用工时无效")
 		return
 	}
 
@@ -97,15 +106,18 @@ func (uc *UserController) Create(c *gin.Context) {
 	}
 
 	user := models.User{
-		Username:      req.Username,
-		Password:      hashedPassword,
-		Name:          req.Name,
-		Email:         req.Email,
-		Phone:         req.Phone,
-		Department:    req.Department,
-		FunctionGroup: req.FunctionGroup,
-		RoleID:        req.RoleID,
-		Status:        1,
+		Username:       req.Username,
+		Password:       hashedPassword,
+		Name:           req.Name,
+		Email:          req.Email,
+		Phone:          req.Phone,
+		Department:     req.Department,
+		FunctionGroup:  req.FunctionGroup,
+		RoleID:         req.RoleID,
+		Status:         1,
+		WeeklyCapacity: req.WeeklyCapacity,
+		// 管理员设置的初始密码，首次登录后须由本人修改
+		MustChangePassword: true,
 	}
 
 	if err := db.Create(&user).Error; err != nil {
@@ -154,6 +166,7 @@ func (uc *UserController) Update(c *gin.Context) {
 		return
 	}
 
+	oldRoleID := user.RoleID
 	updates := make(map[string]interface{})
 	if req.Name != "" {
 		updates["name"] = req.Name
@@ -176,12 +189,24 @@ func (uc *UserController) Update(c *gin.Context) {
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
+	if req.WeeklyCapacity != nil {
+		if *req.WeeklyCapacity < 0 || *req.WeeklyCapacity > 168 {
+			utils.BadRequest(c, "每周可用工时无效")
+			return
+		}
+		updates["weekly_capacity"] = *req.WeeklyCapacity
+	}
 
 	if err := db.Model(&user).Updates(updates).Error; err != nil {
 		utils.ServerError(c, "更新失败")
 		return
 	}
 
+	// 禁用账号或变更角色时立即吊销其所有会话
+	if (req.Status != nil && *req.Status == 0) || (req.RoleID != 0 && req.RoleID != oldRoleID) {
+		revokeUserSessions(user.ID)
+	}
+
 	// 记录日志
 	middleware.LogOperation(c, "update", "user", "user", user.ID, user.Name, "更新用户: "+user.Name, "success")
 
@@ -203,6 +228,7 @@ func (uc *UserController) Delete(c *gin.Context) {
 		utils.ServerError(c, "删除失败")
 		return
 	}
+	revokeUserSessions(user.ID)
 
 	// 记录日志
 	middleware.LogOperation(c, "delete", "user", "user", user.ID, user.Name, "删除用户: "+user.Name, "success")
@@ -210,6 +236,11 @@ func (uc *UserController) Delete(c *gin.Context) {
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	ResetTwoFactor bool `json:"reset_two_factor"` // 是否同时重置双因素认证
+}
+
 // ResetPassword 重置密码
 func (uc *UserController) ResetPassword(c *gin.Context) {
 	id := c.Param("id")
@@ -221,17 +252,157 @@ func (uc *UserController) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// 重置为默认密码
-	hashedPassword, _ := utils.HashPassword("Reset@123")
+	// 可选：同时重置双因素认证（用户丢失验证器设备时使用）
+	var req ResetPasswordRequest
+	c.ShouldBindJSON(&req)
+
+	// 目录账号的密码由企业目录维护，只允许重置双因素认证
+	if user.AuthSource == config.AuthSourceLDAP {
+		if !req.ResetTwoFactor {
+			utils.Error(c, 400, "目录账号请在企业目录中重置密码")
+			return
+		}
+		resetTwoFactor(user.ID)
+		revokeUserSessions(user.ID)
+		middleware.LogOperation(c, "reset_2fa", "user", "user", user.ID, user.Name, "重置双因素认证: "+user.Name, "success")
+		utils.SuccessWithMessage(c, "双因素认证已重置", nil)
+		return
+	}
+
+	// 重置为随机临时密码（仅在本次响应中返回），登录后须先修改
+	password, err := utils.GenerateTemporaryPassword(user.Username)
+	if err != nil {
+		utils.ServerError(c, "生成临时密码失败")
+		return
+	}
+	hashedPassword, _ := utils.HashPassword(password)
 	user.Password = hashedPassword
+	user.MustChangePassword = true
 	user.FailedLogins = 0
 	user.LockedUntil = nil
 	db.Save(&user)
 
+	message := "密码已重置，请将临时密码告知用户"
+	description := "重置密码: " + user.Name
+	if req.ResetTwoFactor {
+		resetTwoFactor(user.ID)
+		message += "，双因素认证已重置"
+		description += "（含双因素认证）"
+	}
+
+	// 重置密码后吊销所有会话
+	revokeUserSessions(user.ID)
+
+	// 记录日志
+	middleware.LogOperation(c, "reset_password", "user", "user", user.ID, user.Name, description, "success")
+
+	utils.SuccessWithMessage(c, message, gin.H{"password": password})
+}
+
+// Sessions 获取用户的有效会话（登录IP、客户端、签发时间）
+func (uc *UserController) Sessions(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	var sessions []models.UserSession
+	db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("created_at DESC").Find(&sessions)
+
+	utils.Success(c, sessions)
+}
+
+// RevokeSession 强制下线用户的指定会话
+func (uc *UserController) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	sessionID := c.Param("sessionId")
+
+	db := config.GetDB()
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	var session models.UserSession
+	if err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, user.ID).First(&session).Error; err != nil {
+		utils.NotFound(c, "会话不存在或已失效")
+		return
+	}
+	db.Model(&session).Update("revoked_at", time.Now())
+
+	// 记录日志
+	middleware.LogOperation(c, "revoke_session", "user", "user", user.ID, user.Name,
+		fmt.Sprintf("强制下线: %s（会话%d，IP %s）", user.Name, session.ID, session.IPAddress), "success")
+
+	utils.SuccessWithMessage(c, "已强制下线", nil)
+}
+
+// RevokeSessions 强制下线用户的全部会话
+func (uc *UserController) RevokeSessions(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	revokeUserSessions(user.ID)
+
+	// 记录日志
+	middleware.LogOperation(c, "revoke_session", "user", "user", user.ID, user.Name, "强制下线全部会话: "+user.Name, "success")
+
+	utils.SuccessWithMessage(c, "已强制下线", nil)
+}
+
+// LockedUser 被锁定的账号
+type LockedUser struct {
+	ID           uint      `json:"id"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	Department   string    `json:"department"`
+	FailedLogins int       `json:"failed_logins"`
+	LockedUntil  time.Time `json:"locked_until"`
+}
+
+// LockedUsers 获取当前被锁定的账号
+func (uc *UserController) LockedUsers(c *gin.Context) {
+	var users []LockedUser
+	config.GetDB().Model(&models.User{}).
+		Select("id, username, name, department, failed_logins, locked_until").
+		Where("locked_until > ?", time.Now()).
+		Order("locked_until DESC").Scan(&users)
+
+	utils.Success(c, users)
+}
+
+// Unlock 解除账号锁定并清零失败次数
+func (uc *UserController) Unlock(c *gin.Context) {
+	id := c.Param("id")
+
+	db := config.GetDB()
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	db.Model(&user).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	})
+
 	// 记录日志
-	middleware.LogOperation(c, "reset_password", "user", "user", user.ID, user.Name, "重置密码: "+user.Name, "success")
+	middleware.LogOperation(c, "unlock", "user", "user", user.ID, user.Name, "解除账号锁定: "+user.Name, "success")
 
-	utils.SuccessWithMessage(c, "密码已重置为: Reset@123", nil)
+	utils.SuccessWithMessage(c, "已解除锁定", nil)
 }
 
 // GetRoles 获取角色列表
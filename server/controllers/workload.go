@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"math"
+	"project-flow/config"
+	"project-flow/models"
+	"project-flow/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxWorkloadWeeks 工作负荷最多统计的周数
+const maxWorkloadWeeks = 26
+
+// WorkloadTask 某周分摊到的任务
+type WorkloadTask struct {
+	TaskID      uint       `json:"task_id"`
+	TaskName    string     `json:"task_name"`
+	ProjectID   uint       `json:"project_id"`
+	ProjectName string     `json:"project_name"`
+	Deadline    *time.Time `json:"deadline"`
+	Hours       float64    `json:"hours"`
+	Overdue     bool       `json:"overdue"`
+	Hidden      bool       `json:"hidden"` // 当前用户无权查看该项目，只计入工时不显示明细
+}
+
+// WorkloadWeek 用户某一周的负荷
+type WorkloadWeek struct {
+	WeekStart  string         `json:"week_start"`
+	Hours      float64        `json:"hours"`
+	Load       int            `json:"load"` // 负荷百分比
+	Overloaded bool           `json:"overloaded"`
+	Tasks      []WorkloadTask `json:"tasks"`
+}
+
+// UserWorkload 用户工作负荷
+type UserWorkload struct {
+	UserID           uint           `json:"user_id"`
+	Name             string         `json:"name"`
+	Department       string         `json:"department"`
+	FunctionGroup    string         `json:"function_group"`
+	Capacity         float64        `json:"capacity"` // 每周可用工时
+	OpenTasks        int            `json:"open_tasks"`
+	OverdueTasks     int            `json:"overdue_tasks"`
+	UnestimatedTasks int            `json:"unestimated_tasks"` // 未填预估工时且无工期的任务
+	UnscheduledHours float64        `json:"unscheduled_hours"` // 无截止日期任务的工时
+	LaterHours       float64        `json:"later_hours"`       // 统计范围之后的工时
+	Overloaded       bool           `json:"overloaded"`
+	Weeks            []WorkloadWeek `json:"weeks"`
+}
+
+// taskEffort 任务的预估工时：优先使用预估工时，其次按工期估算
+func taskEffort(task *models.Task) float64 {
+	if task.EstimatedHours > 0 {
+		return task.EstimatedHours
+	}
+	return float64(task.Duration) * config.WorkHoursPerDay
+}
+
+// spreadEffort 把任务工时平均分摊到计划开始至截止日期之间的工作日（周一至周五），
+// 早于 from 的部分顺延到 from 之后；未估算的任务工时为0，同样会列在所跨的周内；返回 日期 -> 工时
+func spreadEffort(task *models.Task, effort float64, from time.Time) map[time.Time]float64 {
+	end := truncateDate(*task.Deadline)
+	start := end
+	if task.PlannedStart != nil {
+		start = truncateDate(*task.PlannedStart)
+	} else if task.Duration > 0 {
+		start = end.AddDate(0, 0, -(task.Duration - 1))
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.Before(start) {
+		// 已逾期：剩余工时全部计入当前
+		return map[time.Time]float64{from: effort}
+	}
+
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days = append(days, d)
+		}
+	}
+	if len(days) == 0 {
+		return map[time.Time]float64{end: effort}
+	}
+	result := make(map[time.Time]float64, len(days))
+	for _, d := range days {
+		result[d] = effort / float64(len(days))
+	}
+	return result
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// GetWorkload 团队工作负荷：按周汇总每个人在所有项目中未完成任务的预估工时，并标记超出容量的周
+// 参数：weeks（统计周数，默认8）、department、function_group
+func (tc *TaskController) GetWorkload(c *gin.Context) {
+	weeks, _ := strconv.Atoi(c.DefaultQuery("weeks", "8"))
+	if weeks < 1 || weeks > maxWorkloadWeeks {
+		utils.BadRequest(c, "统计周数必须在1到"+strconv.Itoa(maxWorkloadWeeks)+"之间")
+		return
+	}
+
+	db := config.GetDB()
+	query := db.Model(&models.User{}).Where("status = ?", 1).
+		Where("role_id NOT IN (?)", db.Model(&models.Role{}).Select("id").Where("code = ?", config.RoleAdmin))
+	if department := c.Query("department"); department != "" {
+		query = query.Where("department = ?", department)
+	}
+	if group := c.Query("function_group"); group != "" {
+		query = query.Where("function_group = ?", group)
+	}
+	var users []models.User
+	query.Order("department, function_group, id").Find(&users)
+
+	firstWeek := weekStartOf(time.Now())
+	today := truncateDate(time.Now())
+	windowEnd := firstWeek.AddDate(0, 0, 7*weeks)
+
+	result := make([]UserWorkload, 0, len(users))
+	index := make(map[uint]int, len(users))
+	userIDs := make([]uint, 0, len(users))
+	for _, u := range users {
+		capacity := u.WeeklyCapacity
+		if capacity <= 0 {
+			capacity = config.DefaultWeeklyCapacity
+		}
+		w := UserWorkload{
+			UserID:        u.ID,
+			Name:          u.Name,
+			Department:    u.Department,
+			FunctionGroup: u.FunctionGroup,
+			Capacity:      capacity,
+			Weeks:         make([]WorkloadWeek, weeks),
+		}
+		for i := range w.Weeks {
+			w.Weeks[i] = WorkloadWeek{WeekStart: firstWeek.AddDate(0, 0, 7*i).Format("2006-01-02"), Tasks: []WorkloadTask{}}
+		}
+		index[u.ID] = len(result)
+		result = append(result, w)
+		userIDs = append(userIDs, u.ID)
+	}
+
+	// 未完成的叶子任务（有子任务的父任务由子任务体现工作量，不重复统计）
+	var tasks []models.Task
+	if len(userIDs) > 0 {
+		db.Preload("Project", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, status")
+		}).Where("assignee_id IN ? AND status <> ?", userIDs, config.TaskCompleted).
+			Where("id NOT IN (?)", db.Model(&models.Task{}).Select("parent_id").Where("parent_id IS NOT NULL")).
+			Order("deadline").Find(&tasks)
+	}
+
+	access := getProjectAccess(c)
+	visible := make(map[uint]bool)
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Project == nil || task.Project.Status == config.StatusCompleted {
+			continue
+		}
+		w := &result[index[task.AssigneeID]]
+		w.OpenTasks++
+
+		if _, ok := visible[task.ProjectID]; !ok {
+			visible[task.ProjectID] = access.canView(task.ProjectID)
+		}
+		item := WorkloadTask{
+			TaskID:      task.ID,
+			TaskName:    task.TaskName,
+			ProjectID:   task.ProjectID,
+			ProjectName: task.Project.Name,
+			Deadline:    task.Deadline,
+			Overdue:     task.Deadline != nil && task.Deadline.Before(time.Now()),
+		}
+		if !visible[task.ProjectID] {
+			item = WorkloadTask{Deadline: task.Deadline, Overdue: item.Overdue, Hidden: true}
+		}
+		if item.Overdue {
+			w.OverdueTasks++
+		}
+
+		effort := taskEffort(task)
+		if effort == 0 {
+			w.UnestimatedTasks++
+		}
+		if task.Deadline == nil {
+			w.UnscheduledHours += effort
+			continue
+		}
+
+		// 按周汇总分摊的工时
+		perWeek := make(map[int]float64)
+		for day, hours := range spreadEffort(task, effort, today) {
+			if !day.Before(windowEnd) {
+				w.LaterHours += hours
+				continue
+			}
+			perWeek[int(math.Round(day.Sub(firstWeek).Hours()/24))/7] += hours
+		}
+		for i, hours := range perWeek {
+			week := &w.Weeks[i]
+			week.Hours += hours
+			entry := item
+			entry.Hours = math.Round(hours*10) / 10
+			week.Tasks = append(week.Tasks, entry)
+		}
+	}
+
+	for i := range result {
+		w := &result[i]
+		w.UnscheduledHours = math.Round(w.UnscheduledHours*10) / 10
+		w.LaterHours = math.Round(w.LaterHours*10) / 10
+		for j := range w.Weeks {
+			week := &w.Weeks[j]
+			week.Hours = math.Round(week.Hours*10) / 10
+			if w.Capacity > 0 {
+				week.Load = int(math.Round(week.Hours / w.Capacity * 100))
+			}
+			week.Overloaded = week.Hours > w.Capacity
+			w.Overloaded = w.Overloaded || week.Overloaded
+		}
+	}
+
+	weekStarts := make([]string, weeks)
+	for i := range weekStarts {
+		weekStarts[i] = firstWeek.AddDate(0, 0, 7*i).Format("2006-01-02")
+	}
+	utils.Success(c, gin.H{
+		"weeks":            weekStarts,
+		"default_capacity": config.DefaultWeeklyCapacity,
+		"users":            result,
+	})
+}
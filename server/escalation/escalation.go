@@ -0,0 +1,180 @@
+package escalation
+
+import (
+	"fmt"
+	"log"
+	"project-flow/config"
+	"project-flow/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Start 启动后台逾期检查：启动时立即检查一次，之后按配置的间隔定期检查
+func Start() {
+	go func() {
+		for {
+			if n := Run(config.GetDB(), time.Now()); n > 0 {
+				log.Printf("逾期提醒：发送了%d条提醒", n)
+			}
+			time.Sleep(config.EscalationCheckInterval)
+		}
+	}()
+}
+
+// DefaultPolicy 系统默认的逾期提醒策略
+func DefaultPolicy(projectID uint) models.EscalationPolicy {
+	return models.EscalationPolicy{
+		ProjectID:       projectID,
+		Enabled:         true,
+		RemindHours:     config.EscalationRemindHours,
+		ManagerDays:     config.EscalationManagerDays,
+		DeptManagerDays: config.EscalationDeptManagerDays,
+	}
+}
+
+// PolicyFor 项目的逾期提醒策略，未配置时返回默认策略
+func PolicyFor(db *gorm.DB, projectID uint) models.EscalationPolicy {
+	var policy models.EscalationPolicy
+	if db.Where("project_id = ?", projectID).First(&policy).Error != nil {
+		return DefaultPolicy(projectID)
+	}
+	return policy
+}
+
+// Run 检查未完成任务的截止时间：维护逾期标记并按阶段发送提醒，返回发送的提醒数量
+func Run(db *gorm.DB, now time.Time) int {
+	// 只需检查即将进入提醒范围、已逾期或已有提醒记录的任务
+	maxRemind := config.EscalationRemindHours
+	var configured int
+	db.Model(&models.EscalationPolicy{}).Select("COALESCE(MAX(remind_hours), 0)").Scan(&configured)
+	maxRemind = max(maxRemind, configured)
+
+	var tasks []models.Task
+	db.Preload("Project").Preload("Assignee").
+		Where("status <> ? AND deadline IS NOT NULL", config.TaskCompleted).
+		Where("deadline <= ? OR escalation_level > ? OR overdue_since IS NOT NULL",
+			now.Add(time.Duration(maxRemind)*time.Hour), models.EscalationNone).
+		Find(&tasks)
+
+	policies := make(map[uint]models.EscalationPolicy)
+	sent := 0
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Project == nil || task.Project.Status == config.StatusCompleted {
+			continue
+		}
+		policy, ok := policies[task.ProjectID]
+		if !ok {
+			policy = PolicyFor(db, task.ProjectID)
+			policies[task.ProjectID] = policy
+		}
+		sent += check(db, task, &policy, now)
+	}
+	return sent
+}
+
+// check 检查单个任务，返回发送的提醒数量
+func check(db *gorm.DB, task *models.Task, policy *models.EscalationPolicy, now time.Time) int {
+	deadline := *task.Deadline
+	updates := map[string]interface{}{}
+	level := task.EscalationLevel
+	sent := 0
+
+	if deadline.After(now) {
+		// 未逾期（或截止日期已延后）：清除逾期标记，超出提醒范围时重新开始提醒
+		if task.OverdueSince != nil {
+			updates["overdue_since"] = nil
+		}
+		remindAt := deadline.Add(-time.Duration(policy.RemindHours) * time.Hour)
+		switch {
+		case policy.RemindHours > 0 && !now.Before(remindAt):
+			if level > models.EscalationDueSoon {
+				level = models.EscalationNone
+			}
+			if level < models.EscalationDueSoon && policy.Enabled {
+				sent += notify(db, task, []uint{task.AssigneeID}, "任务即将到期",
+					fmt.Sprintf("任务「%s」将于 %s 到期，请及时处理", task.TaskName, deadline.Format("2006-01-02 15:04")))
+				level = models.EscalationDueSoon
+			}
+		default:
+			level = models.EscalationNone
+		}
+	} else {
+		if task.OverdueSince == nil {
+			updates["overdue_since"] = deadline
+		}
+		if policy.Enabled {
+			overdueDays := int(now.Sub(deadline).Hours() / 24)
+			if level < models.EscalationOverdue {
+				sent += notify(db, task, []uint{task.AssigneeID}, "任务已逾期",
+					fmt.Sprintf("任务「%s」已超过截止时间 %s，请尽快完成", task.TaskName, deadline.Format("2006-01-02 15:04")))
+				level = models.EscalationOverdue
+			}
+			if level < models.EscalationManager && policy.ManagerDays > 0 && overdueDays >= policy.ManagerDays {
+				sent += notify(db, task, []uint{task.Project.ManagerID}, "任务逾期升级",
+					fmt.Sprintf("项目「%s」的任务「%s」（负责人：%s）已逾期%d天", task.Project.Name, task.TaskName, assigneeName(task), overdueDays))
+				level = models.EscalationManager
+			}
+			if level < models.EscalationDeptManager && policy.DeptManagerDays > 0 && overdueDays >= policy.DeptManagerDays {
+				sent += notify(db, task, deptManagerIDs(db, task.Project.ManagerID), "任务严重逾期",
+					fmt.Sprintf("项目「%s」的任务「%s」（负责人：%s）已逾期%d天，请关注", task.Project.Name, task.TaskName, assigneeName(task), overdueDays))
+				level = models.EscalationDeptManager
+			}
+		}
+	}
+
+	if level != task.EscalationLevel {
+		updates["escalation_level"] = level
+	}
+	if len(updates) > 0 {
+		db.Model(&models.Task{}).Where("id = ?", task.ID).Updates(updates)
+	}
+	return sent
+}
+
+// notify 向用户发送逾期提醒（系统发出，跳过无效和重复的接收人），返回发送数量
+func notify(db *gorm.DB, task *models.Task, userIDs []uint, title, content string) int {
+	seen := make(map[uint]bool)
+	for _, id := range userIDs {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		db.Create(&models.Notification{
+			UserID:     id,
+			Type:       models.NotificationOverdue,
+			Title:      title,
+			Content:    content,
+			TargetType: models.CommentTargetTask,
+			TargetID:   task.ID,
+			ProjectID:  task.ProjectID,
+		})
+	}
+	return len(seen)
+}
+
+// deptManagerIDs 项目负责人所在部门的部门经理，部门内没有部门经理时通知全部部门经理
+func deptManagerIDs(db *gorm.DB, managerID uint) []uint {
+	deptManagers := func() *gorm.DB {
+		return db.Model(&models.User{}).
+			Where("status = ? AND role_id IN (?)", 1, db.Model(&models.Role{}).Select("id").Where("code = ?", config.RoleDeptManager))
+	}
+
+	var ids []uint
+	var manager models.User
+	if db.Select("id, department").First(&manager, managerID).Error == nil && manager.Department != "" {
+		deptManagers().Where("department = ?", manager.Department).Pluck("id", &ids)
+	}
+	if len(ids) == 0 {
+		deptManagers().Pluck("id", &ids)
+	}
+	return ids
+}
+
+func assigneeName(task *models.Task) string {
+	if task.Assignee != nil {
+		return task.Assignee.Name
+	}
+	return "未分配"
+}
@@ -4,8 +4,10 @@ import (
 	"log"
 	"os"
 	"project-flow/config"
+	"project-flow/escalation"
 	"project-flow/middleware"
 	"project-flow/models"
+	"project-flow/recurrence"
 	"project-flow/routes"
 
 	"github.com/gin-gonic/gin"
@@ -21,18 +23,26 @@ func main() {
 	// 初始化默认数据
 	models.InitDefaultData()
 
+	// 启动重复任务生成
+	recurrence.Start()
+
+	// 启动任务逾期检查和提醒
+	escalation.Start()
+
 	// 确保上传目录存在
 	os.MkdirAll(config.UploadPath, 0755)
 
 	// 创建Gin实例
 	r := gin.Default()
 
+	// 只采信受信任代理转发的客户端IP，避免伪造 X-Forwarded-For 绕过按IP限流
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatal("受信任代理配置错误:", err)
+	}
+
 	// 跨域中间件
 	r.Use(middleware.CORSMiddleware())
 
-	// 静态文件服务
-	r.Static("/uploads", config.UploadPath)
-
 	// 设置路由
 	routes.SetupRoutes(r)
 
@@ -2,12 +2,21 @@ package middleware
 
 import (
 	"project-flow/config"
+	"project-flow/models"
 	"project-flow/utils"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// passwordChangeAllowed 必须修改密码时允许访问的接口（修改密码，以及页面所需的当前用户信息和退出登录）
+var passwordChangeAllowed = map[string]bool{
+	"/api/user/change-password": true,
+	"/api/user/current":         true,
+	"/api/logout":               true,
+}
+
 // AuthMiddleware JWT认证中间件
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -23,9 +32,10 @@ func AuthMiddleware() gin.HandlerFunc {
 			}
 		}
 
-		// 如果 Header 没有，从 Query 参数获取（用于文件下载等场景）
-		if token == "" {
-			token = c.Query("token")
+		// 个人访问Token（只允许通过 Header 传递，避免出现在访问日志中）
+		if strings.HasPrefix(token, config.AccessTokenPrefix) {
+			authenticateAccessToken(c, token)
+			return
 		}
 
 		if token == "" {
@@ -41,14 +51,86 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查会话是否已被吊销（退出登录、修改密码、禁用账号等）
+		var session models.UserSession
+		if err := config.GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?",
+			claims.SessionID, claims.UserID, time.Now()).First(&session).Error; err != nil {
+			utils.Unauthorized(c, "登录已失效，请重新登录")
+			c.Abort()
+			return
+		}
+
+		// 必须修改密码的用户只能访问修改密码相关接口
+		if claims.MustChangePassword && !passwordChangeAllowed[c.FullPath()] {
+			utils.Forbidden(c, "请先修改密码")
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("roleCode", claims.RoleCode)
+		c.Set("sessionID", claims.SessionID)
 		c.Next()
 	}
 }
 
+// authenticateAccessToken 校验个人访问Token及其授权范围
+func authenticateAccessToken(c *gin.Context, token string) {
+	db := config.GetDB()
+
+	var pat models.PersonalAccessToken
+	if err := db.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?",
+		utils.HashToken(token), time.Now()).First(&pat).Error; err != nil {
+		utils.Unauthorized(c, "访问Token无效、已过期或已吊销")
+		c.Abort()
+		return
+	}
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, pat.UserID).Error; err != nil || user.Status != 1 {
+		utils.Unauthorized(c, "访问Token所属账号不可用")
+		c.Abort()
+		return
+	}
+	if user.MustChangePassword {
+		utils.Forbidden(c, "访问Token所属账号需先修改密码")
+		c.Abort()
+		return
+	}
+
+	// 按 /api/ 下的一级路由分组校验授权范围，GET/HEAD 视为读操作
+	group := strings.SplitN(strings.TrimPrefix(c.FullPath(), "/api/"), "/", 2)[0]
+	write := c.Request.Method != "GET" && c.Request.Method != "HEAD"
+	if !pat.Allows(group, write) {
+		utils.Forbidden(c, "访问Token无权访问该接口")
+		c.Abort()
+		return
+	}
+
+	// 更新最近使用时间（按间隔节流）
+	now := time.Now()
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > config.AccessTokenTouchIntervalS*time.Second {
+		db.Model(&pat).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": c.ClientIP(),
+		})
+	}
+
+	roleCode := ""
+	if user.Role != nil {
+		roleCode = user.Role.Code
+	}
+
+	// 将用户信息存入上下文（接口权限仍受用户角色限制）
+	c.Set("userID", user.ID)
+	c.Set("username", user.Username)
+	c.Set("roleCode", roleCode)
+	c.Set("accessTokenID", pat.ID)
+	c.Next()
+}
+
 // RoleMiddleware 角色权限中间件
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -84,6 +166,40 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission 权限中间件：根据当前用户角色的 Permissions 判断是否拥有任一所需权限
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roleCode, exists := c.Get("roleCode")
+		if !exists {
+			utils.Unauthorized(c, "未获取到用户角色信息")
+			c.Abort()
+			return
+		}
+
+		for _, perm := range perms {
+			if HasPermission(roleCode.(string), perm) {
+				c.Next()
+				return
+			}
+		}
+
+		utils.Forbidden(c, "没有权限执行此操作")
+		c.Abort()
+	}
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(roleCode, perm string) bool {
+	if roleCode == "" {
+		return false
+	}
+	var role models.Role
+	if err := config.GetDB().Where("code = ?", roleCode).First(&role).Error; err != nil {
+		return false
+	}
+	return role.HasPermission(perm)
+}
+
 // CORSMiddleware 跨域中间件
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
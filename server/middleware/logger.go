@@ -43,8 +43,13 @@ func LogOperation(c *gin.Context, action, module, targetType string, targetID ui
 		return
 	}
 
+	LogOperationByUser(c, userID.(uint), action, module, targetType, targetID, targetName, description, result)
+}
+
+// LogOperationByUser 以指定用户身份记录操作日志（用于登录流程等尚未建立登录态的场景）
+func LogOperationByUser(c *gin.Context, userID uint, action, module, targetType string, targetID uint, targetName, description, result string) {
 	log := models.OperationLog{
-		UserID:      userID.(uint),
+		UserID:      userID,
 		Action:      action,
 		Module:      module,
 		TargetType:  targetType,
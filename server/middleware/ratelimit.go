@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"project-flow/config"
+	"project-flow/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter 令牌桶限流器（进程内存储，按键分别计数）
+type RateLimiter struct {
+	rule    config.RateLimitRule
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	sweepAt time.Time
+}
+
+// tokenBucket 单个键的令牌桶
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time
+	blocked bool // 是否处于被拦截状态（同一轮拦截只记录一次日志）
+}
+
+// NewRateLimiter 创建令牌桶限流器
+func NewRateLimiter(rule config.RateLimitRule) *RateLimiter {
+	return &RateLimiter{
+		rule:    rule,
+		buckets: make(map[string]*tokenBucket),
+		sweepAt: time.Now().Add(rule.Period),
+	}
+}
+
+// Allow 消耗一个令牌；被拦截时返回需等待的时间，以及是否为本轮首次拦截
+func (l *RateLimiter) Allow(key string) (allowed bool, retryAfter time.Duration, firstBlock bool) {
+	if !l.rule.Enabled() {
+		return true, 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	refill := float64(l.rule.Burst) / l.rule.Period.Seconds() // 每秒补充的令牌数
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.rule.Burst), last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(l.rule.Burst), b.tokens+now.Sub(b.last).Seconds()*refill)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.blocked = false
+		return true, 0, false
+	}
+
+	firstBlock = !b.blocked
+	b.blocked = true
+	return false, time.Duration((1 - b.tokens) / refill * float64(time.Second)), firstBlock
+}
+
+// sweep 定期清理已补满的令牌桶，避免内存无限增长
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.rule.Period {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweepAt = now.Add(l.rule.Period)
+}
+
+// RateLimitMiddleware 按客户端IP限流（客户端IP只采信 TRUSTED_PROXIES 中代理转发的地址），group 为路由分组名称（用于日志）
+func RateLimitMiddleware(group string, rule config.RateLimitRule) gin.HandlerFunc {
+	limiter := NewRateLimiter(rule)
+	return func(c *gin.Context) {
+		if allowed, retryAfter, firstBlock := limiter.Allow(c.ClientIP()); !allowed {
+			rejectRateLimited(c, group, "", retryAfter, firstBlock)
+			return
+		}
+		c.Next()
+	}
+}
+
+// LoginRateLimitMiddleware 登录类接口限流：按客户端IP限流，请求体中带用户名时再按用户名限流
+func LoginRateLimitMiddleware(ipRule, userRule config.RateLimitRule) gin.HandlerFunc {
+	ipLimiter := NewRateLimiter(ipRule)
+	userLimiter := NewRateLimiter(userRule)
+	return func(c *gin.Context) {
+		if allowed, retryAfter, firstBlock := ipLimiter.Allow(c.ClientIP()); !allowed {
+			rejectRateLimited(c, "login", "", retryAfter, firstBlock)
+			return
+		}
+
+		if username := peekUsername(c); username != "" {
+			if allowed, retryAfter, firstBlock := userLimiter.Allow(strings.ToLower(username)); !allowed {
+				rejectRateLimited(c, "login", username, retryAfter, firstBlock)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// peekUsername 读取 JSON 请求体中的用户名，读取后还原请求体供后续处理
+func peekUsername(c *gin.Context) string {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	json.Unmarshal(body, &req)
+	return strings.TrimSpace(req.Username)
+}
+
+// rejectRateLimited 返回429并在本轮首次拦截时记录操作日志
+func rejectRateLimited(c *gin.Context, group, username string, retryAfter time.Duration, firstBlock bool) {
+	if firstBlock {
+		target := c.ClientIP()
+		description := fmt.Sprintf("请求过于频繁被拦截（%s %s，来源IP %s）", c.Request.Method, c.FullPath(), c.ClientIP())
+		if username != "" {
+			target = username
+			description = fmt.Sprintf("用户名 %s 登录尝试过于频繁被拦截（来源IP %s）", username, c.ClientIP())
+		}
+		var userID uint
+		if id, exists := c.Get("userID"); exists {
+			userID = id.(uint)
+		}
+		LogOperationByUser(c, userID, "rate_limit", group, "client", 0, target, description, "failed")
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	utils.ErrorWithStatus(c, http.StatusTooManyRequests, http.StatusTooManyRequests, "请求过于频繁，请稍后再试")
+	c.Abort()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"log"
 	"project-flow/config"
 
@@ -13,16 +14,40 @@ func AutoMigrate() {
 
 	err := db.AutoMigrate(
 		&User{},
+		&UserSession{},
 		&Role{},
+		&PasswordHistory{},
+		&UserRecoveryCode{},
+		&PersonalAccessToken{},
+		&LDAPGroupMapping{},
 		&Project{},
 		&ProjectPhase{},
+		&PhaseGateRecord{},
+		&ProjectTemplate{},
+		&ProjectTemplatePhase{},
+		&ProjectTemplateTask{},
 		&Task{},
+		&TaskDependency{},
+		&TaskChecklistItem{},
+		&TaskRecurrence{},
+		&ReviewChain{},
+		&ReviewChainStep{},
+		&TaskReviewRound{},
+		&TaskReviewStep{},
 		&Document{},
 		&Contract{},
 		&KnowledgeBase{},
 		&KBCategory{},
 		&KBVersion{},
 		&OperationLog{},
+		&Comment{},
+		&CommentRevision{},
+		&Notification{},
+		&LaborRate{},
+		&Timesheet{},
+		&TimeEntry{},
+		&EscalationPolicy{},
+		&TaskStatusHistory{},
 		&ProjectMember{},
 		&Expense{},
 	)
@@ -42,32 +67,56 @@ func InitDefaultData() {
 			Name:        "系统管理员",
 			Code:        config.RoleAdmin,
 			Description: "系统管理员，拥有所有权限",
-			Permissions: `["all"]`,
+			Permissions: permissionsJSON(config.PermAll),
 		},
 		{
 			Name:        "部门经理",
 			Code:        config.RoleDeptManager,
-			Description: "部门经理，可管理用户和查看所有项目",
-			Permissions: `["user:manage","project:view","project:create","document:view","document:download","kb:all","log:view"]`,
+			Description: "部门经理，可管理用户和查看本部门项目",
+			Permissions: permissionsJSON(
+				config.PermUserManage, config.PermProjectView, config.PermProjectViewDept, config.PermProjectCreate, config.PermTemplateManage, config.PermTaskCreate,
+				config.PermDocumentView, config.PermDocumentDownload, config.PermDocumentUpload, config.PermDocumentArchive,
+				config.PermContractView, "kb:all", config.PermLogView,
+				config.PermExpenseView, config.PermExpenseEdit, config.PermExpenseImport, config.PermExpenseExport, config.PermLaborRate,
+			),
 		},
 		{
 			Name:        "组长",
 			Code:        config.RoleTeamLeader,
-			Description: "组长，可创建项目并查看所有项目",
-			Permissions: `["project:view","project:create","document:view","document:download","kb:view","kb:download"]`,
+			Description: "组长，可创建项目并查看参与的项目",
+			Permissions: permissionsJSON(
+				config.PermProjectView, config.PermProjectCreate, config.PermTaskCreate,
+				config.PermDocumentView, config.PermDocumentDownload, config.PermDocumentUpload,
+				config.PermContractView, config.PermContractManage,
+				config.PermKBView, config.PermKBDownload, config.PermKBUpload,
+				config.PermExpenseView, config.PermExpenseEdit, config.PermExpenseImport, config.PermExpenseExport,
+			),
 		},
 		{
 			Name:        "组员",
 			Code:        config.RoleTeamMember,
-			Description: "组员，可查看所有项目和资料",
-			Permissions: `["project:view","document:view","document:download","kb:view","kb:download"]`,
+			Description: "组员，可查看参与的项目和资料",
+			Permissions: permissionsJSON(
+				config.PermProjectView, config.PermProjectCreate, config.PermTaskCreate,
+				config.PermDocumentView, config.PermDocumentDownload, config.PermDocumentUpload,
+				config.PermContractView, config.PermContractManage,
+				config.PermKBView, config.PermKBDownload, config.PermKBUpload,
+				config.PermExpenseView, config.PermExpenseEdit, config.PermExpenseImport, config.PermExpenseExport,
+			),
 		},
 	}
 
+	// 内置角色的权限由系统维护，每次启动时同步为默认值；自定义角色通过角色管理接口维护
 	for _, role := range roles {
+		role.IsSystem = true
 		var existing Role
 		if db.Where("code = ?", role.Code).First(&existing).RowsAffected == 0 {
 			db.Create(&role)
+		} else {
+			db.Model(&existing).Updates(map[string]interface{}{
+				"permissions": role.Permissions,
+				"is_system":   true,
+			})
 		}
 	}
 
@@ -75,19 +124,25 @@ func InitDefaultData() {
 	var adminRole Role
 	db.Where("code = ?", config.RoleAdmin).First(&adminRole)
 
+	// 默认密码只用于首次登录，登录后必须修改
 	var adminUser User
 	if db.Where("username = ?", "admin").First(&adminUser).RowsAffected == 0 {
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Admin@123"), bcrypt.DefaultCost)
 		admin := User{
-			Username:   "admin",
-			Password:   string(hashedPassword),
-			Name:       "系统管理员",
-			Email:      "admin@example.com",
-			Department: "系统部门",
-			RoleID:     adminRole.ID,
-			Status:     1,
+			Username:           "admin",
+			Password:           string(hashedPassword),
+			Name:               "系统管理员",
+			Email:              "admin@example.com",
+			Department:         "系统部门",
+			RoleID:             adminRole.ID,
+			Status:             1,
+			MustChangePassword: true,
 		}
 		db.Create(&admin)
+	} else if !adminUser.MustChangePassword &&
+		bcrypt.CompareHashAndPassword([]byte(adminUser.Password), []byte("Admin@123")) == nil {
+		// 已有部署中仍在使用默认密码的管理员同样要求修改
+		db.Model(&adminUser).Update("must_change_password", true)
 	}
 
 	categories := []KBCategory{
@@ -105,5 +160,16 @@ func InitDefaultData() {
 		}
 	}
 
+	// 引入"已提交审核"状态前提交审核的任务仍为进行中，按审核中的轮次同步状态
+	db.Model(&Task{}).Where("status <> ?", config.TaskSubmitted).
+		Where("id IN (?)", db.Model(&TaskReviewRound{}).Select("task_id").Where("status = ?", ReviewPending)).
+		Update("status", config.TaskSubmitted)
+
 	log.Println("默认数据初始化完成")
 }
+
+// permissionsJSON 将权限列表序列化为 Role.Permissions 存储格式
+func permissionsJSON(perms ...string) string {
+	data, _ := json.Marshal(perms)
+	return string(data)
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,34 +10,144 @@ import (
 
 // User 用户模型
 type User struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	Username     string         `gorm:"unique;not null;size:50" json:"username"`
-	Password     string         `gorm:"not null;size:255" json:"-"`
-	Name         string         `gorm:"size:50" json:"name"`
-	Email        string         `gorm:"size:100" json:"email"`
-	Phone        string         `gorm:"size:20" json:"phone"`
-	Department   string         `gorm:"size:100" json:"department"`
-	FunctionGroup string        `gorm:"size:100" json:"function_group"`
-	RoleID       uint           `json:"role_id"`
-	Role         *Role          `gorm:"foreignKey:RoleID" json:"role,omitempty"`
-	Status       int            `gorm:"default:1" json:"status"` // 1:启用 0:禁用
-	FailedLogins int            `gorm:"default:0" json:"-"`
-	LockedUntil  *time.Time     `json:"-"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Username           string         `gorm:"unique;not null;size:50" json:"username"`
+	Password           string         `gorm:"not null;size:255" json:"-"`
+	Name               string         `gorm:"size:50" json:"name"`
+	Email              string         `gorm:"size:100" json:"email"`
+	Phone              string         `gorm:"size:20" json:"phone"`
+	Department         string         `gorm:"size:100" json:"department"`
+	FunctionGroup      string         `gorm:"size:100" json:"function_group"`
+	WeeklyCapacity     float64        `gorm:"type:decimal(5,1);default:0" json:"weekly_capacity"` // 每周可用工时（小时），为0时使用默认值
+	RoleID             uint           `json:"role_id"`
+	Role               *Role          `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+	Status             int            `gorm:"default:1" json:"status"`                               // 1:启用 0:禁用
+	AuthSource         string         `gorm:"size:20;default:'local'" json:"auth_source"`            // 认证来源：local/ldap
+	OIDCSubject        *string        `gorm:"column:oidc_subject;uniqueIndex;size:255" json:"-"`     // 关联的OIDC账号（issuer|sub）
+	TOTPSecret         string         `gorm:"column:totp_secret;size:64" json:"-"`                   // TOTP密钥（启用前为待验证的密钥）
+	TOTPEnabled        bool           `gorm:"column:totp_enabled;default:false" json:"totp_enabled"` // 是否已启用双因素认证
+	TOTPLastStep       int64          `gorm:"column:totp_last_step;default:0" json:"-"`              // 最近一次使用的时间步，防止动态码重放
+	MustChangePassword bool           `gorm:"default:false" json:"must_change_password"`             // 登录后必须先修改密码（初始密码、重置后或已过期）
+	PasswordChangedAt  *time.Time     `json:"password_changed_at"`                                   // 最近一次修改密码时间
+	FailedLogins       int            `gorm:"default:0" json:"-"`
+	LockedUntil        *time.Time     `json:"-"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// UserSession 用户登录会话（刷新Token持久化，用于服务端吊销）
+type UserSession struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"index;not null" json:"user_id"`
+	RefreshTokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // 刷新Token的SHA-256摘要
+	IPAddress        string     `gorm:"size:50" json:"ip_address"`
+	UserAgent        string     `gorm:"size:500" json:"user_agent"`
+	ExpiresAt        time.Time  `json:"expires_at"`   // 会话过期时间
+	RefreshedAt      *time.Time `json:"refreshed_at"` // 最近一次刷新时间
+	RevokedAt        *time.Time `json:"revoked_at"`   // 吊销时间（为空表示有效）
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 // Role 角色模型
 type Role struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"unique;not null;size:50" json:"name"`
-	Code        string         `gorm:"unique;not null;size:50" json:"code"`
-	Description string         `gorm:"size:255" json:"description"`
-	Permissions string         `gorm:"type:text" json:"permissions"` // JSON格式存储权限列表
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"unique;not null;size:50" json:"name"`
+	Code             string         `gorm:"unique;not null;size:50" json:"code"`
+	Description      string         `gorm:"size:255" json:"description"`
+	Permissions      string         `gorm:"type:text" json:"permissions"`            // JSON格式存储权限列表
+	IsSystem         bool           `gorm:"default:false" json:"is_system"`          // 是否内置角色（内置角色的权限由系统维护）
+	RequireTwoFactor bool           `gorm:"default:false" json:"require_two_factor"` // 该角色的用户是否必须启用双因素认证
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PermissionList 解析角色的权限列表
+func (r *Role) PermissionList() []string {
+	var perms []string
+	if r.Permissions != "" {
+		json.Unmarshal([]byte(r.Permissions), &perms)
+	}
+	return perms
+}
+
+// HasPermission 判断角色是否拥有指定权限（支持 "all" 与 "模块:all" 通配）
+func (r *Role) HasPermission(perm string) bool {
+	module := perm
+	if idx := strings.Index(perm, ":"); idx >= 0 {
+		module = perm[:idx]
+	}
+	for _, p := range r.PermissionList() {
+		if p == "all" || p == perm || p == module+":all" {
+			return true
+		}
+	}
+	return false
+}
+
+// PasswordHistory 历史密码（用于拒绝重复使用最近的密码）
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserRecoveryCode 双因素认证恢复码（只保存摘要，每个恢复码只能使用一次）
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PersonalAccessToken 个人访问Token（供脚本和系统集成调用接口，只保存摘要）
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	TokenPrefix string     `gorm:"size:20" json:"token_prefix"` // 明文前几位，便于识别
+	Scopes      string     `gorm:"type:text" json:"scopes"`     // JSON格式存储授权范围
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:50" json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ScopeList 解析Token的授权范围
+func (t *PersonalAccessToken) ScopeList() []string {
+	var scopes []string
+	if t.Scopes != "" {
+		json.Unmarshal([]byte(t.Scopes), &scopes)
+	}
+	return scopes
+}
+
+// Allows 判断Token是否可以访问指定路由分组（write 包含 read）
+func (t *PersonalAccessToken) Allows(group string, write bool) bool {
+	for _, s := range t.ScopeList() {
+		if s == group+":write" || (!write && s == group+":read") {
+			return true
+		}
+	}
+	return false
+}
+
+// LDAPGroupMapping 目录组到系统角色的映射
+type LDAPGroupMapping struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	GroupDN   string    `gorm:"uniqueIndex;size:255;not null" json:"group_dn"` // 目录组DN
+	RoleCode  string    `gorm:"size:50;not null" json:"role_code"`             // 对应 Role.Code
+	Priority  int       `gorm:"default:0" json:"priority"`                     // 用户属于多个组时取优先级最高的映射
+	Remark    string    `gorm:"size:255" json:"remark"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Project 项目模型
@@ -59,6 +171,7 @@ type Project struct {
 	OtherCost       float64        `gorm:"not null;default:0" json:"other_cost"`              // 其他费用
 	CurrentPhase    string         `gorm:"size:50;default:'initiation'" json:"current_phase"` // 当前阶段
 	Status          string         `gorm:"size:50;default:'not_started'" json:"status"`       // 项目状态
+	TemplateID      *uint          `json:"template_id"`                                       // 创建时使用的项目模板
 	CreatedBy       uint           `json:"created_by"`
 	Creator         *User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
 	CreatedAt       time.Time      `json:"created_at"`
@@ -70,47 +183,314 @@ type Project struct {
 
 // ProjectPhase 项目阶段模型
 type ProjectPhase struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	ProjectID   uint       `json:"project_id"`
-	PhaseName   string     `gorm:"size:50;not null" json:"phase_name"` // 阶段名称
-	PhaseOrder  int        `json:"phase_order"`                        // 阶段顺序
-	IsFixed     bool       `gorm:"default:false" json:"is_fixed"`      // 是否固定阶段（固定阶段不可删除）
-	Status      string     `gorm:"size:50;default:'not_started'" json:"status"`
-	StartDate   *time.Time `json:"start_date"`
-	EndDate     *time.Time `json:"end_date"`
-	CompletedAt *time.Time `json:"completed_at"`
-	Remark      string     `gorm:"type:text" json:"remark"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ProjectID        uint       `json:"project_id"`
+	PhaseName        string     `gorm:"size:50;not null" json:"phase_name"` // 阶段名称
+	PhaseOrder       int        `json:"phase_order"`                        // 阶段顺序
+	IsFixed          bool       `gorm:"default:false" json:"is_fixed"`      // 是否固定阶段（固定阶段不可删除）
+	Status           string     `gorm:"size:50;default:'not_started'" json:"status"`
+	RequiredDocTypes string     `gorm:"type:text" json:"required_doc_types"`   // JSON格式存储本阶段必须提交的资料类型
+	RequireSignOff   bool       `gorm:"default:false" json:"require_sign_off"` // 完成前是否需要部门经理签核
+	SignedOffBy      *uint      `json:"signed_off_by"`                         // 签核人
+	SignedOffAt      *time.Time `json:"signed_off_at"`                         // 签核时间（为空表示未签核）
+	StartDate        *time.Time `json:"start_date"`
+	EndDate          *time.Time `json:"end_date"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	Remark           string     `gorm:"type:text" json:"remark"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DocTypeList 解析阶段必须提交的资料类型
+func (p *ProjectPhase) DocTypeList() []string {
+	return decodeDocTypes(p.RequiredDocTypes)
+}
+
+// 阶段门禁决策类型
+const (
+	GateDecisionPassed          = "passed"            // 门禁检查通过，阶段完成
+	GateDecisionBlocked         = "blocked"           // 门禁检查未通过，拒绝完成
+	GateDecisionSignOff         = "sign_off"          // 部门经理签核通过
+	GateDecisionSignOffRejected = "sign_off_rejected" // 部门经理签核驳回
+	GateDecisionReopened        = "reopened"          // 已完成的阶段重新打开
+	GateDecisionRolledBack      = "rolled_back"       // 因前序阶段重新打开而回退为未开始
+	GateDecisionCriteriaChanged = "criteria_changed"  // 门禁条件（必需资料、签核要求）被修改
+)
+
+// PhaseGateRecord 阶段门禁决策记录（含重新打开、回退，审计用，只增不改）
+type PhaseGateRecord struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProjectID  uint      `gorm:"index;not null" json:"project_id"`
+	PhaseID    uint      `gorm:"index;not null" json:"phase_id"`
+	Decision   string    `gorm:"size:30;not null" json:"decision"` // 决策类型
+	Criteria   string    `gorm:"type:text" json:"criteria"`        // JSON格式存储决策时的门禁检查结果
+	Comment    string    `gorm:"type:text" json:"comment"`
+	OperatorID uint      `json:"operator_id"`
+	Operator   *User     `gorm:"foreignKey:OperatorID" json:"operator,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProjectTemplate 项目模板（预置阶段、任务骨架和必需资料，创建项目时一次性实例化）
+type ProjectTemplate struct {
+	ID          uint                   `gorm:"primaryKey" json:"id"`
+	Name        string                 `gorm:"unique;not null;size:100" json:"name"` // 模板名称
+	ProjectType string                 `gorm:"size:50" json:"project_type"`          // 适用的项目类型（为空表示不限）
+	Description string                 `gorm:"type:text" json:"description"`
+	IsActive    bool                   `gorm:"default:true" json:"is_active"` // 停用后不能再用于创建项目
+	CreatedBy   uint                   `json:"created_by"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Phases      []ProjectTemplatePhase `gorm:"foreignKey:TemplateID" json:"phases,omitempty"`
+}
+
+// ProjectTemplatePhase 模板阶段
+// 阶段名称为固定阶段编码（initiation 等）时对应项目的固定阶段，其余按顺序作为自定义阶段
+type ProjectTemplatePhase struct {
+	ID               uint                  `gorm:"primaryKey" json:"id"`
+	TemplateID       uint                  `gorm:"index;not null" json:"template_id"`
+	PhaseName        string                `gorm:"size:50;not null" json:"phase_name"`
+	PhaseOrder       int                   `json:"phase_order"`                                       // 模板内的阶段顺序
+	RequiredDocTypes string                `gorm:"type:text" json:"required_doc_types"`               // JSON格式存储本阶段必须提交的资料类型
+	RequireSignOff   bool                  `gorm:"default:false" json:"require_sign_off"`             // 完成前是否需要部门经理签核
+	Tasks            []ProjectTemplateTask `gorm:"foreignKey:TemplatePhaseID" json:"tasks,omitempty"` // 任务骨架
+}
+
+// DocTypeList 解析阶段必须提交的资料类型
+func (p *ProjectTemplatePhase) DocTypeList() []string {
+	return decodeDocTypes(p.RequiredDocTypes)
+}
+
+// ProjectTemplateTask 模板任务骨架
+type ProjectTemplateTask struct {
+	ID              uint   `gorm:"primaryKey" json:"id"`
+	TemplatePhaseID uint   `gorm:"index;not null" json:"template_phase_id"`
+	TaskName        string `gorm:"size:200;not null" json:"task_name"`
+	Description     string `gorm:"type:text" json:"description"`
+	TaskType        string `gorm:"size:50" json:"task_type"`
+	Priority        int    `gorm:"default:2" json:"priority"`
+	Deliverables    string `gorm:"type:text" json:"deliverables"` // 交付件要求
+	DeadlineOffset  int    `json:"deadline_offset"`               // 截止日期：相对立项日期的天数（0表示不设置）
+	AssigneeRole    string `gorm:"size:50" json:"assignee_role"`  // 默认责任人：项目中的角色（manager/sub_manager），为空则不指派
+	SortOrder       int    `gorm:"default:0" json:"sort_order"`
+}
+
+// decodeDocTypes 解析JSON格式的资料类型列表
+func decodeDocTypes(raw string) []string {
+	var types []string
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &types)
+	}
+	return types
 }
 
 // Task 任务模型
 type Task struct {
-	ID            uint           `gorm:"primaryKey" json:"id"`
-	ProjectID     uint           `json:"project_id"`
-	Project       *Project       `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
-	PhaseID       uint           `json:"phase_id"` // 所属阶段ID
-	Phase         *ProjectPhase  `gorm:"foreignKey:PhaseID" json:"phase,omitempty"`
-	TaskName      string         `gorm:"size:200;not null" json:"task_name"` // 任务名称
-	Description   string         `gorm:"type:text" json:"description"`       // 任务描述
-	TaskType      string         `gorm:"size:50" json:"task_type"`           // 任务类型
-	AssigneeID    uint           `json:"assignee_id"`                        // 责任人ID
-	Assignee      *User          `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
-	AssigneeType  string         `gorm:"size:50" json:"assignee_type"` // 责任主体类型
-	Deadline      *time.Time     `json:"deadline"`                     // 截止日期
-	Status        string         `gorm:"size:50;default:'not_started'" json:"status"`
-	Priority      int            `gorm:"default:2" json:"priority"`       // 优先级 1高 2中 3低
-	Deliverables  string         `gorm:"type:text" json:"deliverables"`   // 交付件要求
-	ReviewStatus  string         `gorm:"size:50" json:"review_status"`    // 审核状态
-	ReviewComment string         `gorm:"type:text" json:"review_comment"` // 审核意见
-	ReviewedBy    uint           `json:"reviewed_by"`
-	ReviewedAt    *time.Time     `json:"reviewed_at"`
-	CompletedAt   *time.Time     `json:"completed_at"`
-	CreatedBy     uint           `json:"created_by"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
-	Documents     []Document     `gorm:"foreignKey:TaskID" json:"documents,omitempty"`
+	ID              uint                `gorm:"primaryKey" json:"id"`
+	ProjectID       uint                `json:"project_id"`
+	Project         *Project            `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	PhaseID         uint                `json:"phase_id"` // 所属阶段ID
+	Phase           *ProjectPhase       `gorm:"foreignKey:PhaseID" json:"phase,omitempty"`
+	ParentID        *uint               `gorm:"index" json:"parent_id"`                               // 父任务ID（为空表示顶层任务）
+	RecurrenceID    *uint               `gorm:"uniqueIndex:idx_task_occurrence" json:"recurrence_id"` // 所属重复任务系列ID
+	OccurrenceAt    *time.Time          `gorm:"uniqueIndex:idx_task_occurrence" json:"occurrence_at"` // 在重复系列中的发生时间
+	TaskName        string              `gorm:"size:200;not null" json:"task_name"`                   // 任务名称
+	Description     string              `gorm:"type:text" json:"description"`                         // 任务描述
+	TaskType        string              `gorm:"size:50" json:"task_type"`                             // 任务类型
+	AssigneeID      uint                `json:"assignee_id"`                                          // 责任人ID
+	Assignee        *User               `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+	AssigneeType    string              `gorm:"size:50" json:"assignee_type"`                       // 责任主体类型
+	Deadline        *time.Time          `json:"deadline"`                                           // 截止日期
+	PlannedStart    *time.Time          `json:"planned_start"`                                      // 计划开始日期
+	Duration        int                 `json:"duration"`                                           // 计划工期（天）
+	EstimatedHours  float64             `gorm:"type:decimal(6,1);default:0" json:"estimated_hours"` // 预估工时（小时），为0时按工期估算
+	Status          string              `gorm:"size:50;default:'not_started'" json:"status"`
+	Priority        int                 `gorm:"default:2" json:"priority"`       // 优先级 1高 2中 3低
+	Deliverables    string              `gorm:"type:text" json:"deliverables"`   // 交付件要求
+	ReviewStatus    string              `gorm:"size:50" json:"review_status"`    // 审核状态
+	ReviewComment   string              `gorm:"type:text" json:"review_comment"` // 审核意见
+	ReviewedBy      uint                `json:"reviewed_by"`
+	ReviewedAt      *time.Time          `json:"reviewed_at"`
+	StartedAt       *time.Time          `json:"started_at"` // 实际开始时间（首次变为进行中）
+	CompletedAt     *time.Time          `json:"completed_at"`
+	OverdueSince    *time.Time          `gorm:"index" json:"overdue_since"`        // 开始逾期的时间（截止日期延后到未来时清除）
+	EscalationLevel int                 `gorm:"default:0" json:"escalation_level"` // 已发送的逾期提醒阶段
+	CreatedBy       uint                `json:"created_by"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt      `gorm:"index" json:"-"`
+	Documents       []Document          `gorm:"foreignKey:TaskID" json:"documents,omitempty"`
+	Children        []Task              `gorm:"foreignKey:ParentID" json:"children,omitempty"` // 子任务
+	Checklist       []TaskChecklistItem `gorm:"foreignKey:TaskID" json:"checklist,omitempty"`  // 检查清单
+	Progress        int                 `gorm:"-" json:"progress"`                             // 进度百分比（由子任务或检查清单汇总，不入库）
+}
+
+// TaskStatusHistory 任务状态变更记录
+type TaskStatusHistory struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TaskID     uint      `gorm:"index;not null" json:"task_id"`
+	FromStatus string    `gorm:"size:50" json:"from_status"`
+	ToStatus   string    `gorm:"size:50" json:"to_status"`
+	Actor      string    `gorm:"size:20" json:"actor"` // 操作角色：assignee/manager/reviewer/system
+	OperatorID uint      `json:"operator_id"`          // 操作人（系统自动变更时为0）
+	Operator   *User     `gorm:"foreignKey:OperatorID" json:"operator,omitempty"`
+	Comment    string    `gorm:"size:500" json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TaskChecklistItem 任务检查清单项
+type TaskChecklistItem struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TaskID    uint       `gorm:"index;not null" json:"task_id"`
+	Content   string     `gorm:"size:500;not null" json:"content"`
+	Checked   bool       `gorm:"default:false" json:"checked"`
+	CheckedBy *uint      `json:"checked_by"`
+	CheckedAt *time.Time `json:"checked_at"`
+	SortOrder int        `gorm:"default:0" json:"sort_order"`
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// 逾期提醒阶段（按顺序逐级升级）
+const (
+	EscalationNone        = 0 // 未提醒
+	EscalationDueSoon     = 1 // 即将到期，已提醒负责人
+	EscalationOverdue     = 2 // 已逾期，已提醒负责人
+	EscalationManager     = 3 // 已通知项目负责人
+	EscalationDeptManager = 4 // 已通知部门经理
+)
+
+// EscalationPolicy 项目的任务逾期提醒策略（未配置时使用系统默认值）
+type EscalationPolicy struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ProjectID       uint      `gorm:"uniqueIndex;not null" json:"project_id"`
+	Enabled         bool      `gorm:"not null" json:"enabled"`
+	RemindHours     int       `json:"remind_hours"`      // 截止前N小时提醒负责人（0表示不提前提醒）
+	ManagerDays     int       `json:"manager_days"`      // 逾期N天后通知项目负责人（0表示不通知）
+	DeptManagerDays int       `json:"dept_manager_days"` // 逾期N天后通知部门经理（0表示不通知）
+	UpdatedBy       uint      `json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// 重复任务系列状态
+const (
+	RecurrenceActive  = "active"  // 生效中
+	RecurrenceStopped = "stopped" // 已停止
+)
+
+// TaskRecurrence 重复任务系列：按重复规则定期生成任务实例
+type TaskRecurrence struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ProjectID      uint       `gorm:"index;not null" json:"project_id"`
+	PhaseID        uint       `json:"phase_id"` // 所属阶段ID（为0时按发生日期匹配项目阶段）
+	TaskName       string     `gorm:"size:200;not null" json:"task_name"`
+	Description    string     `gorm:"type:text" json:"description"`
+	TaskType       string     `gorm:"size:50" json:"task_type"`
+	AssigneeID     uint       `json:"assignee_id"`
+	Assignee       *User      `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+	Priority       int        `gorm:"default:2" json:"priority"`
+	Deliverables   string     `gorm:"type:text" json:"deliverables"`
+	Duration       int        `json:"duration"`                      // 每个实例的计划工期（天），用于推算计划开始日期
+	Rule           string     `gorm:"size:255;not null" json:"rule"` // 重复规则（RRULE格式，如 FREQ=MONTHLY;BYDAY=-1FR）
+	StartAt        time.Time  `json:"start_at"`                      // 第一次截止时间，之后每个实例的截止时刻与其相同
+	EffectiveFrom  time.Time  `json:"effective_from"`                // 只生成此时间之后的实例（修改系列后只影响以后的实例）
+	Status         string     `gorm:"size:20;default:'active'" json:"status"`
+	GeneratedUntil *time.Time `json:"generated_until"` // 已生成到的截止时间
+	StoppedAt      *time.Time `json:"stopped_at"`
+	CreatedBy      uint       `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// 任务依赖类型
+const (
+	DependencyFinishToStart  = "FS" // 前置任务完成后才能开始
+	DependencyStartToStart   = "SS" // 前置任务开始后才能开始
+	DependencyFinishToFinish = "FF" // 前置任务完成后才能完成
+)
+
+// TaskDependency 任务依赖（同一项目内，前置任务 -> 后续任务）
+type TaskDependency struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ProjectID     uint      `gorm:"index;not null" json:"project_id"`
+	PredecessorID uint      `gorm:"uniqueIndex:idx_task_dependency;not null" json:"predecessor_id"` // 前置任务ID
+	Predecessor   *Task     `gorm:"foreignKey:PredecessorID" json:"predecessor,omitempty"`
+	SuccessorID   uint      `gorm:"uniqueIndex:idx_task_dependency;not null" json:"successor_id"` // 后续任务ID
+	Successor     *Task     `gorm:"foreignKey:SuccessorID" json:"successor,omitempty"`
+	Type          string    `gorm:"size:10;default:'FS'" json:"type"` // 依赖类型：FS/SS/FF
+	Lag           int       `json:"lag"`                              // 间隔天数（可为负数表示提前）
+	CreatedBy     uint      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// 审核步骤的审核人类型
+const (
+	ReviewerProjectManager = "project_manager" // 项目负责人
+	ReviewerSubManager     = "sub_manager"     // 项目子负责人
+	ReviewerDeptManager    = "dept_manager"    // 部门经理（任一可查看该项目的部门经理）
+	ReviewerUser           = "user"            // 指定用户
+)
+
+// ReviewChain 任务审核链（按项目配置，可按任务类型区分）
+type ReviewChain struct {
+	ID        uint              `gorm:"primaryKey" json:"id"`
+	ProjectID uint              `gorm:"uniqueIndex:idx_review_chain;not null" json:"project_id"`
+	TaskType  string            `gorm:"uniqueIndex:idx_review_chain;size:50" json:"task_type"` // 适用的任务类型（为空表示该项目的默认审核链）
+	Steps     []ReviewChainStep `gorm:"foreignKey:ChainID" json:"steps,omitempty"`
+	CreatedBy uint              `json:"created_by"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ReviewChainStep 审核链步骤
+type ReviewChainStep struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	ChainID      uint   `gorm:"index;not null" json:"chain_id"`
+	StepOrder    int    `json:"step_order"`
+	Name         string `gorm:"size:100" json:"name"`         // 步骤名称
+	ReviewerType string `gorm:"size:20" json:"reviewer_type"` // 审核人类型
+	ReviewerID   *uint  `json:"reviewer_id"`                  // 指定用户（审核人类型为 user 时）
+	Reviewer     *User  `gorm:"foreignKey:ReviewerID" json:"reviewer,omitempty"`
+}
+
+// 审核轮次和审核步骤状态
+const (
+	ReviewWaiting   = "waiting"   // 等待前序步骤
+	ReviewPending   = "pending"   // 审核中
+	ReviewApproved  = "approved"  // 通过
+	ReviewRejected  = "rejected"  // 驳回
+	ReviewCancelled = "cancelled" // 因前序步骤驳回或撤回审核而取消
+)
+
+// TaskReviewRound 任务审核轮次（每次提交审核为一轮，驳回后重新提交开始新一轮）
+type TaskReviewRound struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	TaskID      uint             `gorm:"index;not null" json:"task_id"`
+	Round       int              `json:"round"`                 // 第几轮
+	Status      string           `gorm:"size:20" json:"status"` // pending/approved/rejected/cancelled
+	CurrentStep int              `json:"current_step"`          // 当前审核步骤（从1开始）
+	SubmittedBy uint             `json:"submitted_by"`          // 提交人
+	Submitter   *User            `gorm:"foreignKey:SubmittedBy" json:"submitter,omitempty"`
+	Comment     string           `gorm:"type:text" json:"comment"` // 提交说明
+	Steps       []TaskReviewStep `gorm:"foreignKey:RoundID" json:"steps,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at"`
+}
+
+// TaskReviewStep 审核轮次中的步骤（提交时从审核链复制，审核链后续修改不影响进行中的审核）
+type TaskReviewStep struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	RoundID      uint       `gorm:"index;not null" json:"round_id"`
+	StepOrder    int        `json:"step_order"`
+	Name         string     `gorm:"size:100" json:"name"`
+	ReviewerType string     `gorm:"size:20" json:"reviewer_type"`
+	ReviewerID   *uint      `json:"reviewer_id"` // 指定审核人（审核人类型为 user 时）
+	Status       string     `gorm:"size:20" json:"status"`
+	Comment      string     `gorm:"type:text" json:"comment"` // 审核意见
+	ReviewedBy   *uint      `json:"reviewed_by"`              // 实际审核人
+	ReviewedUser *User      `gorm:"foreignKey:ReviewedBy" json:"reviewed_user,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
 }
 
 // Document 资料/文档模型
@@ -130,6 +510,9 @@ type Document struct {
 	Status     string         `gorm:"size:50;default:'pending'" json:"status"` // 状态:pending/approved/archived
 	UploadedBy uint           `json:"uploaded_by"`
 	Uploader   *User          `gorm:"foreignKey:UploadedBy" json:"uploader,omitempty"`
+	ApprovedBy *uint          `json:"approved_by"` // 审核通过人（项目负责人、子负责人或部门经理，不能是上传者本人）
+	Approver   *User          `gorm:"foreignKey:ApprovedBy" json:"approver,omitempty"`
+	ApprovedAt *time.Time     `json:"approved_at"` // 审核通过时间，阶段门禁只认可审核通过的资料
 	Remark     string         `gorm:"type:text" json:"remark"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
@@ -218,6 +601,68 @@ type OperationLog struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// 评论对象类型
+const (
+	CommentTargetTask     = "task"
+	CommentTargetDocument = "document"
+	CommentTargetContract = "contract"
+)
+
+// Comment 评论（任务、文档、合同下的讨论，支持回复和 Markdown）
+type Comment struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	TargetType string         `gorm:"size:20;index:idx_comment_target;not null" json:"target_type"` // 评论对象类型
+	TargetID   uint           `gorm:"index:idx_comment_target;not null" json:"target_id"`           // 评论对象ID
+	ProjectID  uint           `gorm:"index" json:"project_id"`
+	ParentID   *uint          `gorm:"index" json:"parent_id"`   // 回复的评论ID
+	Content    string         `gorm:"type:text" json:"content"` // Markdown 内容
+	Mentions   string         `gorm:"type:text" json:"-"`       // JSON格式存储被@的用户ID
+	AuthorID   uint           `json:"author_id"`
+	Author     *User          `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	EditedAt   *time.Time     `json:"edited_at"` // 最后编辑时间（为空表示未编辑）
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	Deleted    bool           `gorm:"-" json:"deleted"`           // 已删除（仍有回复时保留占位）
+	Replies    []Comment      `gorm:"-" json:"replies,omitempty"` // 回复
+}
+
+// CommentRevision 评论修改历史（编辑或删除前的内容）
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"index;not null" json:"comment_id"`
+	Content   string    `gorm:"type:text" json:"content"`
+	Action    string    `gorm:"size:20" json:"action"` // edit/delete
+	EditorID  uint      `json:"editor_id"`
+	Editor    *User     `gorm:"foreignKey:EditorID" json:"editor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 通知类型
+const (
+	NotificationMention = "mention" // 评论中被@
+	NotificationReply   = "reply"   // 评论被回复
+	NotificationReview  = "review"  // 任务待审核或审核结果
+	NotificationOverdue = "overdue" // 任务即将到期或逾期
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"` // 接收人
+	Type       string     `gorm:"size:50" json:"type"`           // 通知类型
+	Title      string     `gorm:"size:255" json:"title"`
+	Content    string     `gorm:"type:text" json:"content"`
+	TargetType string     `gorm:"size:50" json:"target_type"` // 关联对象类型
+	TargetID   uint       `json:"target_id"`                  // 关联对象ID
+	ProjectID  uint       `json:"project_id"`
+	SenderID   uint       `json:"sender_id"`
+	Sender     *User      `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
+	IsRead     bool       `gorm:"default:false;index" json:"is_read"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
 // ProjectMember 项目成员模型
 type ProjectMember struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
@@ -303,3 +748,64 @@ type Expense struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// LaborRate 人工小时费率（按用户或职能组配置，按用户配置的优先）
+type LaborRate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        *uint     `gorm:"uniqueIndex" json:"user_id"` // 用户（为空时按职能组）
+	User          *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	FunctionGroup string    `gorm:"size:100;index" json:"function_group"`  // 职能组
+	HourlyRate    float64   `gorm:"type:decimal(10,2)" json:"hourly_rate"` // 小时费率（元）
+	Remark        string    `gorm:"size:255" json:"remark"`
+	UpdatedBy     uint      `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// 工时单状态
+const (
+	TimesheetDraft     = "draft"     // 未提交
+	TimesheetSubmitted = "submitted" // 已提交待审批
+	TimesheetApproved  = "approved"  // 已审批
+	TimesheetRejected  = "rejected"  // 被驳回
+)
+
+// Timesheet 周工时单（每人每项目每周一张，提交后由项目负责人审批）
+type Timesheet struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	UserID        uint        `gorm:"uniqueIndex:idx_timesheet_week;not null" json:"user_id"`
+	User          *User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ProjectID     uint        `gorm:"uniqueIndex:idx_timesheet_week;not null" json:"project_id"`
+	Project       *Project    `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	WeekStart     time.Time   `gorm:"type:date;uniqueIndex:idx_timesheet_week" json:"week_start"` // 所在周的周一
+	Status        string      `gorm:"size:20;default:'draft'" json:"status"`
+	TotalHours    float64     `gorm:"type:decimal(8,2)" json:"total_hours"`
+	LaborCost     float64     `gorm:"type:decimal(12,2)" json:"labor_cost"` // 审批通过时按费率计算的人工成本
+	SubmittedAt   *time.Time  `json:"submitted_at"`
+	ReviewedBy    *uint       `json:"reviewed_by"`
+	Reviewer      *User       `gorm:"foreignKey:ReviewedBy" json:"reviewer,omitempty"`
+	ReviewedAt    *time.Time  `json:"reviewed_at"`
+	ReviewComment string      `gorm:"type:text" json:"review_comment"`
+	Entries       []TimeEntry `gorm:"foreignKey:TimesheetID" json:"entries,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// TimeEntry 每日工时记录（可关联到项目、阶段或任务）
+type TimeEntry struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	TimesheetID uint          `gorm:"index;not null" json:"timesheet_id"`
+	UserID      uint          `gorm:"index;not null" json:"user_id"`
+	ProjectID   uint          `gorm:"index;not null" json:"project_id"`
+	PhaseID     *uint         `json:"phase_id"`
+	Phase       *ProjectPhase `gorm:"foreignKey:PhaseID" json:"phase,omitempty"`
+	TaskID      *uint         `gorm:"index" json:"task_id"`
+	Task        *Task         `gorm:"foreignKey:TaskID" json:"task,omitempty"`
+	WorkDate    time.Time     `gorm:"type:date;index" json:"work_date"`
+	Hours       float64       `gorm:"type:decimal(5,2)" json:"hours"`
+	Description string        `gorm:"size:500" json:"description"`
+	HourlyRate  float64       `gorm:"type:decimal(10,2)" json:"hourly_rate"` // 审批通过时的小时费率（之后调整费率不影响已审批工时）
+	Cost        float64       `gorm:"type:decimal(12,2)" json:"cost"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
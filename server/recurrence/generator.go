@@ -0,0 +1,171 @@
+package recurrence
+
+import (
+	"fmt"
+	"log"
+	"project-flow/config"
+	"project-flow/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Start 启动后台生成任务：启动时立即生成一次，之后按配置的间隔定期生成
+func Start() {
+	go func() {
+		for {
+			if n := GenerateAll(config.GetDB(), time.Now()); n > 0 {
+				log.Printf("重复任务：生成了%d个任务实例", n)
+			}
+			time.Sleep(config.RecurrenceCheckInterval)
+		}
+	}()
+}
+
+// GenerateAll 为全部生效中的重复系列生成实例，返回生成的数量
+func GenerateAll(db *gorm.DB, now time.Time) int {
+	var series []models.TaskRecurrence
+	db.Where("status = ?", models.RecurrenceActive).Find(&series)
+
+	total := 0
+	for i := range series {
+		n, err := Generate(db, &series[i], now)
+		if err != nil {
+			log.Printf("重复任务系列 %d 生成失败: %v", series[i].ID, err)
+		}
+		total += n
+	}
+	return total
+}
+
+// Generate 为重复系列生成截止时间在未来 RecurrenceLeadDays 天以内、尚未生成的任务实例
+func Generate(db *gorm.DB, series *models.TaskRecurrence, now time.Time) (int, error) {
+	if series.Status != models.RecurrenceActive {
+		return 0, nil
+	}
+	var project models.Project
+	if err := db.First(&project, series.ProjectID).Error; err != nil {
+		return 0, err
+	}
+	// 已结项的项目不再生成
+	if project.Status == config.StatusCompleted {
+		return 0, nil
+	}
+
+	rule, err := Parse(series.Rule, series.StartAt)
+	if err != nil {
+		return 0, err
+	}
+	from := series.EffectiveFrom
+	if series.GeneratedUntil != nil && series.GeneratedUntil.After(from) {
+		from = series.GeneratedUntil.Add(time.Nanosecond)
+	}
+	to := now.AddDate(0, 0, config.RecurrenceLeadDays)
+	if !to.After(from) {
+		return 0, nil
+	}
+
+	var phases []models.ProjectPhase
+	db.Where("project_id = ?", project.ID).Order("phase_order").Find(&phases)
+
+	created := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, at := range rule.Between(from, to) {
+			var count int64
+			tx.Model(&models.Task{}).Where("recurrence_id = ? AND occurrence_at = ?", series.ID, at).Count(&count)
+			if count > 0 {
+				continue
+			}
+			task := newInstance(series, resolvePhase(phases, series.PhaseID, at), at)
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			created++
+		}
+		return tx.Model(series).Update("generated_until", to).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// newInstance 按系列设置生成一个任务实例，截止时间为发生时间
+func newInstance(series *models.TaskRecurrence, phaseID uint, at time.Time) models.Task {
+	deadline, occurrence := at, at
+	recurrenceID := series.ID
+	task := models.Task{
+		ProjectID:    series.ProjectID,
+		PhaseID:      phaseID,
+		RecurrenceID: &recurrenceID,
+		OccurrenceAt: &occurrence,
+		TaskName:     fmt.Sprintf("%s（%s）", series.TaskName, at.Format("2006-01-02")),
+		Description:  series.Description,
+		TaskType:     series.TaskType,
+		AssigneeID:   series.AssigneeID,
+		Priority:     series.Priority,
+		Deliverables: series.Deliverables,
+		Duration:     series.Duration,
+		Deadline:     &deadline,
+		Status:       config.TaskNotStarted,
+		CreatedBy:    series.CreatedBy,
+	}
+	if series.Duration > 0 {
+		start := time.Date(at.Year(), at.Month(), at.Day()-series.Duration+1, 0, 0, 0, 0, at.Location())
+		task.PlannedStart = &start
+	}
+	return task
+}
+
+// resolvePhase 确定实例所属阶段，已完成的阶段不再加入新实例：系列指定了阶段且未完成时使用该阶段；
+// 否则取计划时间覆盖发生日期的阶段，其次为进行中的阶段，再次为第一个未完成的阶段
+func resolvePhase(phases []models.ProjectPhase, phaseID uint, at time.Time) uint {
+	if phaseID != 0 {
+		for _, p := range phases {
+			if p.ID == phaseID && p.Status != config.StatusCompleted {
+				return phaseID
+			}
+		}
+	}
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	for _, p := range phases {
+		if p.Status == config.StatusCompleted {
+			continue
+		}
+		if p.StartDate != nil && p.EndDate != nil && !day.Before(truncateDay(*p.StartDate)) && !day.After(truncateDay(*p.EndDate)) {
+			return p.ID
+		}
+	}
+	for _, p := range phases {
+		if p.Status == config.StatusInProgress {
+			return p.ID
+		}
+	}
+	for _, p := range phases {
+		if p.Status != config.StatusCompleted {
+			return p.ID
+		}
+	}
+	return 0
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// DeleteFuture 删除系列中发生时间在 from 之后且尚未开始的实例（已开始或有子任务的实例保留），返回删除的数量
+func DeleteFuture(db *gorm.DB, seriesID uint, from time.Time) int {
+	var ids []uint
+	db.Model(&models.Task{}).
+		Where("recurrence_id = ? AND occurrence_at >= ? AND status = ?", seriesID, from, config.TaskNotStarted).
+		Where("id NOT IN (?)", db.Model(&models.Task{}).Select("parent_id").Where("parent_id IS NOT NULL")).
+		Pluck("id", &ids)
+	if len(ids) == 0 {
+		return 0
+	}
+	db.Unscoped().Where("id IN ?", ids).Delete(&models.Task{})
+	db.Where("predecessor_id IN ? OR successor_id IN ?", ids, ids).Delete(&models.TaskDependency{})
+	db.Where("task_id IN ?", ids).Delete(&models.TaskChecklistItem{})
+	db.Model(&models.TimeEntry{}).Where("task_id IN ?", ids).Update("task_id", nil)
+	return len(ids)
+}
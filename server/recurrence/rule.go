@@ -0,0 +1,248 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 重复频率
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxPeriods 展开规则时最多遍历的周期数，防止异常规则导致死循环
+const maxPeriods = 10000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// WeekdayNum 星期几，Nth 非0时表示当月第N个（负数表示倒数第N个），仅用于按月重复
+type WeekdayNum struct {
+	Nth     int
+	Weekday time.Weekday
+}
+
+// Rule 重复规则（RRULE 子集：FREQ、INTERVAL、BYDAY、BYMONTHDAY、COUNT、UNTIL）
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int // 负数表示倒数第N天
+	Count      int
+	Until      *time.Time
+	Start      time.Time // 第一次发生的时间（DTSTART），每次发生的时刻与其相同
+}
+
+// Parse 解析 RRULE 字符串，如 FREQ=MONTHLY;INTERVAL=1;BYDAY=-1FR
+func Parse(rrule string, start time.Time) (*Rule, error) {
+	r := &Rule{Interval: 1, Start: start}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("规则格式错误: %s", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		switch key {
+		case "FREQ":
+			if value != Daily && value != Weekly && value != Monthly {
+				return nil, fmt.Errorf("不支持的重复频率: %s", value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, errors.New("重复间隔必须为正整数")
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(d)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("每月日期无效: %s", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, errors.New("重复次数必须为正整数")
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := time.ParseInLocation("20060102", value[:min(len(value), 8)], start.Location())
+			if err != nil {
+				return nil, fmt.Errorf("结束日期无效: %s", value)
+			}
+			// 结束日期当天的发生仍然有效
+			until := t.Add(24*time.Hour - time.Nanosecond)
+			r.Until = &until
+		default:
+			return nil, fmt.Errorf("不支持的规则项: %s", key)
+		}
+	}
+	if r.Freq == "" {
+		return nil, errors.New("缺少重复频率")
+	}
+	for _, d := range r.ByDay {
+		if d.Nth != 0 && r.Freq != Monthly {
+			return nil, errors.New("第N个星期几只能用于按月重复")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return nil, errors.New("每月日期只能用于按月重复")
+	}
+	// RRULE 中两者同时出现表示取交集（如每月13日且为星期五），暂不支持
+	if len(r.ByMonthDay) > 0 && len(r.ByDay) > 0 {
+		return nil, errors.New("星期和每月日期不能同时指定")
+	}
+	return r, nil
+}
+
+// parseWeekdayNum 解析 BYDAY 项，如 MO、1MO、-1FR
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("星期无效: %s", s)
+	}
+	wd, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("星期无效: %s", s)
+	}
+	result := WeekdayNum{Weekday: wd}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("星期序号无效: %s", s)
+		}
+		result.Nth = n
+	}
+	return result, nil
+}
+
+// Between 返回 [from, to] 范围内的发生时间（COUNT 从 DTSTART 起计算）
+func (r *Rule) Between(from, to time.Time) []time.Time {
+	var result []time.Time
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		dates := r.periodDates(period)
+		if dates == nil {
+			continue
+		}
+		for _, d := range dates {
+			if d.Before(r.Start) {
+				continue
+			}
+			if (r.Until != nil && d.After(*r.Until)) || d.After(to) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if !d.Before(from) {
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
+
+// periodDates 第 period 个周期内的候选发生时间（已排序）
+func (r *Rule) periodDates(period int) []time.Time {
+	start := r.Start
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		d := start.AddDate(0, 0, period*r.Interval)
+		if len(r.ByDay) == 0 || r.matchWeekday(d.Weekday()) {
+			dates = append(dates, d)
+		}
+	case Weekly:
+		// 周期从 DTSTART 所在周的周一开始
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(start.Year(), start.Month(), start.Day()-offset).AddDate(0, 0, period*7*r.Interval)
+		if len(r.ByDay) == 0 {
+			return []time.Time{monday.AddDate(0, 0, offset)}
+		}
+		for i := 0; i < 7; i++ {
+			if d := monday.AddDate(0, 0, i); r.matchWeekday(d.Weekday()) {
+				dates = append(dates, d)
+			}
+		}
+	case Monthly:
+		first := at(start.Year(), start.Month(), 1).AddDate(0, period*r.Interval, 0)
+		days := first.AddDate(0, 1, -1).Day()
+		switch {
+		case len(r.ByMonthDay) > 0:
+			for _, n := range r.ByMonthDay {
+				if n < 0 {
+					n = days + n + 1
+				}
+				if n >= 1 && n <= days {
+					dates = append(dates, at(first.Year(), first.Month(), n))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for _, wd := range r.ByDay {
+				dates = append(dates, monthWeekdays(first, days, wd)...)
+			}
+		default:
+			// 与 DTSTART 同一天，当月没有该日期时跳过
+			if start.Day() <= days {
+				dates = append(dates, at(first.Year(), first.Month(), start.Day()))
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// monthWeekdays 当月中符合条件的星期几（Nth 为0时返回全部）
+func monthWeekdays(first time.Time, days int, wd WeekdayNum) []time.Time {
+	var all []time.Time
+	for d := first; d.Day() <= days && d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == wd.Weekday {
+			all = append(all, d)
+		}
+	}
+	switch {
+	case wd.Nth == 0:
+		return all
+	case wd.Nth > 0 && wd.Nth <= len(all):
+		return []time.Time{all[wd.Nth-1]}
+	case wd.Nth < 0 && -wd.Nth <= len(all):
+		return []time.Time{all[len(all)+wd.Nth]}
+	}
+	return nil
+}
+
+func (r *Rule) matchWeekday(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
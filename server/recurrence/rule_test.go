@@ -0,0 +1,166 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+// date 当天 09:00 的时间（发生时刻与 DTSTART 相同）
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(9 * time.Hour)
+}
+
+func TestParse(t *testing.T) {
+	start := date("2024-01-01")
+	tests := []struct {
+		name    string
+		rrule   string
+		wantErr bool
+	}{
+		{"按月倒数第一个星期五", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", false},
+		{"小写与多个星期", "freq=weekly;interval=2;byday=mo,th", false},
+		{"每月最后一天", "FREQ=MONTHLY;BYMONTHDAY=-1", false},
+		{"结束日期", "FREQ=DAILY;UNTIL=20240110T000000Z", false},
+		{"缺少频率", "INTERVAL=2", true},
+		{"不支持的频率", "FREQ=YEARLY", true},
+		{"间隔为0", "FREQ=DAILY;INTERVAL=0", true},
+		{"重复次数无效", "FREQ=DAILY;COUNT=-1", true},
+		{"星期无效", "FREQ=WEEKLY;BYDAY=XX", true},
+		{"星期序号超出范围", "FREQ=MONTHLY;BYDAY=6MO", true},
+		{"第N个星期几用于按周重复", "FREQ=WEEKLY;BYDAY=1MO", true},
+		{"每月日期超出范围", "FREQ=MONTHLY;BYMONTHDAY=32", true},
+		{"每月日期用于按周重复", "FREQ=WEEKLY;BYMONTHDAY=1", true},
+		{"星期和每月日期同时指定", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", true},
+		{"不支持的规则项", "FREQ=DAILY;BYHOUR=9", true},
+		{"格式错误", "FREQ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.rrule, start)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.rrule, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		rrule    string
+		start    string
+		from, to string
+		want     []string
+	}{
+		{
+			name:  "每月倒数第一个星期五",
+			rrule: "FREQ=MONTHLY;BYDAY=-1FR",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-04-30",
+			want: []string{"2024-01-26", "2024-02-23", "2024-03-29", "2024-04-26"},
+		},
+		{
+			name:  "每月第一个星期一",
+			rrule: "FREQ=MONTHLY;BYDAY=1MO",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-03-31",
+			want: []string{"2024-01-01", "2024-02-05", "2024-03-04"},
+		},
+		{
+			name:  "每月31日跳过没有31日的月份",
+			rrule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: "2024-01-31", from: "2024-01-01", to: "2024-07-31",
+			want: []string{"2024-01-31", "2024-03-31", "2024-05-31", "2024-07-31"},
+		},
+		{
+			name:  "未指定日期时与开始日期同一天，短月跳过",
+			rrule: "FREQ=MONTHLY",
+			start: "2024-01-31", from: "2024-01-01", to: "2024-05-31",
+			want: []string{"2024-01-31", "2024-03-31", "2024-05-31"},
+		},
+		{
+			name:  "每月最后一天",
+			rrule: "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-04-30",
+			want: []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"},
+		},
+		{
+			name:  "重复次数",
+			rrule: "FREQ=DAILY;COUNT=3",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name:  "重复次数从开始日期起计算",
+			rrule: "FREQ=DAILY;COUNT=5",
+			start: "2024-01-01", from: "2024-01-04", to: "2024-12-31",
+			want: []string{"2024-01-04", "2024-01-05"},
+		},
+		{
+			name:  "结束日期当天仍然有效",
+			rrule: "FREQ=WEEKLY;UNTIL=20240115",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-01-01", "2024-01-08", "2024-01-15"},
+		},
+		{
+			name:  "同时指定重复次数和结束日期时先到者为准",
+			rrule: "FREQ=DAILY;COUNT=10;UNTIL=20240103",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name:  "每两周的星期一和星期四",
+			rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-01-31",
+			want: []string{"2024-01-04", "2024-01-15", "2024-01-18", "2024-01-29"},
+		},
+		{
+			name:  "每两周未指定星期时与开始日期同一天",
+			rrule: "FREQ=WEEKLY;INTERVAL=2",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-02-15",
+			want: []string{"2024-01-03", "2024-01-17", "2024-01-31", "2024-02-14"},
+		},
+		{
+			name:  "工作日每天",
+			rrule: "FREQ=DAILY;BYDAY=MO,WE,FR",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-01-07",
+			want: []string{"2024-01-01", "2024-01-03", "2024-01-05"},
+		},
+		{
+			name:  "每三个月",
+			rrule: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15",
+			start: "2024-01-01", from: "2024-03-01", to: "2024-12-31",
+			want: []string{"2024-04-15", "2024-07-15", "2024-10-15"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rrule, date(tt.start))
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rrule, err)
+			}
+			// 查询范围覆盖 to 当天全天
+			got := rule.Between(date(tt.from), date(tt.to).Add(15*time.Hour))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Between() = %v, want %v", formatDates(got), tt.want)
+			}
+			for i, d := range got {
+				if !d.Equal(date(tt.want[i])) {
+					t.Errorf("Between()[%d] = %v, want %s 09:00", i, d, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func formatDates(dates []time.Time) []string {
+	result := make([]string, 0, len(dates))
+	for _, d := range dates {
+		result = append(result, d.Format("2006-01-02 15:04"))
+	}
+	return result
+}
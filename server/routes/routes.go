@@ -19,9 +19,21 @@ func SetupRoutes(r *gin.Engine) {
 	contractCtrl := &controllers.ContractController{}
 	logCtrl := &controllers.LogController{}
 	expenseCtrl := &controllers.ExpenseController{}
+	roleCtrl := &controllers.RoleController{}
+	ldapCtrl := &controllers.LDAPController{}
+	oidcCtrl := &controllers.OIDCController{}
+	twoFactorCtrl := &controllers.TwoFactorController{}
+	tokenCtrl := &controllers.AccessTokenController{}
+	fileCtrl := &controllers.FileController{}
+	templateCtrl := &controllers.ProjectTemplateController{}
+	commentCtrl := &controllers.CommentController{}
+	notificationCtrl := &controllers.NotificationController{}
+	recurrenceCtrl := &controllers.RecurrenceController{}
+	timesheetCtrl := &controllers.TimesheetController{}
 
 	// API路由组
 	api := r.Group("/api")
+	api.Use(middleware.RateLimitMiddleware("api", config.RateLimitAPI))
 	{
 		api.GET("/health", func(c *gin.Context) {
 			c.JSON(200, gin.H{"status": "ok", "message": "服务运行正常"})
@@ -31,7 +43,28 @@ func SetupRoutes(r *gin.Engine) {
 		})
 
 		// 公开接口
-		api.POST("/login", authCtrl.Login)
+		api.POST("/refresh-token", authCtrl.RefreshToken)
+		api.GET("/password-policy", authCtrl.GetPasswordPolicy)
+
+		// 登录接口（按客户端IP和用户名限流，防止暴力破解和撞库）
+		login := api.Group("/login")
+		login.Use(middleware.LoginRateLimitMiddleware(config.RateLimitLoginIP, config.RateLimitLoginUser))
+		{
+			login.POST("", authCtrl.Login)
+
+			// 登录第二步：双因素认证（使用登录返回的挑战Token）
+			login.POST("/2fa", twoFactorCtrl.VerifyLogin)
+			login.POST("/2fa/setup", twoFactorCtrl.SetupLogin)
+			login.POST("/2fa/activate", twoFactorCtrl.ActivateLogin)
+		}
+
+		// OpenID Connect 单点登录（授权码模式）
+		api.GET("/oidc/config", oidcCtrl.GetConfig)
+		api.GET("/oidc/login", oidcCtrl.Login)
+		api.GET("/oidc/callback", oidcCtrl.Callback)
+
+		// 签名URL文件下载（签名由各资源的 download-url 接口签发）
+		api.GET("/files/:resource/:id", fileCtrl.Download)
 
 		// 需要认证的接口
 		auth := api.Group("")
@@ -40,6 +73,19 @@ func SetupRoutes(r *gin.Engine) {
 			// 认证相关
 			auth.GET("/user/current", authCtrl.GetCurrentUser)
 			auth.POST("/user/change-password", authCtrl.ChangePassword)
+
+			// 双因素认证（个人设置）
+			auth.GET("/user/2fa", twoFactorCtrl.Status)
+			auth.POST("/user/2fa/setup", twoFactorCtrl.Setup)
+			auth.POST("/user/2fa/enable", twoFactorCtrl.Enable)
+			auth.POST("/user/2fa/disable", twoFactorCtrl.Disable)
+			auth.POST("/user/2fa/recovery-codes", twoFactorCtrl.RegenerateRecoveryCodes)
+
+			// 个人访问Token（供脚本和系统集成使用，不能使用访问Token管理）
+			auth.GET("/user/token-scopes", tokenCtrl.GetScopes)
+			auth.GET("/user/tokens", tokenCtrl.List)
+			auth.POST("/user/tokens", tokenCtrl.Create)
+			auth.DELETE("/user/tokens/:id", tokenCtrl.Revoke)
 			auth.POST("/logout", authCtrl.Logout)
 
 			// 用户管理
@@ -47,25 +93,68 @@ func SetupRoutes(r *gin.Engine) {
 			{
 				// 查询用户列表（所有人可查询，用于项目负责人选择等）
 				users.GET("", userCtrl.List)
+				users.GET("/locked", middleware.RequirePermission(config.PermUserManage), userCtrl.LockedUsers)
 				users.GET("/:id", userCtrl.Get)
 
 				// 用户管理操作（仅管理员和部门经理）
-				users.POST("", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), userCtrl.Create)
-				users.PUT("/:id", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), userCtrl.Update)
-				users.DELETE("/:id", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), userCtrl.Delete)
-				users.POST("/:id/reset-password", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), userCtrl.ResetPassword)
+				users.POST("", middleware.RequirePermission(config.PermUserManage), userCtrl.Create)
+				users.PUT("/:id", middleware.RequirePermission(config.PermUserManage), userCtrl.Update)
+				users.DELETE("/:id", middleware.RequirePermission(config.PermUserManage), userCtrl.Delete)
+				users.POST("/:id/reset-password", middleware.RequirePermission(config.PermUserManage), userCtrl.ResetPassword)
+				users.POST("/:id/unlock", middleware.RequirePermission(config.PermUserManage), userCtrl.Unlock)
+
+				// 会话管理（查看登录设备、强制下线）
+				users.GET("/:id/sessions", middleware.RequirePermission(config.PermUserManage), userCtrl.Sessions)
+				users.DELETE("/:id/sessions", middleware.RequirePermission(config.PermUserManage), userCtrl.RevokeSessions)
+				users.DELETE("/:id/sessions/:sessionId", middleware.RequirePermission(config.PermUserManage), userCtrl.RevokeSession)
 			}
+
+			// 角色与权限管理（查询角色列表所有人可用，其余需要角色管理权限）
 			auth.GET("/roles", userCtrl.GetRoles)
+			auth.GET("/permissions", middleware.RequirePermission(config.PermRoleManage), roleCtrl.GetPermissions)
+			roles := auth.Group("/roles")
+			roles.Use(middleware.RequirePermission(config.PermRoleManage))
+			{
+				roles.GET("/:id", roleCtrl.Get)
+				roles.POST("", roleCtrl.Create)
+				roles.PUT("/:id", roleCtrl.Update)
+				roles.DELETE("/:id", roleCtrl.Delete)
+			}
+
+			// LDAP目录组到角色的映射
+			ldapGroups := auth.Group("/ldap/group-mappings")
+			ldapGroups.Use(middleware.RequirePermission(config.PermRoleManage))
+			{
+				ldapGroups.GET("", ldapCtrl.ListGroupMappings)
+				ldapGroups.POST("", ldapCtrl.CreateGroupMapping)
+				ldapGroups.PUT("/:id", ldapCtrl.UpdateGroupMapping)
+				ldapGroups.DELETE("/:id", ldapCtrl.DeleteGroupMapping)
+			}
 
 			// 项目管理（所有用户可查看，权限控制在控制器中实现）
+			// 项目模板（创建项目时选择，维护需要模板管理权限）
+			templates := auth.Group("/project-templates")
+			{
+				templates.GET("", middleware.RequirePermission(config.PermProjectCreate, config.PermTemplateManage), templateCtrl.List)
+				templates.GET("/:id", middleware.RequirePermission(config.PermProjectCreate, config.PermTemplateManage), templateCtrl.Get)
+				templates.POST("", middleware.RequirePermission(config.PermTemplateManage), templateCtrl.Create)
+				templates.PUT("/:id", middleware.RequirePermission(config.PermTemplateManage), templateCtrl.Update)
+				templates.DELETE("/:id", middleware.RequirePermission(config.PermTemplateManage), templateCtrl.Delete)
+			}
+
 			projects := auth.Group("/projects")
+			projects.Use(middleware.RequirePermission(config.PermProjectView))
 			{
 				projects.GET("", projectCtrl.List)
 				projects.GET("/statistics", projectCtrl.GetStatistics)
 				projects.GET("/:id", projectCtrl.Get)
 
 				// 创建项目（组长和组员）
-				projects.POST("", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), projectCtrl.Create)
+				projects.POST("", middleware.RequirePermission(config.PermProjectCreate), projectCtrl.Create)
+				projects.POST("/:id/clone", middleware.RequirePermission(config.PermProjectCreate), projectCtrl.Clone)
+				projects.GET("/:id/schedule", projectCtrl.Schedule) // 任务排程（关键路径）
+				projects.GET("/:id/gantt", projectCtrl.Gantt)
+				projects.PUT("/:id/gantt", projectCtrl.Reschedule) // 甘特图拖拽批量调整计划（权限在控制器中检查）
 				// 修改/删除项目（权限在控制器中检查）
 				projects.PUT("/:id", projectCtrl.Update)
 				projects.DELETE("/:id", projectCtrl.Delete)
@@ -74,7 +163,22 @@ func SetupRoutes(r *gin.Engine) {
 				projects.GET("/:id/phases", projectCtrl.GetPhases)
 				projects.POST("/:id/phases", projectCtrl.AddPhase) // 添加自定义阶段
 				projects.PUT("/:id/phases/:phaseId", projectCtrl.UpdatePhase)
-				projects.DELETE("/:id/phases/:phaseId", projectCtrl.DeletePhase) // 删除自定义阶段
+				projects.DELETE("/:id/phases/:phaseId", projectCtrl.DeletePhase)      // 删除自定义阶段
+				projects.PUT("/:id/phase-order", projectCtrl.ReorderPhases)           // 调整自定义阶段顺序
+				projects.POST("/:id/phases/:phaseId/reopen", projectCtrl.ReopenPhase) // 重新打开已完成的阶段
+
+				// 阶段门禁（完成检查、部门经理签核）
+				projects.GET("/:id/phases/:phaseId/gate", projectCtrl.GetPhaseGate)
+				projects.POST("/:id/phases/:phaseId/sign-off", projectCtrl.SignOffPhase)
+
+				// 任务审核链（按任务类型配置，项目负责人维护）
+				projects.GET("/:id/review-chains", projectCtrl.GetReviewChains)
+				projects.PUT("/:id/review-chains", projectCtrl.SaveReviewChain)
+				projects.DELETE("/:id/review-chains/:chainId", projectCtrl.DeleteReviewChain)
+
+				// 逾期提醒策略（项目负责人维护）
+				projects.GET("/:id/escalation-policy", projectCtrl.GetEscalationPolicy)
+				projects.PUT("/:id/escalation-policy", projectCtrl.SaveEscalationPolicy)
 
 				// 成员管理
 				projects.GET("/:id/members", projectCtrl.GetMembers)
@@ -84,74 +188,128 @@ func SetupRoutes(r *gin.Engine) {
 
 			// 任务管理（所有用户可查看）
 			tasks := auth.Group("/tasks")
+			tasks.Use(middleware.RequirePermission(config.PermProjectView))
 			{
 				tasks.GET("", taskCtrl.List)
 				tasks.GET("/statistics", taskCtrl.GetTaskStatistics)
 				tasks.GET("/my", taskCtrl.GetMyTasks)
+				tasks.GET("/workload", middleware.RequirePermission(config.PermTaskCreate), taskCtrl.GetWorkload) // 团队工作负荷（分配任务时参考）
 				tasks.GET(":id", taskCtrl.Get)
 
 				// 创建/分配任务（组长和组员）
-				tasks.POST("", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), taskCtrl.Create)
-				tasks.POST("/batch", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), taskCtrl.BatchCreate)
+				tasks.POST("", middleware.RequirePermission(config.PermTaskCreate), taskCtrl.Create)
+				tasks.POST("/batch", middleware.RequirePermission(config.PermTaskCreate), taskCtrl.BatchCreate)
 				tasks.PUT("/:id", taskCtrl.Update)
 				tasks.DELETE("/:id", taskCtrl.Delete) // 权限在控制器中检查（项目负责人）
 
 				// 状态更新（所有人可更新自己的任务状态）
 				tasks.PUT("/:id/status", taskCtrl.UpdateStatus)
+				tasks.GET("/:id/transitions", taskCtrl.GetStatusTransitions) // 当前用户可变更到的状态
+				tasks.GET("/:id/status-history", taskCtrl.GetStatusHistory)  // 状态变更记录
+
+				// 任务依赖（权限在控制器中检查：项目负责人）
+				tasks.GET("/:id/dependencies", taskCtrl.GetDependencies)
+				tasks.POST("/:id/dependencies", taskCtrl.AddDependency)
+				tasks.DELETE("/:id/dependencies/:depId", taskCtrl.RemoveDependency)
+
+				// 检查清单（权限在控制器中检查：任务负责人或项目负责人）
+				tasks.GET("/:id/checklist", taskCtrl.GetChecklist)
+				tasks.POST("/:id/checklist", taskCtrl.AddChecklistItem)
+				tasks.PUT("/:id/checklist/:itemId", taskCtrl.UpdateChecklistItem)
+				tasks.DELETE("/:id/checklist/:itemId", taskCtrl.DeleteChecklistItem)
+
+				// 任务动态（评论、状态变更、审核、上传）
+				tasks.GET("/:id/activity", taskCtrl.Activity)
+
+				// 审核（按项目审核链逐级审核，审核人在控制器中检查，任务负责人不能审核自己的任务）
+				tasks.GET("/pending-reviews", taskCtrl.GetPendingReviews)
+				tasks.GET("/:id/reviews", taskCtrl.GetReviews)
+				tasks.POST("/:id/submit-review", taskCtrl.SubmitReview)
+				tasks.POST("/:id/review", taskCtrl.ReviewTask)
+			}
+
+			// 重复任务（项目负责人管理，权限在控制器中检查）
+			recurrences := auth.Group("/recurrences")
+			{
+				recurrences.GET("", recurrenceCtrl.List)
+				recurrences.POST("", recurrenceCtrl.Create)
+				recurrences.POST("/preview", recurrenceCtrl.Preview)
+				recurrences.PUT("/:id", recurrenceCtrl.Update)
+				recurrences.POST("/:id/stop", recurrenceCtrl.Stop)
+			}
+
+			// 评论（任务、文档、合同；查看权限和编辑、删除权限在控制器中检查）
+			comments := auth.Group("/comments")
+			{
+				comments.GET("", commentCtrl.List)
+				comments.POST("", commentCtrl.Create)
+				comments.PUT("/:id", commentCtrl.Update)
+				comments.DELETE("/:id", commentCtrl.Delete)
+				comments.GET("/:id/revisions", commentCtrl.Revisions)
+			}
 
-				// 审核（组长和组员）
-				tasks.POST("/:id/review", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), taskCtrl.ReviewTask)
+			// 站内通知（仅当前用户自己的通知）
+			notifications := auth.Group("/notifications")
+			{
+				notifications.GET("", notificationCtrl.List)
+				notifications.GET("/unread-count", notificationCtrl.UnreadCount)
+				notifications.PUT("/read-all", notificationCtrl.MarkAllRead)
+				notifications.PUT("/:id/read", notificationCtrl.MarkRead)
 			}
 
 			// 文档管理（所有用户可查看和下载，上传权限在控制器中检查）
 			docs := auth.Group("/documents")
 			{
-				docs.GET("", docCtrl.List)
-				docs.GET("/:id", docCtrl.Get)
-				docs.GET("/:id/download", docCtrl.Download)
-				docs.POST("/upload", docCtrl.Upload)
-				docs.PUT("/:id", docCtrl.Update)
-				docs.DELETE("/:id", docCtrl.Delete)
-				docs.POST("/:id/archive", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), docCtrl.Archive)
+				docs.GET("", middleware.RequirePermission(config.PermDocumentView), docCtrl.List)
+				docs.GET("/:id", middleware.RequirePermission(config.PermDocumentView), docCtrl.Get)
+				docs.GET("/:id/download", middleware.RequirePermission(config.PermDocumentDownload), docCtrl.Download)
+				docs.GET("/:id/download-url", middleware.RequirePermission(config.PermDocumentDownload), docCtrl.DownloadURL)
+				docs.POST("/upload", middleware.RequirePermission(config.PermDocumentUpload), docCtrl.Upload)
+				docs.PUT("/:id", middleware.RequirePermission(config.PermDocumentUpload), docCtrl.Update)
+				docs.DELETE("/:id", middleware.RequirePermission(config.PermDocumentUpload), docCtrl.Delete)
+				docs.POST("/:id/archive", middleware.RequirePermission(config.PermDocumentArchive), docCtrl.Archive)
+				docs.POST("/:id/approve", middleware.RequirePermission(config.PermDocumentView), docCtrl.Approve) // 权限在控制器中检查（项目负责人、子负责人、部门经理）
 			}
 
 			// 合同管理（组长和组员可操作）
 			contracts := auth.Group("/contracts")
 			{
-				contracts.GET("", contractCtrl.List)
-				contracts.GET("/:id", contractCtrl.Get)
-				contracts.POST("", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), contractCtrl.Create)
-				contracts.PUT("/:id", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), contractCtrl.Update)
-				contracts.POST("/:id/upload", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), contractCtrl.UploadFile)
-				contracts.DELETE("/:id", middleware.RoleMiddleware(config.RoleTeamLeader, config.RoleTeamMember), contractCtrl.Delete)
+				contracts.GET("", middleware.RequirePermission(config.PermContractView), contractCtrl.List)
+				contracts.GET("/:id", middleware.RequirePermission(config.PermContractView), contractCtrl.Get)
+				contracts.GET("/:id/download-url", middleware.RequirePermission(config.PermContractView), contractCtrl.DownloadURL)
+				contracts.POST("", middleware.RequirePermission(config.PermContractManage), contractCtrl.Create)
+				contracts.PUT("/:id", middleware.RequirePermission(config.PermContractManage), contractCtrl.Update)
+				contracts.POST("/:id/upload", middleware.RequirePermission(config.PermContractManage), contractCtrl.UploadFile)
+				contracts.DELETE("/:id", middleware.RequirePermission(config.PermContractManage), contractCtrl.Delete)
 			}
 
 			// 知识库管理（所有用户可查看和下载）
 			kb := auth.Group("/knowledge")
 			{
-				kb.GET("", kbCtrl.List)
-				kb.GET("/hot", kbCtrl.GetHotItems)
-				kb.GET("/categories", kbCtrl.GetCategories)
-				kb.GET("/:id", kbCtrl.Get)
-				kb.GET("/:id/download", kbCtrl.Download)
-				kb.GET("/:id/versions", kbCtrl.GetVersions)
-
-				// 上传和编辑（部门经理、组长、组员）
-				kb.POST("/upload", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager, config.RoleTeamLeader, config.RoleTeamMember), kbCtrl.Upload)
-				kb.PUT("/:id", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), kbCtrl.Update)
-				kb.POST("/:id/version", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager, config.RoleTeamLeader, config.RoleTeamMember), kbCtrl.NewVersion)
+				kb.GET("", middleware.RequirePermission(config.PermKBView), kbCtrl.List)
+				kb.GET("/hot", middleware.RequirePermission(config.PermKBView), kbCtrl.GetHotItems)
+				kb.GET("/categories", middleware.RequirePermission(config.PermKBView), kbCtrl.GetCategories)
+				kb.GET("/:id", middleware.RequirePermission(config.PermKBView), kbCtrl.Get)
+				kb.GET("/:id/download", middleware.RequirePermission(config.PermKBDownload), kbCtrl.Download)
+				kb.GET("/:id/download-url", middleware.RequirePermission(config.PermKBDownload), kbCtrl.DownloadURL)
+				kb.GET("/:id/versions", middleware.RequirePermission(config.PermKBView), kbCtrl.GetVersions)
+
+				// 上传和编辑
+				kb.POST("/upload", middleware.RequirePermission(config.PermKBUpload), kbCtrl.Upload)
+				kb.PUT("/:id", middleware.RequirePermission(config.PermKBManage), kbCtrl.Update)
+				kb.POST("/:id/version", middleware.RequirePermission(config.PermKBUpload), kbCtrl.NewVersion)
 				// 删除（权限在控制器中检查：管理员/部门经理可删除所有，其他用户只能删除自己的）
-				kb.DELETE("/:id", kbCtrl.Delete)
+				kb.DELETE("/:id", middleware.RequirePermission(config.PermKBUpload, config.PermKBManage), kbCtrl.Delete)
 
-				// 分类管理（管理员、部门经理）
-				kb.POST("/categories", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), kbCtrl.CreateCategory)
-				kb.PUT("/categories/:id", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), kbCtrl.UpdateCategory)
-				kb.DELETE("/categories/:id", middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager), kbCtrl.DeleteCategory)
+				// 分类管理
+				kb.POST("/categories", middleware.RequirePermission(config.PermKBManage), kbCtrl.CreateCategory)
+				kb.PUT("/categories/:id", middleware.RequirePermission(config.PermKBManage), kbCtrl.UpdateCategory)
+				kb.DELETE("/categories/:id", middleware.RequirePermission(config.PermKBManage), kbCtrl.DeleteCategory)
 			}
 
-			// 操作日志（管理员和部门经理可查看）
+			// 操作日志
 			logs := auth.Group("/logs")
-			logs.Use(middleware.RoleMiddleware(config.RoleAdmin, config.RoleDeptManager))
+			logs.Use(middleware.RequirePermission(config.PermLogView))
 			{
 				logs.GET("", logCtrl.List)
 				logs.GET("/actions", logCtrl.GetActions)
@@ -159,22 +317,44 @@ func SetupRoutes(r *gin.Engine) {
 				logs.GET("/statistics", logCtrl.GetStatistics)
 			}
 
-			// 费用管理（所有用户可查看费用及导出，管理员可执行部分高级操作）
+			// 费用管理
 			expenses := auth.Group("/expenses")
 			{
-				expenses.GET("", expenseCtrl.List)
-				expenses.GET("/statistics", expenseCtrl.GetStatistics)
-				expenses.GET("/comparison", expenseCtrl.GetProjectComparison)
-				expenses.GET("/non-project-stats", expenseCtrl.GetNonProjectExpenseStats) // 非研发项目费用统计
-				expenses.GET("/export", expenseCtrl.Export)
-				expenses.GET("/:id", expenseCtrl.Get)
-				expenses.POST("", expenseCtrl.Create)
-				expenses.PUT("/:id", expenseCtrl.Update)
-				expenses.DELETE("/:id", expenseCtrl.Delete)
+				expenses.GET("", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.List)
+				expenses.GET("/statistics", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.GetStatistics)
+				expenses.GET("/comparison", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.GetProjectComparison)
+				expenses.GET("/non-project-stats", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.GetNonProjectExpenseStats) // 非研发项目费用统计
+				expenses.GET("/export", middleware.RequirePermission(config.PermExpenseExport), expenseCtrl.Export)
+				expenses.GET("/:id", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.Get)
+				expenses.GET("/:id/vouchers/:index/download-url", middleware.RequirePermission(config.PermExpenseView), expenseCtrl.VoucherDownloadURL)
+				expenses.POST("", middleware.RequirePermission(config.PermExpenseEdit), expenseCtrl.Create)
+				expenses.PUT("/:id", middleware.RequirePermission(config.PermExpenseEdit), expenseCtrl.Update)
+				expenses.DELETE("/:id", middleware.RequirePermission(config.PermExpenseEdit), expenseCtrl.Delete)
 				// 导入功能
-				expenses.POST("/import", expenseCtrl.ImportExpenses)
-				// 一键删除（仅管理员）
-				expenses.DELETE("/all", middleware.RoleMiddleware(config.RoleAdmin), expenseCtrl.DeleteAll)
+				expenses.POST("/import", middleware.RequirePermission(config.PermExpenseImport), expenseCtrl.ImportExpenses)
+				// 一键删除
+				expenses.DELETE("/all", middleware.RequirePermission(config.PermExpenseDeleteAll), expenseCtrl.DeleteAll)
+			}
+
+			// 工时填报（本人填报和提交，项目负责人审批，权限在控制器中检查）
+			timesheets := auth.Group("/timesheets")
+			{
+				timesheets.GET("/my", timesheetCtrl.GetMyWeek)
+				timesheets.GET("/reviews", timesheetCtrl.ListReviews)
+				timesheets.POST("/entries", timesheetCtrl.CreateEntry)
+				timesheets.PUT("/entries/:id", timesheetCtrl.UpdateEntry)
+				timesheets.DELETE("/entries/:id", timesheetCtrl.DeleteEntry)
+				timesheets.POST("/:id/submit", timesheetCtrl.Submit)
+				timesheets.POST("/:id/review", timesheetCtrl.Review)
+			}
+
+			// 人工费率配置
+			laborRates := auth.Group("/labor-rates")
+			laborRates.Use(middleware.RequirePermission(config.PermLaborRate))
+			{
+				laborRates.GET("", timesheetCtrl.GetLaborRates)
+				laborRates.POST("", timesheetCtrl.SaveLaborRate)
+				laborRates.DELETE("/:id", timesheetCtrl.DeleteLaborRate)
 			}
 		}
 	}
@@ -0,0 +1,49 @@
+package schedule
+
+import (
+	"project-flow/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ForProject 计算项目全部任务的排程，以立项日期为项目开始日期
+func ForProject(db *gorm.DB, project *models.Project) (*Result, error) {
+	var tasks []models.Task
+	db.Where("project_id = ?", project.ID).Find(&tasks)
+	var deps []models.TaskDependency
+	db.Where("project_id = ?", project.ID).Find(&deps)
+
+	start := time.Now()
+	if project.InitiationDate != nil {
+		start = *project.InitiationDate
+	}
+
+	nodes := make([]Node, 0, len(tasks))
+	for i := range tasks {
+		nodes = append(nodes, Node{ID: tasks[i].ID, Start: tasks[i].PlannedStart, Duration: TaskDuration(&tasks[i])})
+	}
+	return Compute(start, nodes, Edges(deps))
+}
+
+// TaskDuration 任务工期：未填写时按计划开始和截止日期推算，仍无法确定时按1天计
+func TaskDuration(task *models.Task) int {
+	if task.Duration > 0 {
+		return task.Duration
+	}
+	if task.PlannedStart != nil && task.Deadline != nil {
+		if days := dayOffset(truncateDay(*task.PlannedStart), *task.Deadline) + 1; days > 0 {
+			return days
+		}
+	}
+	return 1
+}
+
+// Edges 将依赖记录转换为排程用的依赖边
+func Edges(deps []models.TaskDependency) []Edge {
+	edges := make([]Edge, 0, len(deps))
+	for _, d := range deps {
+		edges = append(edges, Edge{From: d.PredecessorID, To: d.SuccessorID, Type: d.Type, Lag: d.Lag})
+	}
+	return edges
+}
@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"errors"
+	"math"
+	"project-flow/models"
+	"sort"
+	"time"
+)
+
+// ErrCycle 任务依赖存在循环
+var ErrCycle = errors.New("任务依赖存在循环")
+
+// Node 参与排程的任务
+type Node struct {
+	ID       uint
+	Start    *time.Time // 计划开始日期，作为"不早于"约束；为空时按依赖关系推算
+	Duration int        // 工期（天），0 表示里程碑
+}
+
+// Edge 任务依赖（From 为前置任务，To 为后续任务）
+type Edge struct {
+	From uint
+	To   uint
+	Type string // models.DependencyFinishToStart 等
+	Lag  int    // 间隔天数
+}
+
+// TaskSchedule 单个任务的排程结果
+// 完成时间为工期结束时刻，即最后一个工作日的次日零点
+type TaskSchedule struct {
+	TaskID         uint      `json:"task_id"`
+	Duration       int       `json:"duration"`
+	EarliestStart  time.Time `json:"earliest_start"`
+	EarliestFinish time.Time `json:"earliest_finish"`
+	LatestStart    time.Time `json:"latest_start"`
+	LatestFinish   time.Time `json:"latest_finish"`
+	Slack          int       `json:"slack"`    // 总浮动时间（天）
+	Critical       bool      `json:"critical"` // 是否在关键路径上
+}
+
+// Result 项目排程结果
+type Result struct {
+	Start        time.Time      `json:"start"`
+	Finish       time.Time      `json:"finish"`
+	Tasks        []TaskSchedule `json:"tasks"`         // 按最早开始时间排序
+	CriticalPath []uint         `json:"critical_path"` // 关键任务ID，按最早开始时间排序
+}
+
+// Compute 按关键路径法计算最早/最晚开始和完成时间；start 为项目开始日期
+func Compute(start time.Time, nodes []Node, edges []Edge) (*Result, error) {
+	base := truncateDay(start)
+	order, err := topoSort(nodes, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*Node, len(nodes))
+	for i := range nodes {
+		byID[nodes[i].ID] = &nodes[i]
+	}
+	preds := make(map[uint][]Edge)
+	succs := make(map[uint][]Edge)
+	for _, e := range edges {
+		if byID[e.From] == nil || byID[e.To] == nil {
+			continue
+		}
+		preds[e.To] = append(preds[e.To], e)
+		succs[e.From] = append(succs[e.From], e)
+	}
+
+	// 正推：最早开始/完成（相对项目开始的天数）
+	es := make(map[uint]int, len(nodes))
+	ef := make(map[uint]int, len(nodes))
+	finish := 0
+	for _, id := range order {
+		n := byID[id]
+		earliest := 0
+		if n.Start != nil {
+			earliest = dayOffset(base, *n.Start)
+		}
+		for _, e := range preds[id] {
+			var bound int
+			switch e.Type {
+			case models.DependencyStartToStart:
+				bound = es[e.From] + e.Lag
+			case models.DependencyFinishToFinish:
+				bound = ef[e.From] + e.Lag - n.Duration
+			default:
+				bound = ef[e.From] + e.Lag
+			}
+			if bound > earliest {
+				earliest = bound
+			}
+		}
+		es[id] = earliest
+		ef[id] = earliest + n.Duration
+		if ef[id] > finish {
+			finish = ef[id]
+		}
+	}
+
+	// 逆推：最晚开始/完成
+	ls := make(map[uint]int, len(nodes))
+	lf := make(map[uint]int, len(nodes))
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		n := byID[id]
+		latest := finish
+		for _, e := range succs[id] {
+			var bound int
+			switch e.Type {
+			case models.DependencyStartToStart:
+				bound = ls[e.To] - e.Lag + n.Duration
+			case models.DependencyFinishToFinish:
+				bound = lf[e.To] - e.Lag
+			default:
+				bound = ls[e.To] - e.Lag
+			}
+			if bound < latest {
+				latest = bound
+			}
+		}
+		lf[id] = latest
+		ls[id] = latest - n.Duration
+	}
+
+	result := &Result{Start: base, Finish: base.AddDate(0, 0, finish), Tasks: make([]TaskSchedule, 0, len(order))}
+	for _, id := range order {
+		slack := ls[id] - es[id]
+		result.Tasks = append(result.Tasks, TaskSchedule{
+			TaskID:         id,
+			Duration:       byID[id].Duration,
+			EarliestStart:  base.AddDate(0, 0, es[id]),
+			EarliestFinish: base.AddDate(0, 0, ef[id]),
+			LatestStart:    base.AddDate(0, 0, ls[id]),
+			LatestFinish:   base.AddDate(0, 0, lf[id]),
+			Slack:          slack,
+			Critical:       slack <= 0,
+		})
+	}
+	sort.SliceStable(result.Tasks, func(i, j int) bool {
+		return result.Tasks[i].EarliestStart.Before(result.Tasks[j].EarliestStart)
+	})
+	for _, t := range result.Tasks {
+		if t.Critical {
+			result.CriticalPath = append(result.CriticalPath, t.TaskID)
+		}
+	}
+	return result, nil
+}
+
+// HasPath 判断依赖图中是否存在从 from 到 to 的路径（新增依赖 to -> from 前用于检测循环）
+func HasPath(edges []Edge, from, to uint) bool {
+	succs := make(map[uint][]uint)
+	for _, e := range edges {
+		succs[e.From] = append(succs[e.From], e.To)
+	}
+	visited := map[uint]bool{from: true}
+	stack := []uint{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		for _, next := range succs[id] {
+			if !visited[next] {
+				visited[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return false
+}
+
+// topoSort 拓扑排序，存在循环时返回 ErrCycle
+func topoSort(nodes []Node, edges []Edge) ([]uint, error) {
+	indegree := make(map[uint]int, len(nodes))
+	for _, n := range nodes {
+		indegree[n.ID] = 0
+	}
+	succs := make(map[uint][]uint)
+	for _, e := range edges {
+		if _, ok := indegree[e.From]; !ok {
+			continue
+		}
+		if _, ok := indegree[e.To]; !ok {
+			continue
+		}
+		succs[e.From] = append(succs[e.From], e.To)
+		indegree[e.To]++
+	}
+
+	var queue, order []uint
+	for _, n := range nodes {
+		if indegree[n.ID] == 0 {
+			queue = append(queue, n.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, next := range succs[id] {
+			if indegree[next]--; indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if len(order) != len(nodes) {
+		return nil, ErrCycle
+	}
+	return order, nil
+}
+
+// truncateDay 截取到当天零点
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// dayOffset 计算日期相对 base 的天数
+func dayOffset(base, t time.Time) int {
+	return int(math.Round(truncateDay(t.In(base.Location())).Sub(base).Hours() / 24))
+}
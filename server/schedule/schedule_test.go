@@ -0,0 +1,267 @@
+package schedule
+
+import (
+	"errors"
+	"project-flow/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+func day(offset int) *time.Time {
+	t := base.AddDate(0, 0, offset)
+	return &t
+}
+
+// want 期望的排程结果（相对项目开始的天数）
+type want struct {
+	es, ef, ls, lf, slack int
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []Node
+		edges    []Edge
+		finish   int
+		tasks    map[uint]want
+		critical []uint
+	}{
+		{
+			name:   "FS 串行，独立任务有浮动时间",
+			nodes:  []Node{{ID: 1, Duration: 3}, {ID: 2, Duration: 2}, {ID: 3, Duration: 1}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToStart}},
+			finish: 5,
+			tasks: map[uint]want{
+				1: {0, 3, 0, 3, 0},
+				2: {3, 5, 3, 5, 0},
+				3: {0, 1, 4, 5, 4},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name:   "FS 间隔天数",
+			nodes:  []Node{{ID: 1, Duration: 3}, {ID: 2, Duration: 2}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToStart, Lag: 2}},
+			finish: 7,
+			tasks: map[uint]want{
+				1: {0, 3, 0, 3, 0},
+				2: {5, 7, 5, 7, 0},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name:   "FS 负间隔（提前开始）",
+			nodes:  []Node{{ID: 1, Duration: 3}, {ID: 2, Duration: 2}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToStart, Lag: -1}},
+			finish: 4,
+			tasks: map[uint]want{
+				1: {0, 3, 0, 3, 0},
+				2: {2, 4, 2, 4, 0},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name:   "类型为空按 FS 处理",
+			nodes:  []Node{{ID: 1, Duration: 2}, {ID: 2, Duration: 2}},
+			edges:  []Edge{{From: 1, To: 2}},
+			finish: 4,
+			tasks: map[uint]want{
+				1: {0, 2, 0, 2, 0},
+				2: {2, 4, 2, 4, 0},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name:   "SS 间隔天数",
+			nodes:  []Node{{ID: 1, Duration: 4}, {ID: 2, Duration: 2}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyStartToStart, Lag: 1}},
+			finish: 4,
+			tasks: map[uint]want{
+				1: {0, 4, 0, 4, 0},
+				2: {1, 3, 2, 4, 1},
+			},
+			critical: []uint{1},
+		},
+		{
+			name:   "SS 负间隔不早于项目开始",
+			nodes:  []Node{{ID: 1, Duration: 2}, {ID: 2, Duration: 3}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyStartToStart, Lag: -2}},
+			finish: 3,
+			tasks: map[uint]want{
+				1: {0, 2, 1, 3, 1},
+				2: {0, 3, 0, 3, 0},
+			},
+			critical: []uint{2},
+		},
+		{
+			name:   "FF 同时完成",
+			nodes:  []Node{{ID: 1, Duration: 3}, {ID: 2, Duration: 1}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToFinish}},
+			finish: 3,
+			tasks: map[uint]want{
+				1: {0, 3, 0, 3, 0},
+				2: {2, 3, 2, 3, 0},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name:   "FF 负间隔",
+			nodes:  []Node{{ID: 1, Duration: 4}, {ID: 2, Duration: 2}, {ID: 3, Duration: 5}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToFinish, Lag: -1}},
+			finish: 5,
+			tasks: map[uint]want{
+				1: {0, 4, 1, 5, 1},
+				2: {1, 3, 3, 5, 2},
+				3: {0, 5, 0, 5, 0},
+			},
+			critical: []uint{3},
+		},
+		{
+			name:   "计划开始作为不早于约束",
+			nodes:  []Node{{ID: 1, Duration: 2, Start: day(5)}, {ID: 2, Duration: 1, Start: day(1)}},
+			edges:  []Edge{{From: 1, To: 2, Type: models.DependencyFinishToStart}},
+			finish: 8,
+			tasks: map[uint]want{
+				1: {5, 7, 5, 7, 0},
+				2: {7, 8, 7, 8, 0},
+			},
+			critical: []uint{1, 2},
+		},
+		{
+			name: "菱形依赖的关键路径与浮动时间",
+			nodes: []Node{
+				{ID: 1, Duration: 2}, {ID: 2, Duration: 3}, {ID: 3, Duration: 1}, {ID: 4, Duration: 1}, {ID: 5, Duration: 0},
+			},
+			edges: []Edge{
+				{From: 1, To: 2, Type: models.DependencyFinishToStart},
+				{From: 1, To: 3, Type: models.DependencyFinishToStart},
+				{From: 2, To: 4, Type: models.DependencyFinishToStart},
+				{From: 3, To: 4, Type: models.DependencyFinishToStart},
+				{From: 4, To: 5, Type: models.DependencyFinishToStart},
+			},
+			finish: 6,
+			tasks: map[uint]want{
+				1: {0, 2, 0, 2, 0},
+				2: {2, 5, 2, 5, 0},
+				3: {2, 3, 4, 5, 2},
+				4: {5, 6, 5, 6, 0},
+				5: {6, 6, 6, 6, 0},
+			},
+			critical: []uint{1, 2, 4, 5},
+		},
+		{
+			name:   "忽略指向未参与排程任务的依赖",
+			nodes:  []Node{{ID: 1, Duration: 2}},
+			edges:  []Edge{{From: 9, To: 1, Type: models.DependencyFinishToStart, Lag: 3}},
+			finish: 2,
+			tasks: map[uint]want{
+				1: {0, 2, 0, 2, 0},
+			},
+			critical: []uint{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 项目开始时间带时分秒，应按当天零点计算
+			result, err := Compute(base.Add(9*time.Hour), tt.nodes, tt.edges)
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			if !result.Start.Equal(base) {
+				t.Errorf("Start = %v, want %v", result.Start, base)
+			}
+			if got := dayOffset(base, result.Finish); got != tt.finish {
+				t.Errorf("Finish = %d, want %d", got, tt.finish)
+			}
+			if len(result.Tasks) != len(tt.tasks) {
+				t.Fatalf("len(Tasks) = %d, want %d", len(result.Tasks), len(tt.tasks))
+			}
+			for i, s := range result.Tasks {
+				if i > 0 && s.EarliestStart.Before(result.Tasks[i-1].EarliestStart) {
+					t.Errorf("Tasks 未按最早开始排序")
+				}
+				w, ok := tt.tasks[s.TaskID]
+				if !ok {
+					t.Errorf("unexpected task %d", s.TaskID)
+					continue
+				}
+				got := want{
+					es:    dayOffset(base, s.EarliestStart),
+					ef:    dayOffset(base, s.EarliestFinish),
+					ls:    dayOffset(base, s.LatestStart),
+					lf:    dayOffset(base, s.LatestFinish),
+					slack: s.Slack,
+				}
+				if got != w {
+					t.Errorf("task %d = %+v, want %+v", s.TaskID, got, w)
+				}
+				if s.Critical != (w.slack <= 0) {
+					t.Errorf("task %d Critical = %v, want %v", s.TaskID, s.Critical, w.slack <= 0)
+				}
+			}
+			if !reflect.DeepEqual(result.CriticalPath, tt.critical) {
+				t.Errorf("CriticalPath = %v, want %v", result.CriticalPath, tt.critical)
+			}
+		})
+	}
+}
+
+func TestComputeCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+		edges []Edge
+	}{
+		{
+			name:  "两个任务互为前置",
+			nodes: []Node{{ID: 1, Duration: 1}, {ID: 2, Duration: 1}},
+			edges: []Edge{{From: 1, To: 2}, {From: 2, To: 1}},
+		},
+		{
+			name:  "三个任务成环",
+			nodes: []Node{{ID: 1, Duration: 1}, {ID: 2, Duration: 1}, {ID: 3, Duration: 1}, {ID: 4, Duration: 1}},
+			edges: []Edge{{From: 4, To: 1}, {From: 1, To: 2}, {From: 2, To: 3}, {From: 3, To: 1}},
+		},
+		{
+			name:  "依赖自身",
+			nodes: []Node{{ID: 1, Duration: 1}},
+			edges: []Edge{{From: 1, To: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compute(base, tt.nodes, tt.edges); !errors.Is(err, ErrCycle) {
+				t.Errorf("Compute() error = %v, want %v", err, ErrCycle)
+			}
+		})
+	}
+}
+
+func TestHasPath(t *testing.T) {
+	edges := []Edge{{From: 1, To: 2}, {From: 2, To: 3}, {From: 1, To: 4}, {From: 5, To: 1}}
+	tests := []struct {
+		name     string
+		from, to uint
+		want     bool
+	}{
+		{"直接依赖", 1, 2, true},
+		{"间接依赖", 5, 3, true},
+		{"反向不可达", 3, 1, false},
+		{"分支之间不可达", 4, 3, false},
+		{"自身", 2, 2, true},
+		{"不在图中的任务", 6, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasPath(edges, tt.from, tt.to); got != tt.want {
+				t.Errorf("HasPath(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
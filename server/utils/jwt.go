@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"project-flow/config"
 	"time"
 
@@ -9,18 +13,23 @@ import (
 
 // Claims JWT声明
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	RoleCode string `json:"role_code"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	RoleCode  string `json:"role_code"`
+	SessionID uint   `json:"session_id"` // 所属会话ID，用于服务端吊销检查
+	// 必须先修改密码，此时只允许访问修改密码等少数接口
+	MustChangePassword bool `json:"must_change_password,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username string, roleCode string) (string, error) {
+// GenerateToken 生成JWT Token（短期访问Token）
+func GenerateToken(userID uint, username string, roleCode string, sessionID uint, mustChangePassword bool) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RoleCode: roleCode,
+		UserID:             userID,
+		Username:           username,
+		RoleCode:           roleCode,
+		SessionID:          sessionID,
+		MustChangePassword: mustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.JWTExpireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -49,3 +58,66 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return nil, jwt.ErrSignatureInvalid
 }
+
+// GenerateRefreshToken 生成随机刷新Token
+func GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken 计算Token的SHA-256摘要（数据库中只保存摘要）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 挑战Token用途
+const (
+	ChallengeTwoFactor      = "2fa"       // 已启用双因素认证，等待输入动态码
+	ChallengeTwoFactorSetup = "2fa_setup" // 角色要求双因素认证但尚未启用，等待完成绑定
+)
+
+// ChallengeClaims 登录第二步验证的挑战Token声明
+// 不携带会话ID，无法通过 AuthMiddleware 访问业务接口
+type ChallengeClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateChallengeToken 生成挑战Token
+func GenerateChallengeToken(userID uint, purpose string) (string, error) {
+	claims := ChallengeClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.ChallengeExpireTime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "project-flow",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.JWTSecret)
+}
+
+// ParseChallengeToken 解析挑战Token并校验用途
+func ParseChallengeToken(tokenString string, purpose string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return config.JWTSecret, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*ChallengeClaims); ok && token.Valid && claims.Purpose == purpose {
+		return claims, nil
+	}
+
+	return nil, errors.New("无效的挑战Token")
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "regexp"
+
+// mentionPattern 匹配 @用户名（用户名由字母、数字、下划线、点和连字符组成，
+// 不以点和连字符结尾，避免把句末标点算作用户名的一部分）
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@-])@([A-Za-z0-9_](?:[A-Za-z0-9_.-]*[A-Za-z0-9_])?)`)
+
+// ParseMentions 提取文本中 @ 的用户名（去重，保持出现顺序）
+func ParseMentions(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if name := m[1]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -1,9 +1,19 @@
 package utils
 
 import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
 	"project-flow/config"
 	"regexp"
+	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -23,11 +33,20 @@ func CheckPassword(hashedPassword, password string) bool {
 	return err == nil
 }
 
-// ValidatePassword 验证密码复杂度
-// 至少8位，包含大小写字母、数字、特殊符号
-func ValidatePassword(password string) bool {
-	if len(password) < config.MinPasswordLength {
-		return false
+// dummyPasswordHash 用于不存在的用户，使其登录校验与真实用户耗时一致
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("project-flow-dummy-password"), bcrypt.DefaultCost)
+
+// DummyCheckPassword 执行一次与 CheckPassword 开销相同的校验（结果恒为失败），避免通过响应时间枚举用户名
+func DummyCheckPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+// ValidatePassword 按密码策略校验密码，不符合时返回具体原因
+func ValidatePassword(password, username string) error {
+	policy := config.Password
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
 	}
 
 	var (
@@ -50,7 +69,155 @@ func ValidatePassword(password string) bool {
 		}
 	}
 
-	return hasUpper && hasLower && hasDigit && hasSpecial
+	if (policy.RequireUpper && !hasUpper) || (policy.RequireLower && !hasLower) ||
+		(policy.RequireDigit && !hasDigit) || (policy.RequireSpecial && !hasSpecial) {
+		return errors.New(PasswordPolicyDescription())
+	}
+
+	if policy.DictionaryCheck {
+		lower := strings.ToLower(password)
+		if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+			return errors.New("密码不能包含用户名")
+		}
+		if isWeakPassword(lower) {
+			return errors.New("密码过于常见，请使用更复杂的密码")
+		}
+	}
+
+	return nil
+}
+
+// 临时密码字符集（去除了 0/O、1/l/I 等易混淆字符）
+var temporaryPasswordCharsets = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnpqrstuvwxyz",
+	"23456789",
+	"!@#$%^&*-_=+?",
+}
+
+// GenerateTemporaryPassword 生成符合密码策略的随机临时密码（管理员重置密码时使用）
+func GenerateTemporaryPassword(username string) (string, error) {
+	length := config.Password.MinLength
+	if length < 12 {
+		length = 12
+	}
+	all := strings.Join(temporaryPasswordCharsets, "")
+
+	for attempt := 0; attempt < 10; attempt++ {
+		// 每类字符至少一个，其余从全部字符中随机选取，最后打乱顺序
+		chars := make([]byte, 0, length)
+		for _, set := range temporaryPasswordCharsets {
+			ch, err := randomChar(set)
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, ch)
+		}
+		for len(chars) < length {
+			ch, err := randomChar(all)
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, ch)
+		}
+		for i := len(chars) - 1; i > 0; i-- {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return "", err
+			}
+			chars[i], chars[j.Int64()] = chars[j.Int64()], chars[i]
+		}
+
+		if password := string(chars); ValidatePassword(password, username) == nil {
+			return password, nil
+		}
+	}
+	return "", errors.New("生成临时密码失败")
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
+
+// PasswordPolicyDescription 密码策略的文字说明
+func PasswordPolicyDescription() string {
+	policy := config.Password
+
+	var classes []string
+	if policy.RequireUpper {
+		classes = append(classes, "大写字母")
+	}
+	if policy.RequireLower {
+		classes = append(classes, "小写字母")
+	}
+	if policy.RequireDigit {
+		classes = append(classes, "数字")
+	}
+	if policy.RequireSpecial {
+		classes = append(classes, "特殊符号")
+	}
+
+	desc := fmt.Sprintf("密码至少%d位", policy.MinLength)
+	if len(classes) > 0 {
+		desc += "，需包含" + strings.Join(classes, "、")
+	}
+	return desc
+}
+
+// commonPasswords 内置的常见弱密码（小写，已去除末尾的数字和符号）
+var commonPasswords = []string{
+	"password", "passw0rd", "p@ssw0rd", "p@ssword", "admin", "administrator", "root",
+	"qwerty", "qwertyuiop", "asdfgh", "asdfghjkl", "zxcvbnm", "qazwsx", "1qaz2wsx",
+	"abc", "abcd", "abcdef", "letmein", "welcome", "iloveyou", "monkey", "dragon",
+	"changeme", "default", "test", "guest", "user", "login", "master", "sunshine",
+	"reset", "company", "woaini", "aini",
+}
+
+var (
+	weakPasswordsOnce sync.Once
+	weakPasswords     map[string]bool
+)
+
+// isWeakPassword 判断密码是否为常见弱密码（忽略大小写及末尾追加的数字、符号，如 Password@123）
+func isWeakPassword(lower string) bool {
+	weakPasswordsOnce.Do(loadWeakPasswords)
+
+	if weakPasswords[lower] {
+		return true
+	}
+	trimmed := strings.TrimRightFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return trimmed == "" || weakPasswords[trimmed]
+}
+
+// loadWeakPasswords 加载内置弱密码和自定义字典文件
+func loadWeakPasswords() {
+	weakPasswords = make(map[string]bool, len(commonPasswords))
+	for _, p := range commonPasswords {
+		weakPasswords[p] = true
+	}
+
+	if config.Password.DictionaryFile == "" {
+		return
+	}
+	file, err := os.Open(config.Password.DictionaryFile)
+	if err != nil {
+		log.Printf("加载密码字典失败: %v", err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if word := strings.ToLower(strings.TrimSpace(scanner.Text())); word != "" {
+			weakPasswords[word] = true
+		}
+	}
 }
 
 // ValidateEmail 验证邮箱格式
@@ -55,6 +55,15 @@ func ErrorWithStatus(c *gin.Context, httpStatus int, code int, message string) {
 	})
 }
 
+// ErrorWithData 携带数据的错误响应（如需要返回未满足的校验项）
+func ErrorWithData(c *gin.Context, code int, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // BadRequest 请求参数错误
 func BadRequest(c *gin.Context, message string) {
 	c.JSON(http.StatusBadRequest, Response{
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"project-flow/config"
+	"time"
+)
+
+// SignDownload 生成文件下载签名，资源类型、资源ID、文件序号和过期时间共同参与签名
+func SignDownload(resource string, id uint, index int, expires int64) string {
+	mac := hmac.New(sha256.New, config.DownloadSignSecret)
+	fmt.Fprintf(mac, "%s:%d:%d:%d", resource, id, index, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyDownload 校验文件下载签名并检查是否过期
+func VerifyDownload(resource string, id uint, index int, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	expected := SignDownload(resource, id, index, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -15,6 +15,11 @@ export function changePassword(data) {
   return request.post('/user/change-password', data)
 }
 
+// 获取密码策略
+export function getPasswordPolicy() {
+  return request.get('/password-policy')
+}
+
 // 退出登录
 export function logout() {
   return request.post('/logout')
@@ -24,3 +29,68 @@ export function logout() {
 export function getRoles() {
   return request.get('/roles')
 }
+
+// 获取单点登录配置
+export function getOIDCConfig() {
+  return request.get('/oidc/config')
+}
+
+// 登录第二步：校验双因素认证动态码或恢复码
+export function verifyTwoFactorLogin(data) {
+  return request.post('/login/2fa', data)
+}
+
+// 登录时强制绑定双因素认证：生成密钥
+export function setupTwoFactorLogin(data) {
+  return request.post('/login/2fa/setup', data)
+}
+
+// 登录时强制绑定双因素认证：校验动态码并登录
+export function activateTwoFactorLogin(data) {
+  return request.post('/login/2fa/activate', data)
+}
+
+// 获取双因素认证状态
+export function getTwoFactorStatus() {
+  return request.get('/user/2fa')
+}
+
+// 生成双因素认证密钥
+export function setupTwoFactor() {
+  return request.post('/user/2fa/setup')
+}
+
+// 启用双因素认证
+export function enableTwoFactor(data) {
+  return request.post('/user/2fa/enable', data)
+}
+
+// 停用双因素认证
+export function disableTwoFactor(data) {
+  return request.post('/user/2fa/disable', data)
+}
+
+// 重新生成恢复码
+export function regenerateRecoveryCodes(data) {
+  return request.post('/user/2fa/recovery-codes', data)
+}
+
+// 获取个人访问Token授权范围
+export function getTokenScopes() {
+  return request.get('/user/token-scopes')
+}
+
+// 获取个人访问Token列表
+export function getAccessTokens() {
+  return request.get('/user/tokens')
+}
+
+// 创建个人访问Token
+export function createAccessToken(data) {
+  return request.post('/user/tokens', data)
+}
+
+// 吊销个人访问Token
+export function revokeAccessToken(id) {
+  return request.delete(`/user/tokens/${id}`)
+}
@@ -0,0 +1,26 @@
+import request from '@/utils/request'
+
+// 获取评论列表（target_type: task/document/contract）
+export function getComments(targetType, targetId) {
+  return request.get('/comments', { params: { target_type: targetType, target_id: targetId } })
+}
+
+// 发表评论或回复
+export function createComment(data) {
+  return request.post('/comments', data)
+}
+
+// 编辑评论
+export function updateComment(id, content) {
+  return request.put(`/comments/${id}`, { content })
+}
+
+// 删除评论
+export function deleteComment(id) {
+  return request.delete(`/comments/${id}`)
+}
+
+// 获取评论修改历史
+export function getCommentRevisions(id) {
+  return request.get(`/comments/${id}/revisions`)
+}
@@ -27,13 +27,18 @@ export function deleteDocument(id) {
   return request.delete(`/documents/${id}`)
 }
 
+// 审核通过资料（阶段门禁只认可审核通过的资料）
+export function approveDocument(id) {
+  return request.post(`/documents/${id}/approve`)
+}
+
 // 归档文档
 export function archiveDocument(id) {
   return request.post(`/documents/${id}/archive`)
 }
 
-// 获取下载URL（带token参数）
-export function getDownloadUrl(id) {
-  const token = localStorage.getItem('token')
-  return `/api/documents/${id}/download?token=${token}`
+// 获取签名下载URL（几分钟内有效，只能下载该文档）
+export async function getDownloadUrl(id, inline = false) {
+  const res = await request.get(`/documents/${id}/download-url`)
+  return `${request.defaults.baseURL}${res.data.url}${inline ? '&inline=1' : ''}`
 }
@@ -50,6 +50,66 @@ export function deleteProjectPhase(projectId, phaseId) {
   return request.delete(`/projects/${projectId}/phases/${phaseId}`)
 }
 
+// 调整自定义阶段顺序
+export function reorderProjectPhases(projectId, phaseIds) {
+  return request.put(`/projects/${projectId}/phase-order`, { phase_ids: phaseIds })
+}
+
+// 重新打开已完成的阶段
+export function reopenProjectPhase(projectId, phaseId, data) {
+  return request.post(`/projects/${projectId}/phases/${phaseId}/reopen`, data)
+}
+
+// 获取项目任务排程（关键路径）
+export function getProjectSchedule(id) {
+  return request.get(`/projects/${id}/schedule`)
+}
+
+// 获取项目甘特图数据
+export function getProjectGantt(id) {
+  return request.get(`/projects/${id}/gantt`)
+}
+
+// 甘特图拖拽后批量调整计划
+export function rescheduleProject(id, data) {
+  return request.put(`/projects/${id}/gantt`, data)
+}
+
+// 获取阶段门禁检查结果
+export function getPhaseGate(projectId, phaseId) {
+  return request.get(`/projects/${projectId}/phases/${phaseId}/gate`)
+}
+
+// 部门经理签核阶段
+export function signOffPhase(projectId, phaseId, data) {
+  return request.post(`/projects/${projectId}/phases/${phaseId}/sign-off`, data)
+}
+
+// 获取项目审核链配置
+export function getReviewChains(projectId) {
+  return request.get(`/projects/${projectId}/review-chains`)
+}
+
+// 保存项目审核链
+export function saveReviewChain(projectId, data) {
+  return request.put(`/projects/${projectId}/review-chains`, data)
+}
+
+// 删除项目审核链
+export function deleteReviewChain(projectId, chainId) {
+  return request.delete(`/projects/${projectId}/review-chains/${chainId}`)
+}
+
+// 获取项目逾期提醒策略
+export function getEscalationPolicy(projectId) {
+  return request.get(`/projects/${projectId}/escalation-policy`)
+}
+
+// 保存项目逾期提醒策略
+export function saveEscalationPolicy(projectId, data) {
+  return request.put(`/projects/${projectId}/escalation-policy`, data)
+}
+
 // 获取项目成员
 export function getProjectMembers(projectId) {
   return request.get(`/projects/${projectId}/members`)
@@ -64,3 +124,33 @@ export function addProjectMember(projectId, data) {
 export function removeProjectMember(projectId, memberId) {
   return request.delete(`/projects/${projectId}/members/${memberId}`)
 }
+
+// 克隆项目
+export function cloneProject(id, data) {
+  return request.post(`/projects/${id}/clone`, data)
+}
+
+// 获取项目模板列表
+export function getProjectTemplates(params) {
+  return request.get('/project-templates', { params })
+}
+
+// 获取项目模板详情
+export function getProjectTemplate(id) {
+  return request.get(`/project-templates/${id}`)
+}
+
+// 创建项目模板
+export function createProjectTemplate(data) {
+  return request.post('/project-templates', data)
+}
+
+// 更新项目模板
+export function updateProjectTemplate(id, data) {
+  return request.put(`/project-templates/${id}`, data)
+}
+
+// 删除项目模板
+export function deleteProjectTemplate(id) {
+  return request.delete(`/project-templates/${id}`)
+}
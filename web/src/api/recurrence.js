@@ -0,0 +1,26 @@
+import request from '@/utils/request'
+
+// 获取项目的重复任务
+export function getRecurrences(projectId) {
+  return request.get('/recurrences', { params: { project_id: projectId } })
+}
+
+// 创建重复任务
+export function createRecurrence(data) {
+  return request.post('/recurrences', data)
+}
+
+// 预览重复规则接下来的发生时间
+export function previewRecurrence(data) {
+  return request.post('/recurrences/preview', data)
+}
+
+// 修改重复任务（只影响以后的实例）
+export function updateRecurrence(id, data) {
+  return request.put(`/recurrences/${id}`, data)
+}
+
+// 停止重复任务
+export function stopRecurrence(id) {
+  return request.post(`/recurrences/${id}/stop`)
+}
@@ -35,16 +35,81 @@ export function deleteTask(id) {
   return request.delete(`/tasks/${id}`)
 }
 
-// 更新任务状态
-export function updateTaskStatus(id, status) {
-  return request.put(`/tasks/${id}/status`, { status })
+// 更新任务状态（override 为项目负责人强制变更，忽略未完成的前置任务；变更为受阻时需填写 comment）
+export function updateTaskStatus(id, status, override = false, comment = '') {
+  return request.put(`/tasks/${id}/status`, { status, override, comment })
 }
 
-// 审核任务
+// 获取当前用户可以将任务变更到的状态
+export function getTaskTransitions(id) {
+  return request.get(`/tasks/${id}/transitions`)
+}
+
+// 获取任务状态变更记录
+export function getTaskStatusHistory(id) {
+  return request.get(`/tasks/${id}/status-history`)
+}
+
+// 获取任务依赖
+export function getTaskDependencies(id) {
+  return request.get(`/tasks/${id}/dependencies`)
+}
+
+// 添加前置任务
+export function addTaskDependency(id, data) {
+  return request.post(`/tasks/${id}/dependencies`, data)
+}
+
+// 删除任务依赖
+export function removeTaskDependency(id, depId) {
+  return request.delete(`/tasks/${id}/dependencies/${depId}`)
+}
+
+// 添加检查项
+export function addChecklistItem(id, data) {
+  return request.post(`/tasks/${id}/checklist`, data)
+}
+
+// 修改或勾选检查项
+export function updateChecklistItem(id, itemId, data) {
+  return request.put(`/tasks/${id}/checklist/${itemId}`, data)
+}
+
+// 删除检查项
+export function deleteChecklistItem(id, itemId) {
+  return request.delete(`/tasks/${id}/checklist/${itemId}`)
+}
+
+// 获取任务动态
+export function getTaskActivity(id) {
+  return request.get(`/tasks/${id}/activity`)
+}
+
+// 提交审核
+export function submitTaskReview(id, data) {
+  return request.post(`/tasks/${id}/submit-review`, data)
+}
+
+// 获取任务审核历史
+export function getTaskReviews(id) {
+  return request.get(`/tasks/${id}/reviews`)
+}
+
+// 获取待我审核的任务
+export function getPendingReviews() {
+  return request.get('/tasks/pending-reviews')
+}
+
+// 审核任务（当前审核步骤）
 export function reviewTask(id, data) {
   return request.post(`/tasks/${id}/review`, data)
 }
 
+// 获取团队工作负荷（按周汇总）
+export function getWorkload(params) {
+  return request.get('/tasks/workload', { params })
+}
+
 // 获取任务统计
 export function getTaskStatistics() {
   return request.get('/tasks/statistics')
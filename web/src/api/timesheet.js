@@ -0,0 +1,51 @@
+import request from '@/utils/request'
+
+// 获取本人某一周的工时单
+export function getMyTimesheets(weekStart) {
+  return request.get('/timesheets/my', { params: { week_start: weekStart } })
+}
+
+// 填报工时
+export function createTimeEntry(data) {
+  return request.post('/timesheets/entries', data)
+}
+
+// 修改工时记录
+export function updateTimeEntry(id, data) {
+  return request.put(`/timesheets/entries/${id}`, data)
+}
+
+// 删除工时记录
+export function deleteTimeEntry(id) {
+  return request.delete(`/timesheets/entries/${id}`)
+}
+
+// 提交周工时单
+export function submitTimesheet(id) {
+  return request.post(`/timesheets/${id}/submit`)
+}
+
+// 获取待本人审批的工时单
+export function getTimesheetReviews(params) {
+  return request.get('/timesheets/reviews', { params })
+}
+
+// 审批工时单
+export function reviewTimesheet(id, data) {
+  return request.post(`/timesheets/${id}/review`, data)
+}
+
+// 获取人工费率配置
+export function getLaborRates() {
+  return request.get('/labor-rates')
+}
+
+// 保存人工费率
+export function saveLaborRate(data) {
+  return request.post('/labor-rates', data)
+}
+
+// 删除人工费率
+export function deleteLaborRate(id) {
+  return request.delete(`/labor-rates/${id}`)
+}
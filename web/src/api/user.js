@@ -26,6 +26,31 @@ export function deleteUser(id) {
 }
 
 // 重置密码
-export function resetPassword(id) {
-  return request.post(`/users/${id}/reset-password`)
+export function resetPassword(id, data = {}) {
+  return request.post(`/users/${id}/reset-password`, data)
+}
+
+// 获取被锁定的账号
+export function getLockedUsers() {
+  return request.get('/users/locked')
+}
+
+// 解除账号锁定
+export function unlockUser(id) {
+  return request.post(`/users/${id}/unlock`)
+}
+
+// 获取用户的有效会话
+export function getUserSessions(id) {
+  return request.get(`/users/${id}/sessions`)
+}
+
+// 强制下线指定会话
+export function revokeUserSession(id, sessionId) {
+  return request.delete(`/users/${id}/sessions/${sessionId}`)
+}
+
+// 强制下线全部会话
+export function revokeUserSessions(id) {
+  return request.delete(`/users/${id}/sessions`)
 }
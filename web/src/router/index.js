@@ -49,6 +49,18 @@ const routes = [
         component: () => import('@/views/task/MyTasks.vue'),
         meta: { title: '我的任务' }
       },
+      {
+        path: 'workload',
+        name: 'Workload',
+        component: () => import('@/views/task/Workload.vue'),
+        meta: { title: '团队负荷' }
+      },
+      {
+        path: 'timesheets',
+        name: 'Timesheets',
+        component: () => import('@/views/timesheet/TimesheetList.vue'),
+        meta: { title: '工时填报' }
+      },
       {
         path: 'knowledge',
         name: 'Knowledge',
@@ -118,6 +130,12 @@ router.beforeEach((to, from, next) => {
     return
   }
 
+  // 初始密码、重置后或已过期的密码须先修改
+  if (userStore.user.must_change_password && to.path !== '/profile') {
+    next('/profile')
+    return
+  }
+
   // 检查角色权限
   if (to.meta.roles && to.meta.roles.length > 0) {
     const roleCode = userStore.roleCode
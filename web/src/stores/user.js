@@ -26,22 +26,39 @@ export const useUserStore = defineStore('user', {
     canViewLogs: (state) => state.user.role?.code === 'admin',
     canManageKnowledge: (state) => ['admin', 'dept_manager', 'team_leader', 'team_member'].includes(state.user.role?.code),
     canManageProject: (state) => ['admin', 'dept_manager'].includes(state.user.role?.code),
+    canManageLaborRates: (state) => ['admin', 'dept_manager'].includes(state.user.role?.code),
   },
 
   actions: {
     async doLogin(username, password) {
       try {
         const res = await login({ username, password })
-        this.token = res.data.token
-        this.user = res.data.user
-        localStorage.setItem('token', res.data.token)
-        localStorage.setItem('user', JSON.stringify(res.data.user))
+        // 需要双因素认证时只返回挑战Token，由登录页完成第二步验证
+        if (!res.data.challenge_token) {
+          this.setSession(res.data)
+        }
         return res
       } catch (error) {
         throw error
       }
     },
 
+    // 保存登录结果（Token和用户信息）
+    setSession(data) {
+      this.token = data.token
+      this.user = data.user
+      localStorage.setItem('token', data.token)
+      localStorage.setItem('refresh_token', data.refresh_token)
+      localStorage.setItem('user', JSON.stringify(data.user))
+    },
+
+    // 保存单点登录回调中携带的Token
+    setTokens(token, refreshToken) {
+      this.token = token
+      localStorage.setItem('token', token)
+      localStorage.setItem('refresh_token', refreshToken)
+    },
+
     async fetchCurrentUser() {
       try {
         const res = await getCurrentUser()
@@ -60,6 +77,7 @@ export const useUserStore = defineStore('user', {
         this.token = ''
         this.user = {}
         localStorage.removeItem('token')
+        localStorage.removeItem('refresh_token')
         localStorage.removeItem('user')
         router.push('/login')
       }
@@ -69,6 +87,7 @@ export const useUserStore = defineStore('user', {
       this.token = ''
       this.user = {}
       localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
       localStorage.removeItem('user')
     }
   }
@@ -12,6 +12,36 @@ const request = axios.create({
   }
 })
 
+// 刷新Token：并发请求共享同一次刷新
+let refreshPromise = null
+
+const clearAuthAndLogin = () => {
+  localStorage.removeItem('token')
+  localStorage.removeItem('refresh_token')
+  localStorage.removeItem('user')
+  router.push('/login')
+}
+
+const refreshAccessToken = () => {
+  if (!refreshPromise) {
+    const refreshToken = localStorage.getItem('refresh_token')
+    refreshPromise = (refreshToken
+      ? axios.post('/project_track/api/refresh-token', { refresh_token: refreshToken })
+      : Promise.reject(new Error('no refresh token'))
+    ).then(res => {
+      if (res.data.code !== 200) {
+        throw new Error(res.data.message)
+      }
+      localStorage.setItem('token', res.data.data.token)
+      localStorage.setItem('refresh_token', res.data.data.refresh_token)
+      return res.data.data.token
+    }).finally(() => {
+      refreshPromise = null
+    })
+  }
+  return refreshPromise
+}
+
 // 请求拦截器
 request.interceptors.request.use(
   config => {
@@ -40,24 +70,36 @@ request.interceptors.response.use(
       
       // Token过期或无效
       if (res.code === 401) {
-        localStorage.removeItem('token')
-        localStorage.removeItem('user')
-        router.push('/login')
+        clearAuthAndLogin()
       }
       
-      return Promise.reject(new Error(res.message || '请求失败'))
+      // 附带业务数据（如未满足的条件），便于页面进一步处理
+      const err = new Error(res.message || '请求失败')
+      err.code = res.code
+      err.data = res.data
+      return Promise.reject(err)
     }
     return res
   },
-  error => {
+  async error => {
     console.error('请求错误:', error)
+    const originalRequest = error.config
+    // 访问Token过期时使用刷新Token换取新Token后重试一次
+    if (error.response?.status === 401 && originalRequest && !originalRequest._retry) {
+      originalRequest._retry = true
+      try {
+        const token = await refreshAccessToken()
+        originalRequest.headers.Authorization = `Bearer ${token}`
+        return request(originalRequest)
+      } catch (e) {
+        // 刷新失败，走下方的重新登录逻辑
+      }
+    }
     if (error.response) {
       switch (error.response.status) {
         case 401:
           ElMessage.error('登录已过期，请重新登录')
-          localStorage.removeItem('token')
-          localStorage.removeItem('user')
-          router.push('/login')
+          clearAuthAndLogin()
           break
         case 403:
           ElMessage.error('没有权限执行此操作')